@@ -0,0 +1,135 @@
+// Das Programm convert wandelt Modelldateien in das binäre Format um, z. B.
+// ältere model.json-Dateien in model.bin:
+//
+//	convert model.json
+//	convert -precision float32 -o small.bin model.json
+//
+// Ohne -o wird die Endung der Eingabedatei durch .bin ersetzt. Die Ausgabe
+// wird nach dem Schreiben erneut geladen und mit der Eingabe verglichen.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+func main() {
+	output := flag.String("o", "", "Ausgabedatei (Standard: Eingabedatei mit Endung .bin)")
+	precision := flag.String("precision", "", "Genauigkeit der Ausgabe: "+strings.Join(mlp.Precisions, ", ")+" (Standard: wie Eingabe)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Aufruf: %s [-o ausgabe] [-precision p] modell...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || (*output != "" && flag.NArg() > 1) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for _, in := range flag.Args() {
+		out := *output
+		if out == "" {
+			out = strings.TrimSuffix(in, filepath.Ext(in)) + ".bin"
+		}
+		if err := convert(in, out, *precision); err != nil {
+			log.Fatalf("Fehler beim Umwandeln von %s: %v", in, err)
+		}
+	}
+}
+
+// convert liest das Modell in und schreibt es in der Genauigkeit precision
+// nach out. Ist precision leer, bleibt die Genauigkeit des Modells erhalten.
+func convert(in, out, precision string) error {
+	if in == out {
+		return fmt.Errorf("Ein- und Ausgabedatei sind gleich")
+	}
+	meta, err := modelio.LoadMetadata(in)
+	if err != nil {
+		return err
+	}
+	if precision == "" {
+		precision = meta.Precision
+	}
+	if err := mlp.CheckPrecision(precision); err != nil {
+		return err
+	}
+
+	if precision == mlp.Float32 {
+		return convertTo[float32](in, out)
+	}
+	return convertTo[float64](in, out)
+}
+
+// convertTo lädt in in der Genauigkeit T, schreibt es nach out und prüft,
+// dass out dieselben Parameter enthält.
+func convertTo[T mlp.Float](in, out string) error {
+	model, meta, err := modelio.Load[T](in)
+	if err != nil {
+		return err
+	}
+	if err := modelio.Save(out, model, meta); err != nil {
+		return err
+	}
+
+	check, _, err := modelio.Load[T](out)
+	if err != nil {
+		return fmt.Errorf("Kontrolle von %s: %v", out, err)
+	}
+	for i, layer := range model.Conv {
+		if layer.W != nil && (!equal(layer.W.Data, check.Conv[i].W.Data) || !equal(layer.B, check.Conv[i].B)) {
+			return fmt.Errorf("Kontrolle von %s: Faltungsschicht %d unterscheidet sich", out, i)
+		}
+	}
+	for l, layer := range model.Layers {
+		if !equal(layer.W.Data, check.Layers[l].W.Data) || !equal(layer.B, check.Layers[l].B) {
+			return fmt.Errorf("Kontrolle von %s: Schicht %d unterscheidet sich", out, l)
+		}
+		if !equalNorm(layer.Norm, check.Layers[l].Norm) {
+			return fmt.Errorf("Kontrolle von %s: Normalisierung von Schicht %d unterscheidet sich", out, l)
+		}
+	}
+
+	inSize, outSize := fileSize(in), fileSize(out)
+	fmt.Printf("%s (%d Byte) -> %s (%d Byte, %s)\n", in, inSize, out, outSize, mlp.Precision[T]())
+	return nil
+}
+
+// equal vergleicht zwei Parametervektoren bitgenau.
+func equal[T mlp.Float](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalNorm vergleicht zwei Normalisierungen einschließlich Gamma, Beta und
+// der gleitenden Statistiken bitgenau; nil ist nur gleich nil.
+func equalNorm[T mlp.Float](a, b *mlp.Norm[T]) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Kind == b.Kind && a.Momentum == b.Momentum && a.Epsilon == b.Epsilon &&
+		equal(a.Gamma, b.Gamma) && equal(a.Beta, b.Beta) && equal(a.RunMean, b.RunMean) && equal(a.RunVar, b.RunVar)
+}
+
+// fileSize liefert die Größe der Datei name oder -1.
+func fileSize(name string) int64 {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return -1
+	}
+	return fi.Size()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+)
+
+// indexTemplate ist die Übersicht der Fehler. Die Bilder der Kontaktabzüge
+// verweisen über eine Image Map auf ihre Zeile in der Tabelle.
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="de">
+<head>
+<meta charset="utf-8">
+<title>Falsch klassifizierte Bilder – {{.Model}}</title>
+<style>
+body { font-family: sans-serif; background: #f4f4f4; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 10px; text-align: right; border-bottom: 1px solid #ddd; }
+th { background: #e0e0e0; }
+tr:target { background: #fff3b0; }
+td img { image-rendering: pixelated; vertical-align: middle; }
+.true { color: #1a8a30; }
+.pred { color: #c62828; }
+</style>
+</head>
+<body>
+<h1>Falsch klassifizierte Bilder</h1>
+<p>Modell <code>{{.Model}}</code> auf {{.Dataset}} (<code>{{.Images}}</code>):
+{{len .Rows}} von {{.Samples}} Bildern falsch klassifiziert, Genauigkeit {{printf "%.2f" .Percent}}%.
+Sortiert nach der Konfidenz der falschen Vorhersage, die sichersten Fehlentscheidungen zuerst.</p>
+{{range .Sheets}}
+<h2 id="{{.File}}">{{.File}}</h2>
+<p><img src="{{.File}}" usemap="#map-{{.File}}" alt="{{.File}}"></p>
+<map name="map-{{.File}}">
+{{- range .Tiles}}
+<area shape="rect" coords="{{.Rect.Min.X}},{{.Rect.Min.Y}},{{.Rect.Max.X}},{{.Rect.Max.Y}}" href="#i{{.Index}}" alt="{{.Index}}">
+{{- end}}
+</map>
+{{end}}
+<h2>Alle Fehler</h2>
+<table>
+<tr><th>#</th><th>Bild</th><th>Index</th><th>wahr</th><th>vorhergesagt</th><th>Konfidenz</th><th>Kontaktabzug</th></tr>
+{{- range .Rows}}
+<tr id="i{{.Index}}"><td>{{.Rank}}</td><td><a href="{{.Image}}"><img src="{{.Image}}" width="28" height="28" alt="{{.Index}}"></a></td><td>{{.Index}}</td><td class="true">{{.True}}</td><td class="pred">{{.Predicted}}</td><td>{{printf "%.1f" .Confidence}}%</td><td><a href="#{{.Sheet}}">{{.Sheet}}</a></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// indexRow ist eine Zeile der Tabelle in index.html.
+type indexRow struct {
+	Rank, Index     int
+	Image, Sheet    string
+	True, Predicted string
+	Confidence      float64
+}
+
+// writeIndex schreibt die Übersicht mit den Kontaktabzügen sheets und einer
+// Tabelle aller Fehler in der Reihenfolge von g.Misclassified.
+func (g *gallery) writeIndex(filename string, sheets []sheet) error {
+	var rows []indexRow
+	for _, s := range sheets {
+		for range s.Tiles {
+			m := g.Misclassified[len(rows)]
+			rows = append(rows, indexRow{
+				Rank:       len(rows) + 1,
+				Index:      m.Index,
+				Image:      imageFile(m.Index),
+				Sheet:      s.File,
+				True:       g.Classes[m.True],
+				Predicted:  g.Classes[m.Predicted],
+				Confidence: m.Confidence * 100,
+			})
+		}
+	}
+	data := struct {
+		*gallery
+		Sheets  []sheet
+		Rows    []indexRow
+		Percent float64
+	}{g, sheets, rows, g.Accuracy * 100}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := indexTemplate.Execute(f, data); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp/mnist"
+)
+
+func TestWriteIndex(t *testing.T) {
+	// 5 Fehler auf Kontaktabzügen mit je 3 Bildern
+	g := testGallery(5, mnist.Classes)
+	dir := t.TempDir()
+	if err := g.write(dir, layout{cols: 2, perSheet: 3, scale: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range g.Misclassified {
+		if _, err := os.Stat(filepath.Join(dir, imageFile(m.Index))); err != nil {
+			t.Error(err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, s := range []string{
+		"<h1>Falsch klassifizierte Bilder</h1>",
+		"5 von 100 Bildern falsch klassifiziert, Genauigkeit 95.00%",
+		"<th>Konfidenz</th>",
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("index.html enthält nicht %q", s)
+		}
+	}
+
+	// jede Zeile der Tabelle: Rang, Index, Klassen und Kontaktabzug in der
+	// Reihenfolge der Fehler
+	rows := regexp.MustCompile(`<tr id="i(\d+)"><td>(\d+)</td>.*<td class="true">(.*?)</td><td class="pred">(.*?)</td><td>(.*?)</td><td><a href="#(.*?)">`).FindAllStringSubmatch(html, -1)
+	if len(rows) != len(g.Misclassified) {
+		t.Fatalf("%d Zeilen in der Tabelle, erwartet %d", len(rows), len(g.Misclassified))
+	}
+	for k, m := range g.Misclassified {
+		want := []string{
+			strconv.Itoa(m.Index), strconv.Itoa(k + 1),
+			g.Classes[m.True], g.Classes[m.Predicted],
+			fmt.Sprintf("%.1f%%", m.Confidence*100),
+			fmt.Sprintf("sheet_%d.png", k/3+1),
+		}
+		if !slices.Equal(rows[k][1:], want) {
+			t.Errorf("Zeile %d: %q, erwartet %q", k+1, rows[k][1:], want)
+		}
+	}
+
+	// die Bilder jedes Kontaktabzugs verweisen auf ihre Zeilen
+	area := regexp.MustCompile(`href="#i(\d+)"`)
+	maps := regexp.MustCompile(`(?s)<map name="map-(.*?)">(.*?)</map>`).FindAllStringSubmatch(html, -1)
+	if len(maps) != 2 {
+		t.Fatalf("%d Image Maps, erwartet 2", len(maps))
+	}
+	for i, m := range maps {
+		if want := fmt.Sprintf("sheet_%d.png", i+1); m[1] != want {
+			t.Errorf("Image Map %d für %s, erwartet %s", i, m[1], want)
+		}
+		var got, want []string
+		for _, a := range area.FindAllStringSubmatch(m[2], -1) {
+			got = append(got, a[1])
+		}
+		for _, e := range g.Misclassified[3*i : min(3*i+3, len(g.Misclassified))] {
+			want = append(want, strconv.Itoa(e.Index))
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s verweist auf %v, erwartet %v", m[1], got, want)
+		}
+		if _, err := os.Stat(filepath.Join(dir, m[1])); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Das Programm errors wertet ein gespeichertes Modell auf den Testdaten seines
+// Datensatzes aus (bei MNIST die 10000 t10k-Bilder) und speichert die falsch
+// klassifizierten Bilder zum Ansehen im Verzeichnis -o:
+//
+//	sheet_<n>.png  Kontaktabzüge mit bis zu -per-sheet Bildern in -cols Spalten,
+//	               jedes mit wahrer Klasse (grün), Vorhersage (rot) und Konfidenz
+//	img/<i>.png    das Testbild mit Index i einzeln
+//	index.html     eine Übersicht mit den Kontaktabzügen und einer Tabelle
+//	               aller Fehler, die auf die einzelnen Bilder verweist
+//
+// Die Fehler sind absteigend nach Konfidenz sortiert, die sichersten
+// Fehlentscheidungen stehen also vorne.
+//
+//	errors -model model.bin
+//	errors -o fehler -cols 10 -scale 4 cnn.bin
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"grimm.world/mlp_demo/internal/evaldata"
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/mnist"
+)
+
+func main() {
+	modelFile := flag.String("model", "model.bin", "Modelldatei (binär oder JSON)")
+	dataset := flag.String("dataset", "", "Datensatz: "+strings.Join(mnist.DatasetNames(), ", ")+" (leer: der des Modells)")
+	imageFile := flag.String("images", "", "IDX-Datei mit den Bildern, auch .gz (leer: Testbilder des Datensatzes)")
+	labelFile := flag.String("labels", "", "IDX-Datei mit den Labels, auch .gz (leer: Testlabels des Datensatzes)")
+	outDir := flag.String("o", "errors", "Ausgabeverzeichnis")
+	cols := flag.Int("cols", 16, "Anzahl der Bilder je Zeile eines Kontaktabzugs")
+	perSheet := flag.Int("per-sheet", 256, "Höchstzahl der Bilder je Kontaktabzug")
+	scale := flag.Int("scale", 3, "Vergrößerung der 28x28-Bilder")
+	workers := flag.Int("workers", 0, "Anzahl der Goroutinen (0: alle CPUs)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Aufruf: %s [Optionen] [modell]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.NArg() == 1 {
+		*modelFile = flag.Arg(0)
+	}
+	if *cols < 1 || *perSheet < 1 || *scale < 1 {
+		log.Fatalf("-cols, -per-sheet und -scale müssen mindestens 1 sein")
+	}
+
+	files, err := evaldata.Resolve(*modelFile, *dataset, *imageFile, *labelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g, err := collect(files, *workers)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	layout := layout{cols: *cols, perSheet: *perSheet, scale: *scale}
+	if err := g.write(*outDir, layout); err != nil {
+		log.Fatalf("Fehler beim Schreiben der Galerie: %v", err)
+	}
+	fmt.Printf("%d von %d Bildern falsch klassifiziert (Genauigkeit %.2f%%), Übersicht in %s\n",
+		len(g.Misclassified), g.Samples, g.Accuracy*100, filepath.Join(*outDir, "index.html"))
+}
+
+// gallery enthält die falsch klassifizierten Bilder eines Modells absteigend
+// nach Konfidenz sortiert.
+type gallery struct {
+	Model   string
+	Dataset string
+	Images  string
+	*mlp.Report
+	// pixels enthält die Bilder des Datensatzes wie mnist.Dataset.Pixels.
+	pixels     []byte
+	rows, cols int
+}
+
+// collect lädt das Modell und die Daten files und sammelt die falsch
+// klassifizierten Bilder.
+func collect(files *evaldata.Files, workers int) (*gallery, error) {
+	r, err := evaldata.Evaluate(files, 1, 1, workers)
+	if err != nil {
+		return nil, err
+	}
+	sortByConfidence(r.Misclassified)
+	return &gallery{
+		Model:   files.Model,
+		Dataset: files.Info.Name,
+		Images:  files.Images,
+		Report:  r.Report,
+		pixels:  r.Pixels,
+		rows:    r.Rows,
+		cols:    r.Cols,
+	}, nil
+}
+
+// sortByConfidence sortiert die Fehler ms absteigend nach Konfidenz. Bei
+// gleicher Konfidenz bleibt die Reihenfolge nach Index erhalten.
+func sortByConfidence(ms []mlp.Misclassified) {
+	sort.SliceStable(ms, func(a, b int) bool {
+		return ms[a].Confidence > ms[b].Confidence
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/mnist"
+)
+
+// testGallery erzeugt eine Galerie mit n Fehlern auf 28x28-Bildern aus 100
+// Testbildern. Fehler k hat den Index 2k+1, die wahre Klasse k und die
+// vorhergesagte Klasse k+1 (jeweils modulo der Anzahl der Klassen).
+func testGallery(n int, classes []string) *gallery {
+	g := &gallery{
+		Model:   "model.bin",
+		Dataset: "mnist",
+		Images:  "t10k-images-idx3-ubyte",
+		Report:  &mlp.Report{Samples: 100, Classes: classes, Accuracy: 1 - float64(n)/100},
+		pixels:  make([]byte, 100*mnist.Rows*mnist.Cols),
+		rows:    mnist.Rows,
+		cols:    mnist.Cols,
+	}
+	for i := range g.pixels {
+		g.pixels[i] = byte(i)
+	}
+	for k := 0; k < n; k++ {
+		g.Misclassified = append(g.Misclassified, mlp.Misclassified{
+			Index:      2*k + 1,
+			True:       k % len(classes),
+			Predicted:  (k + 1) % len(classes),
+			Confidence: 1 - float64(k)/100,
+		})
+	}
+	return g
+}
+
+func TestSortByConfidence(t *testing.T) {
+	ms := []mlp.Misclassified{
+		{Index: 1, Confidence: 0.5},
+		{Index: 3, Confidence: 0.9},
+		{Index: 4, Confidence: 0.5},
+		{Index: 7, Confidence: 0.99},
+		{Index: 8, Confidence: 0.9},
+		{Index: 9, Confidence: 0.3},
+	}
+	sortByConfidence(ms)
+	var got []int
+	for _, m := range ms {
+		got = append(got, m.Index)
+	}
+	// gleiche Konfidenz: aufsteigend nach Index wie von Report geliefert
+	if want := []int{7, 3, 8, 1, 4, 9}; !slices.Equal(got, want) {
+		t.Errorf("Reihenfolge %v, erwartet %v", got, want)
+	}
+}
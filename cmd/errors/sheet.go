@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// layout beschreibt die Aufteilung der Fehler auf Kontaktabzüge.
+type layout struct {
+	cols, perSheet, scale int
+}
+
+// Abstand um jedes Bild eines Kontaktabzugs und Farben der Beschriftung
+const pad = 4
+
+var (
+	background = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	trueColor  = color.RGBA{0x4c, 0xd9, 0x64, 0xff}
+	predColor  = color.RGBA{0xff, 0x5a, 0x5a, 0xff}
+	confColor  = color.RGBA{0xd0, 0xd0, 0xd0, 0xff}
+)
+
+// sheet ist ein Kontaktabzug mit den Positionen seiner Bilder.
+type sheet struct {
+	File  string
+	Tiles []tile
+}
+
+// tile ist die Position eines Bildes mit Beschriftung auf einem Kontaktabzug.
+type tile struct {
+	Rect  image.Rectangle
+	Index int
+}
+
+// write speichert die Kontaktabzüge, die einzelnen Bilder und die Übersicht
+// index.html im Verzeichnis dir.
+func (g *gallery) write(dir string, l layout) error {
+	if err := os.MkdirAll(filepath.Join(dir, "img"), 0o755); err != nil {
+		return err
+	}
+	var sheets []sheet
+	for start, n := 0, 1; start < len(g.Misclassified); start, n = start+l.perSheet, n+1 {
+		end := min(start+l.perSheet, len(g.Misclassified))
+		img, tiles := g.contactSheet(start, end, l)
+		s := sheet{File: fmt.Sprintf("sheet_%d.png", n), Tiles: tiles}
+		if err := writePNG(filepath.Join(dir, s.File), img); err != nil {
+			return err
+		}
+		sheets = append(sheets, s)
+	}
+	for _, m := range g.Misclassified {
+		if err := writePNG(filepath.Join(dir, imageFile(m.Index)), g.digit(m.Index, l.scale)); err != nil {
+			return err
+		}
+	}
+	return g.writeIndex(filepath.Join(dir, "index.html"), sheets)
+}
+
+// imageFile ist der Name des einzelnen Bildes mit Index i relativ zum
+// Ausgabeverzeichnis.
+func imageFile(i int) string {
+	return fmt.Sprintf("img/%d.png", i)
+}
+
+// contactSheet zeichnet die Fehler start bis end-1 in einem Raster mit l.cols
+// Spalten. Unter jedem Bild stehen drei Zeilen: die wahre Klasse, die
+// Vorhersage und deren Konfidenz.
+func (g *gallery) contactSheet(start, end int, l layout) (*image.RGBA, []tile) {
+	face := basicfont.Face7x13
+	w, h := g.cols*l.scale, g.rows*l.scale
+	cellW, cellH := w+2*pad, h+3*face.Height+2*pad
+	n := end - start
+	cols := min(l.cols, n)
+	rows := (n + cols - 1) / cols
+
+	img := image.NewRGBA(image.Rect(0, 0, cols*cellW, rows*cellH))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	chars := w / face.Advance
+	tiles := make([]tile, n)
+	for k, m := range g.Misclassified[start:end] {
+		x, y := k%cols*cellW, k/cols*cellH
+		r := image.Rect(x, y, x+cellW, y+cellH)
+		tiles[k] = tile{Rect: r, Index: m.Index}
+
+		digit := g.digit(m.Index, l.scale)
+		draw.Draw(img, image.Rect(x+pad, y+pad, x+pad+w, y+pad+h), digit, image.Point{}, draw.Src)
+		lines := []struct {
+			text string
+			col  color.Color
+		}{
+			{g.label(m.True), trueColor},
+			{g.label(m.Predicted), predColor},
+			{fmt.Sprintf("%.1f%%", m.Confidence*100), confColor},
+		}
+		for i, line := range lines {
+			text := line.text
+			if len(text) > chars {
+				text = text[:chars]
+			}
+			d := font.Drawer{
+				Dst:  img,
+				Src:  image.NewUniform(line.col),
+				Face: face,
+				Dot:  fixed.P(x+pad, y+pad+h+i*face.Height+face.Ascent),
+			}
+			d.DrawString(text)
+		}
+	}
+	return img, tiles
+}
+
+// digit liefert das Bild mit Index i um scale vergrößert.
+func (g *gallery) digit(i, scale int) *image.Gray {
+	size := g.rows * g.cols
+	pixels := g.pixels[i*size : (i+1)*size]
+	img := image.NewGray(image.Rect(0, 0, g.cols*scale, g.rows*scale))
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			img.Pix[y*img.Stride+x] = pixels[y/scale*g.cols+x/scale]
+		}
+	}
+	return img
+}
+
+// label liefert den Namen der Klasse c für die Beschriftung. Die Schrift
+// enthält nur ASCII-Zeichen, andere Namen (z. B. bei KMNIST) werden durch die
+// Nummer der Klasse ersetzt.
+func (g *gallery) label(c int) string {
+	name := g.Classes[c]
+	for _, r := range name {
+		if r < ' ' || r > '~' {
+			return strconv.Itoa(c)
+		}
+	}
+	return name
+}
+
+// writePNG speichert img als PNG-Datei.
+func writePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+
+	"grimm.world/mlp_demo/mlp/mnist"
+)
+
+func TestContactSheet(t *testing.T) {
+	face := basicfont.Face7x13
+	const scale = 2
+	cellW, cellH := mnist.Cols*scale+2*pad, mnist.Rows*scale+3*face.Height+2*pad
+	for _, tc := range []struct {
+		start, end, cols int
+		// Größe des Rasters
+		wantCols, wantRows int
+	}{
+		{0, 1, 16, 1, 1},
+		// weniger Bilder als Spalten: das Raster wird schmaler
+		{0, 3, 16, 3, 1},
+		{0, 4, 4, 4, 1},
+		{0, 5, 4, 4, 2},
+		{0, 8, 4, 4, 2},
+		{0, 9, 4, 4, 3},
+		// ein späterer Kontaktabzug
+		{6, 9, 2, 2, 2},
+	} {
+		g := testGallery(10, mnist.Classes)
+		img, tiles := g.contactSheet(tc.start, tc.end, layout{cols: tc.cols, perSheet: 100, scale: scale})
+		if want := image.Rect(0, 0, tc.wantCols*cellW, tc.wantRows*cellH); img.Bounds() != want {
+			t.Errorf("%d bis %d in %d Spalten: Bild %v, erwartet %v", tc.start, tc.end, tc.cols, img.Bounds(), want)
+		}
+		if len(tiles) != tc.end-tc.start {
+			t.Fatalf("%d bis %d: %d Bilder, erwartet %d", tc.start, tc.end, len(tiles), tc.end-tc.start)
+		}
+		for k, got := range tiles {
+			x, y := k%tc.wantCols*cellW, k/tc.wantCols*cellH
+			want := tile{Rect: image.Rect(x, y, x+cellW, y+cellH), Index: g.Misclassified[tc.start+k].Index}
+			if got != want {
+				t.Errorf("%d bis %d in %d Spalten: Bild %d an %v, erwartet %v", tc.start, tc.end, tc.cols, k, got, want)
+			}
+		}
+	}
+}
+
+func TestContactSheetTruncatesLabels(t *testing.T) {
+	// Bei Vergrößerung 1 passen 28/7 = 4 Zeichen unter ein Bild, längere
+	// Namen werden abgeschnitten und reichen nicht in die Nachbarzelle.
+	face := basicfont.Face7x13
+	g := testGallery(2, []string{"T-shirt/top", "Trouser", "Pullover"})
+	img, _ := g.contactSheet(0, 2, layout{cols: 2, perSheet: 2, scale: 1})
+	cellW := mnist.Cols + 2*pad
+	chars := mnist.Cols / face.Advance
+	text := 0
+	for y := pad + mnist.Rows; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < cellW+pad; x++ {
+			if img.RGBAAt(x, y) == background {
+				continue
+			}
+			if x >= pad+chars*face.Advance {
+				t.Fatalf("Beschriftung der ersten Zelle reicht bis (%d, %d)", x, y)
+			}
+			text++
+		}
+	}
+	if text == 0 {
+		t.Error("keine Beschriftung unter dem ersten Bild")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	g := testGallery(1, []string{"o", "お", "ki"})
+	for c, want := range []string{"o", "1", "ki"} {
+		if got := g.label(c); got != want {
+			t.Errorf("label(%d) = %q, erwartet %q", c, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Das Programm evaluate wertet ein gespeichertes Modell ausführlich auf den
+// Testdaten seines Datensatzes aus (bei MNIST die 10000 t10k-Bilder): die
+// Konfusionsmatrix, Precision, Recall und F1 je Klasse mit Makro- und
+// Mikro-Mittel, die häufigsten Verwechslungen, die Top-k-Genauigkeit, den
+// Log-Loss und den erwarteten Kalibrierungsfehler (ECE).
+//
+//	evaluate -model model.bin
+//	evaluate -format json -o report.json
+//	evaluate -format csv -top-k 3 cnn.bin
+//
+// Die Ausgabe erfolgt als Texttabellen, JSON oder CSV. Mit -xlsx entsteht
+// zusätzlich eine Excel-Arbeitsmappe mit den Hyperparametern des Modells, der
+// Konfusionsmatrix als Heatmap, den Kennzahlen und allen falsch
+// klassifizierten Testbildern.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"grimm.world/mlp_demo/internal/evaldata"
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/excel"
+	"grimm.world/mlp_demo/mlp/mnist"
+)
+
+// Ausgabeformate
+var formats = []string{"text", "json", "csv"}
+
+func main() {
+	modelFile := flag.String("model", "model.bin", "Modelldatei (binär oder JSON)")
+	dataset := flag.String("dataset", "", "Datensatz: "+strings.Join(mnist.DatasetNames(), ", ")+" (leer: der des Modells)")
+	imageFile := flag.String("images", "", "IDX-Datei mit den Bildern, auch .gz (leer: Testbilder des Datensatzes)")
+	labelFile := flag.String("labels", "", "IDX-Datei mit den Labels, auch .gz (leer: Testlabels des Datensatzes)")
+	format := flag.String("format", "text", "Ausgabeformat: "+strings.Join(formats, ", "))
+	output := flag.String("o", "", "Ausgabedatei (leer: Standardausgabe)")
+	xlsxFile := flag.String("xlsx", "", "Excel-Arbeitsmappe mit dem Bericht (leer: keine)")
+	topK := flag.Int("top-k", 5, "Top-k-Genauigkeit für k = 1 bis zu diesem Wert berechnen")
+	bins := flag.Int("bins", 15, "Anzahl der Konfidenzintervalle für den Kalibrierungsfehler")
+	confusions := flag.Int("confusions", 10, "Anzahl der häufigsten Verwechslungen in der Textausgabe")
+	workers := flag.Int("workers", 0, "Anzahl der Goroutinen (0: alle CPUs)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Aufruf: %s [Optionen] [modell]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.NArg() == 1 {
+		*modelFile = flag.Arg(0)
+	}
+	var write func(io.Writer, *evaluation) error
+	switch *format {
+	case "text":
+		write = func(w io.Writer, e *evaluation) error { return writeText(w, e, *confusions) }
+	case "json":
+		write = writeJSON
+	case "csv":
+		write = writeCSV
+	default:
+		log.Fatalf("unbekanntes Ausgabeformat %q, erlaubt sind %s", *format, strings.Join(formats, ", "))
+	}
+	if *topK < 1 || *bins < 1 {
+		log.Fatalf("-top-k und -bins müssen mindestens 1 sein")
+	}
+
+	files, err := evaldata.Resolve(*modelFile, *dataset, *imageFile, *labelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	e, err := evaluate(files, options{topK: *topK, bins: *bins, workers: *workers})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, e); err != nil {
+		log.Fatalf("Fehler beim Schreiben des Berichts: %v", err)
+	}
+
+	if *xlsxFile != "" {
+		run := &excel.Run{
+			Title:           fmt.Sprintf("%s auf %s (%s)", e.Model, e.Dataset, e.Images),
+			Hyperparameters: files.Meta.Hyperparameters,
+			Report:          e.Report,
+		}
+		if err := excel.Write(*xlsxFile, run); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// options sind die Einstellungen der Auswertung.
+type options struct {
+	topK, bins, workers int
+}
+
+// evaluation ist das Ergebnis der Auswertung eines Modells auf einem Datensatz.
+type evaluation struct {
+	Model   string `json:"model"`
+	Dataset string `json:"dataset"`
+	Images  string `json:"images"`
+	*mlp.Report
+}
+
+// evaluate lädt das Modell und die Daten files und wertet das Modell darauf
+// aus.
+func evaluate(files *evaldata.Files, opts options) (*evaluation, error) {
+	r, err := evaldata.Evaluate(files, opts.topK, opts.bins, opts.workers)
+	if err != nil {
+		return nil, err
+	}
+	return &evaluation{
+		Model:   files.Model,
+		Dataset: files.Info.Name,
+		Images:  files.Images,
+		Report:  r.Report,
+	}, nil
+}
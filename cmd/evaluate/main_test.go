@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"grimm.world/mlp_demo/internal/evaldata"
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/mnist"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+// Das Modell der Testdaten sagt unabhängig vom Bild die Wahrscheinlichkeiten
+// probs vorher, also immer Klasse 0 mit Konfidenz 0.45.
+var (
+	probs  = []float64{0.45, 0.3, 0.1, 0.15 / 7, 0.15 / 7, 0.15 / 7, 0.15 / 7, 0.15 / 7, 0.15 / 7, 0.15 / 7}
+	labels = []byte{0, 0, 0, 1, 1, 2, 3, 4, 5, 6}
+)
+
+// Kennzahlen der Testdaten: 3 von 10 Labels sind 0, 5 unter den zwei und 6
+// unter den drei wahrscheinlichsten Klassen.
+var (
+	wantTopK    = []float64{0.3, 0.5, 0.6}
+	wantLogLoss = -(3*math.Log(0.45) + 2*math.Log(0.3) + math.Log(0.1) + 4*math.Log(0.15/7)) / 10
+	wantECE     = 0.45 - 0.3
+)
+
+// testEvaluation schreibt Testdaten und ein Modell, das immer probs
+// vorhersagt, in ein temporäres Verzeichnis und wertet es mit evaluate aus.
+func testEvaluation(t *testing.T) *evaluation {
+	t.Helper()
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(1))
+	pixels := make([]byte, len(labels)*mnist.Rows*mnist.Cols)
+	rng.Read(pixels)
+	imageFile, labelFile := filepath.Join(dir, "images.idx"), filepath.Join(dir, "labels.idx")
+	if err := mnist.WriteImages(imageFile, pixels, mnist.Rows, mnist.Cols); err != nil {
+		t.Fatal(err)
+	}
+	if err := mnist.WriteLabels(labelFile, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	// ohne Gewichte bestimmt allein der Bias die Ausgabe der Softmax
+	m := mlp.NewMLP[float64](rng, []int{mnist.Rows * mnist.Cols, 10})
+	clear(m.Layers[0].W.Data)
+	for i, p := range probs {
+		m.Layers[0].B[i] = math.Log(p)
+	}
+	modelFile := filepath.Join(dir, "model.bin")
+	if err := modelio.Save(modelFile, m, &modelio.Metadata{Dataset: mnist.MNIST.Name}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := evaldata.Resolve(modelFile, "", imageFile, labelFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := evaluate(files, options{topK: 3, bins: 5, workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// near gibt an, ob a und b bis auf Rundungsfehler gleich sind.
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestEvaluate(t *testing.T) {
+	e := testEvaluation(t)
+	if e.Samples != len(labels) || e.Dataset != mnist.MNIST.Name || !slices.Equal(e.Classes, mnist.Classes) {
+		t.Errorf("%d Beispiele aus %s mit Klassen %v", e.Samples, e.Dataset, e.Classes)
+	}
+	if !near(e.Accuracy, wantTopK[0]) {
+		t.Errorf("Genauigkeit %g, erwartet %g", e.Accuracy, wantTopK[0])
+	}
+	if len(e.TopK) != len(wantTopK) {
+		t.Fatalf("Top-k %v, erwartet %v", e.TopK, wantTopK)
+	}
+	for k, want := range wantTopK {
+		if !near(e.TopK[k], want) {
+			t.Errorf("Top-%d %g, erwartet %g", k+1, e.TopK[k], want)
+		}
+	}
+	if !near(e.LogLoss, wantLogLoss) {
+		t.Errorf("Log-Loss %g, erwartet %g", e.LogLoss, wantLogLoss)
+	}
+	if !near(e.ECE, wantECE) {
+		t.Errorf("ECE %g, erwartet %g", e.ECE, wantECE)
+	}
+	// alle Beispiele im Intervall [0.4, 0.6)
+	if len(e.Calibration) != 5 || e.Calibration[2].Count != len(labels) || !near(e.Calibration[2].Accuracy, 0.3) {
+		t.Errorf("Kalibrierung %+v", e.Calibration)
+	}
+	if len(e.Misclassified) != 7 {
+		t.Errorf("%d falsch klassifiziert, erwartet 7", len(e.Misclassified))
+	}
+}
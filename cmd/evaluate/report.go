@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// writeText schreibt den Bericht als Texttabellen mit den n häufigsten
+// Verwechslungen.
+func writeText(w io.Writer, e *evaluation, n int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Modell %s, Datensatz %s (%s), %d Beispiele\n\n", e.Model, e.Dataset, e.Images, e.Samples)
+
+	fmt.Fprintln(tw, "Konfusionsmatrix (Zeilen: wahre Klasse, Spalten: Vorhersage)")
+	fmt.Fprint(tw, "\t")
+	for _, c := range e.Classes {
+		fmt.Fprintf(tw, "%s\t", c)
+	}
+	fmt.Fprintln(tw)
+	for i, row := range e.Confusion {
+		fmt.Fprintf(tw, "%s\t", e.Classes[i])
+		for _, v := range row {
+			fmt.Fprintf(tw, "%d\t", v)
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Klasse\tPrecision\tRecall\tF1\tSupport\t")
+	for _, c := range e.PerClass {
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%.4f\t%d\t\n", c.Class, c.Precision, c.Recall, c.F1, c.Support)
+	}
+	fmt.Fprintf(tw, "Makro\t%.4f\t%.4f\t%.4f\t%d\t\n", e.Macro.Precision, e.Macro.Recall, e.Macro.F1, e.Samples)
+	fmt.Fprintf(tw, "Mikro\t%.4f\t%.4f\t%.4f\t%d\t\n", e.Micro.Precision, e.Micro.Recall, e.Micro.F1, e.Samples)
+	fmt.Fprintln(tw)
+
+	if top := e.TopConfusions(n); len(top) > 0 {
+		fmt.Fprintln(tw, "Häufigste Verwechslungen")
+		fmt.Fprintln(tw, "wahr\tvorhergesagt\tAnzahl\t")
+		for _, c := range top {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t\n", c.True, c.Predicted, c.Count)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Genauigkeit\t%.2f%%\n", e.Accuracy*100)
+	for k, acc := range e.TopK[1:] {
+		fmt.Fprintf(tw, "Top-%d\t%.2f%%\n", k+2, acc*100)
+	}
+	fmt.Fprintf(tw, "Log-Loss\t%.4f\n", e.LogLoss)
+	fmt.Fprintf(tw, "ECE (%d Intervalle)\t%.4f\n", len(e.Calibration), e.ECE)
+	return tw.Flush()
+}
+
+// writeJSON schreibt den Bericht als eingerücktes JSON.
+func writeJSON(w io.Writer, e *evaluation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// writeCSV schreibt den Bericht als CSV in vier durch Leerzeilen getrennten
+// Tabellen mit Kopfzeile: Konfusionsmatrix, Kennzahlen je Klasse,
+// Gesamtkennzahlen und Kalibrierung.
+func writeCSV(w io.Writer, e *evaluation) error {
+	cw := csv.NewWriter(w)
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+	cw.Write(append([]string{"true\\predicted"}, e.Classes...))
+	for i, row := range e.Confusion {
+		rec := []string{e.Classes[i]}
+		for _, v := range row {
+			rec = append(rec, strconv.Itoa(v))
+		}
+		cw.Write(rec)
+	}
+
+	cw.Write(nil)
+	cw.Write([]string{"class", "precision", "recall", "f1", "support"})
+	for _, c := range e.PerClass {
+		cw.Write([]string{c.Class, f(c.Precision), f(c.Recall), f(c.F1), strconv.Itoa(c.Support)})
+	}
+	cw.Write([]string{"macro", f(e.Macro.Precision), f(e.Macro.Recall), f(e.Macro.F1), strconv.Itoa(e.Samples)})
+	cw.Write([]string{"micro", f(e.Micro.Precision), f(e.Micro.Recall), f(e.Micro.F1), strconv.Itoa(e.Samples)})
+
+	cw.Write(nil)
+	cw.Write([]string{"metric", "value"})
+	cw.Write([]string{"samples", strconv.Itoa(e.Samples)})
+	cw.Write([]string{"accuracy", f(e.Accuracy)})
+	for k, acc := range e.TopK {
+		cw.Write([]string{fmt.Sprintf("top_%d", k+1), f(acc)})
+	}
+	cw.Write([]string{"log_loss", f(e.LogLoss)})
+	cw.Write([]string{"ece", f(e.ECE)})
+
+	cw.Write(nil)
+	cw.Write([]string{"lower", "upper", "count", "confidence", "accuracy"})
+	for _, b := range e.Calibration {
+		cw.Write([]string{f(b.Lower), f(b.Upper), strconv.Itoa(b.Count), f(b.Confidence), f(b.Accuracy)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
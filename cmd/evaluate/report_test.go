@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	e := testEvaluation(t)
+	var buf bytes.Buffer
+	if err := writeText(&buf, e, 3); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, pattern := range []string{
+		`Modell .*model\.bin, Datensatz mnist \(.*images\.idx\), 10 Beispiele`,
+		`(?m)^\s+0\s+1\s+2\s+3\s+4\s+5\s+6\s+7\s+8\s+9$`,
+		// Zeile der wahren Klasse 1: beide als 0 erkannt
+		`(?m)^\s+1\s+2\s+0\s+0\s+0\s+0\s+0\s+0\s+0\s+0\s+0$`,
+		`Genauigkeit\s+30\.00%`,
+		`Top-2\s+50\.00%`,
+		`Top-3\s+60\.00%`,
+		`Log-Loss\s+` + regexp.QuoteMeta(fmt.Sprintf("%.4f", wantLogLoss)),
+		`ECE \(5 Intervalle\)\s+0\.1500`,
+		`Häufigste Verwechslungen`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(text) {
+			t.Errorf("Text enthält nicht %s:\n%s", pattern, text)
+		}
+	}
+	// nur die drei häufigsten Verwechslungen
+	if n := strings.Count(text[strings.Index(text, "Häufigste"):strings.Index(text, "Genauigkeit")], "\n"); n != 2+3+1 {
+		t.Errorf("%d Zeilen im Abschnitt der Verwechslungen, erwartet 6", n)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	e := testEvaluation(t)
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, e); err != nil {
+		t.Fatal(err)
+	}
+	var got evaluation
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Model != e.Model || got.Dataset != "mnist" || got.Images != e.Images || got.Samples != 10 {
+		t.Errorf("Kopf %q %q %q, %d Beispiele", got.Model, got.Dataset, got.Images, got.Samples)
+	}
+	if !near(got.Accuracy, 0.3) || len(got.TopK) != 3 || !near(got.TopK[2], 0.6) || !near(got.LogLoss, wantLogLoss) || !near(got.ECE, wantECE) {
+		t.Errorf("Kennzahlen %g %v %g %g", got.Accuracy, got.TopK, got.LogLoss, got.ECE)
+	}
+	if len(got.Misclassified) != 7 || got.Confusion[1][0] != 2 {
+		t.Errorf("%d Fehler, Konfusionsmatrix %v", len(got.Misclassified), got.Confusion)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	e := testEvaluation(t)
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, e); err != nil {
+		t.Fatal(err)
+	}
+	// vier Tabellen, getrennt durch Leerzeilen
+	tables := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n\n")
+	if len(tables) != 4 {
+		t.Fatalf("%d Tabellen, erwartet 4:\n%s", len(tables), buf.String())
+	}
+	var records [][][]string
+	for _, table := range tables {
+		r := csv.NewReader(strings.NewReader(table))
+		rec, err := r.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+
+	confusion, perClass, metrics, calibration := records[0], records[1], records[2], records[3]
+	if len(confusion) != 11 || len(confusion[0]) != 11 || confusion[2][1] != "2" {
+		t.Errorf("Konfusionsmatrix %v", confusion)
+	}
+	if len(perClass) != 1+10+2 || perClass[11][0] != "macro" || perClass[12][0] != "micro" {
+		t.Errorf("Kennzahlen je Klasse %v", perClass)
+	}
+	if len(calibration) != 1+5 || calibration[3][2] != "10" {
+		t.Errorf("Kalibrierung %v", calibration)
+	}
+
+	want := map[string]float64{
+		"samples": 10, "accuracy": 0.3,
+		"top_1": 0.3, "top_2": 0.5, "top_3": 0.6,
+		"log_loss": wantLogLoss, "ece": wantECE,
+	}
+	if len(metrics) != 1+len(want) || metrics[0][0] != "metric" {
+		t.Fatalf("Gesamtkennzahlen %v", metrics)
+	}
+	for _, rec := range metrics[1:] {
+		v, err := strconv.ParseFloat(rec[1], 64)
+		if w, ok := want[rec[0]]; !ok || err != nil || !near(v, w) {
+			t.Errorf("%s = %s, erwartet %g", rec[0], rec[1], w)
+		}
+	}
+}
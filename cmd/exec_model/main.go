@@ -0,0 +1,53 @@
+// Das Programm exec_model ist ein einfacher Inferenz-Client: Es lädt model.bin
+// (oder die mit -model angegebene Datei, auch im alten JSON-Format) und erkennt
+// die Ziffer (bzw. die Klasse des Datensatzes, auf dem es trainiert wurde) im
+// Bild digit.png.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"grimm.world/mlp_demo/mlp/mnist"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+func main() {
+	modelFile := flag.String("model", "model.bin", "Modelldatei (binär oder JSON)")
+	flag.Parse()
+
+	// Beispiel: Wir laden das Modell "model.bin"
+	predict, meta, err := modelio.LoadPredictor(*modelFile)
+	if err != nil {
+		log.Fatalf("Fehler beim Laden des Modells: %v", err)
+	}
+	fmt.Printf("Modell erfolgreich geladen: Architektur %v, %s", meta.Architecture, meta.Precision)
+	if meta.Dataset != "" {
+		fmt.Printf(", Datensatz %s", meta.Dataset)
+	}
+	if meta.Conv != nil {
+		fmt.Printf(", Faltungsteil %v", meta.Conv)
+	}
+	if meta.Norms != nil {
+		fmt.Printf(", Normalisierungen %q", meta.Norms)
+	}
+	if meta.TestAccuracy > 0 {
+		fmt.Printf(", Testgenauigkeit %.2f%%", meta.TestAccuracy*100)
+	}
+	if meta.CreatedAt != "" {
+		fmt.Printf(", erstellt %s", meta.CreatedAt)
+	}
+	fmt.Println()
+
+	// Laden eines einzelnen 28x28 PNG-Bildes, z. B. "digit.png"
+	pixels, err := mnist.LoadGray("digit.png")
+	if err != nil {
+		log.Fatalf("Fehler beim Laden des Eingabebildes: %v", err)
+	}
+	fmt.Println("Eingabebild erfolgreich geladen.")
+
+	// Vorhersage treffen
+	class := predict(pixels)
+	fmt.Printf("Das Modell erkennt das Bild als: %s\n", meta.ClassName(class))
+}
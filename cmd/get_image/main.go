@@ -0,0 +1,160 @@
+// Dieses Programm nimmt einen Index aus den CLI-Argumenten, lädt dieses Bild aus den MNIST Testdaten
+// und speichert es als PNG ab. Außerdem wird das zugehörige Label auf der Konsole ausgegeben.
+// Mit -dataset stammt das Bild aus den Testdaten eines anderen Datensatzes, EMNIST-Bilder
+// werden dabei wie im Training aufrecht gedreht.
+//
+// Mit -augment werden zusätzlich -n zufällig augmentierte Varianten des Bildes als
+// digit_aug_<i>.png gespeichert, um die Augmentierung des Trainings zu prüfen. Die
+// Einstellungen stammen aus dem Abschnitt augmentation einer Trainingskonfiguration
+// (-config, z. B. model.config.yaml), sonst aus mnist.DefaultAugmentation.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"grimm.world/mlp_demo/mlp/mnist"
+)
+
+func main() {
+	// Index als CLI-Argument einlesen
+	var index int
+	flag.IntVar(&index, "index", 0, "Index des MNIST-Bildes, das extrahiert werden soll")
+	dataset := flag.String("dataset", mnist.MNIST.Name, "Datensatz: "+strings.Join(mnist.DatasetNames(), ", "))
+	imageFile := flag.String("images", "", "IDX-Datei mit den Bildern, auch .gz (leer: Testbilder des Datensatzes)")
+	labelFile := flag.String("labels", "", "IDX-Datei mit den Labels, auch .gz (leer: Testlabels des Datensatzes)")
+	augment := flag.Bool("augment", false, "Zusätzlich augmentierte Varianten des Bildes speichern")
+	count := flag.Int("n", 8, "Anzahl der augmentierten Varianten")
+	seed := flag.Int64("seed", 1, "Seed für die Augmentierung")
+	configFile := flag.String("config", "", "Trainingskonfiguration (YAML oder JSON) mit den Einstellungen der Augmentierung")
+	flag.Parse()
+
+	// Wenn kein -index Argument gegeben ist, versuchen wir den ersten CLI-Argument ohne Flag zu nehmen
+	if flag.NArg() > 0 {
+		var err error
+		index, err = atoi(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("Fehler beim Parsen des Index: %v", err)
+		}
+	}
+
+	info, err := mnist.Lookup(*dataset)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *imageFile == "" {
+		*imageFile = info.Path(info.TestImages)
+	}
+	if *labelFile == "" {
+		*labelFile = info.Path(info.TestLabels)
+	}
+	data, err := mnist.OpenDataset[float64](info, *imageFile, *labelFile)
+	if err != nil {
+		log.Fatalf("Fehler beim Laden der Bilder: %v", err)
+	}
+	if index < 0 || index >= data.Len() {
+		log.Fatalf("Index außerhalb der Reichweite. Anzahl Bilder: %d", data.Len())
+	}
+	size := data.Rows * data.Cols
+	imgData := data.Pixels[index*size : (index+1)*size]
+	label := info.Classes[data.Class(index)]
+
+	if err := writePNG("digit.png", imgData); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Bild mit Index %d wurde als 'digit.png' gespeichert. Label: %s\n", index, label)
+
+	if !*augment {
+		return
+	}
+	aug := mnist.DefaultAugmentation()
+	if *configFile != "" {
+		if err := loadAugmentation(*configFile, &aug); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := aug.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	pixels := make([]float64, len(imgData))
+	for i := 0; i < *count; i++ {
+		for p, val := range imgData {
+			pixels[p] = float64(val) * mnist.Scale
+		}
+		mnist.Augment(rng, &aug, pixels)
+		out := make([]byte, len(pixels))
+		for p, v := range pixels {
+			out[p] = byte(v*255 + 0.5)
+		}
+		name := fmt.Sprintf("digit_aug_%d.png", i)
+		if err := writePNG(name, out); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("%d augmentierte Varianten als 'digit_aug_<i>.png' gespeichert.\n", *count)
+}
+
+// writePNG speichert die 784 Pixel (0 bis 255, zeilenweise) als 28x28-Graustufen-PNG.
+func writePNG(filename string, pixels []byte) error {
+	// Erstellen wir ein Grau-Bild und schreiben es als PNG
+	img := image.NewGray(image.Rect(0, 0, 28, 28))
+	for i, val := range pixels {
+		// val ist ein Byte von 0 bis 255
+		x := i % 28
+		y := i / 28
+		img.SetGray(x, y, color.Gray{Y: val})
+	}
+
+	outFile, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Erstellen der Ausgabedatei: %v", err)
+	}
+	defer outFile.Close()
+
+	if err := png.Encode(outFile, img); err != nil {
+		return fmt.Errorf("Fehler beim Schreiben des PNG: %v", err)
+	}
+	return nil
+}
+
+// loadAugmentation liest den Abschnitt augmentation der Trainingskonfiguration
+// filename (JSON bei der Endung .json, sonst YAML) nach aug. Nicht angegebene
+// Felder behalten ihren Wert.
+func loadAugmentation(filename string, aug *mnist.Augmentation) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Konfiguration: %v", err)
+	}
+	cfg := struct {
+		Augmentation *mnist.Augmentation `json:"augmentation" yaml:"augmentation"`
+	}{aug}
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Konfiguration %s: %v", filename, err)
+	}
+	return nil
+}
+
+func atoi(s string) (int, error) {
+	var n int
+	_, err := fmt.Sscan(s, &n)
+	return n, err
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/mnist"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+// Config enthält alle Einstellungen eines Trainingslaufs. Sie stammen aus den
+// Standardwerten, beim Fortsetzen (-resume) der Konfiguration des Checkpoints,
+// einer optionalen YAML- oder JSON-Datei (-config) und den
+// Kommandozeilenoptionen, wobei jede Quelle die vorherigen überschreibt.
+type Config struct {
+	// Preset wählt eine vordefinierte Architektur (siehe presets), die Conv
+	// und Layers vorgibt, soweit sie nicht angegeben sind.
+	Preset string `json:"preset,omitempty" yaml:"preset,omitempty"`
+	// Conv ist der optionale Faltungsteil vor den Dense-Schichten; er muss
+	// mit flatten enden.
+	Conv []mlp.LayerSpec `json:"conv,omitempty" yaml:"conv,omitempty"`
+	// Layers ist die Anzahl der Neuronen je Dense-Schicht, beginnend mit der
+	// Eingabe des Netzes (auch mit Faltungsteil). Ohne Angabe 784, 512 und
+	// die Anzahl der Klassen des Datensatzes.
+	Layers []int `json:"layers" yaml:"layers"`
+	// Activations legt die Aktivierung jeder Schicht fest, leer für ReLU/Softmax.
+	Activations []string `json:"activations,omitempty" yaml:"activations,omitempty"`
+	// Norm ist die Normalisierung der versteckten Schichten (batchnorm oder
+	// layernorm), leer für keine.
+	Norm string `json:"norm,omitempty" yaml:"norm,omitempty"`
+
+	Epochs     int `json:"epochs" yaml:"epochs"`
+	BatchSize  int `json:"batch_size" yaml:"batch_size"`
+	EvalSubset int `json:"eval_subset" yaml:"eval_subset"`
+	// Seed initialisiert den Zufallsgenerator, 0 für einen zufälligen Seed.
+	Seed int64 `json:"seed" yaml:"seed"`
+	// Workers ist die Anzahl paralleler Goroutinen je Mini-Batch, 0 für GOMAXPROCS.
+	Workers int `json:"workers" yaml:"workers"`
+	// Precision ist die Genauigkeit von Daten und Modell, float32 oder float64.
+	Precision string `json:"precision" yaml:"precision"`
+
+	Regularization RegularizationConfig `json:"regularization" yaml:"regularization"`
+	// Augmentation verändert die Trainingsbilder in jedem Mini-Batch zufällig.
+	Augmentation mnist.Augmentation `json:"augmentation" yaml:"augmentation"`
+
+	// Validation ist der Anteil der Trainingsdaten, der nach Klassen
+	// geschichtet für die Validierung zurückgehalten wird, 0 für keine.
+	Validation    float64             `json:"validation" yaml:"validation"`
+	EarlyStopping EarlyStoppingConfig `json:"early_stopping" yaml:"early_stopping"`
+
+	Optimizer OptimizerConfig `json:"optimizer" yaml:"optimizer"`
+	Schedule  ScheduleConfig  `json:"schedule" yaml:"schedule"`
+	// Dataset ist der Name des Datensatzes (mnist.Lookup). Er bestimmt die
+	// Klassen und die Standardpfade in Data.
+	Dataset string     `json:"dataset" yaml:"dataset"`
+	Data    DataConfig `json:"data" yaml:"data"`
+
+	Output string `json:"output" yaml:"output"`
+	// XLSX ist eine optionale Excel-Arbeitsmappe mit Konfiguration, Verlauf
+	// und Auswertung auf den Testdaten, leer für keine.
+	XLSX       string `json:"xlsx,omitempty" yaml:"xlsx,omitempty"`
+	Checkpoint string `json:"checkpoint" yaml:"checkpoint"`
+	// CheckpointEvery ist der Abstand der Checkpoints in Epochen, 0 für
+	// Checkpoints nur bei Abbruch durch ein Signal.
+	CheckpointEvery int    `json:"checkpoint_every" yaml:"checkpoint_every"`
+	Resume          string `json:"resume,omitempty" yaml:"resume,omitempty"`
+}
+
+// RegularizationConfig enthält die Regularisierungen, 0 schaltet sie jeweils aus.
+type RegularizationConfig struct {
+	L1             float64 `json:"l1" yaml:"l1"`
+	L2             float64 `json:"l2" yaml:"l2"`
+	Dropout        float64 `json:"dropout" yaml:"dropout"`
+	LabelSmoothing float64 `json:"label_smoothing" yaml:"label_smoothing"`
+}
+
+// EarlyStoppingConfig legt fest, wann das Training mangels Verbesserung auf
+// den Validierungsdaten endet. Als bestes Modell gilt unabhängig von Metric
+// das mit der höchsten Validierungsgenauigkeit, ohne Validierung das mit der
+// höchsten Trainingsgenauigkeit.
+type EarlyStoppingConfig struct {
+	Metric   string  `json:"metric" yaml:"metric"`
+	Patience int     `json:"patience" yaml:"patience"`
+	MinDelta float64 `json:"min_delta" yaml:"min_delta"`
+}
+
+// OptimizerConfig wählt den Optimizer und seine Hyperparameter.
+type OptimizerConfig struct {
+	Name         string  `json:"name" yaml:"name"`
+	LearningRate float64 `json:"lr" yaml:"lr"`
+	Momentum     float64 `json:"momentum" yaml:"momentum"`
+	Beta1        float64 `json:"beta1" yaml:"beta1"`
+	Beta2        float64 `json:"beta2" yaml:"beta2"`
+	Rho          float64 `json:"rho" yaml:"rho"`
+	Epsilon      float64 `json:"eps" yaml:"eps"`
+	WeightDecay  float64 `json:"weight_decay" yaml:"weight_decay"`
+}
+
+// ScheduleConfig wählt den Lernraten-Schedule und seine Parameter.
+type ScheduleConfig struct {
+	Name       string  `json:"name" yaml:"name"`
+	Warmup     float64 `json:"warmup" yaml:"warmup"`
+	StepSize   float64 `json:"step_size" yaml:"step_size"`
+	Gamma      float64 `json:"gamma" yaml:"gamma"`
+	Period     float64 `json:"period" yaml:"period"`
+	PeriodMult float64 `json:"period_mult" yaml:"period_mult"`
+	MinLR      float64 `json:"min_lr" yaml:"min_lr"`
+	PctStart   float64 `json:"pct_start" yaml:"pct_start"`
+	Factor     float64 `json:"factor" yaml:"factor"`
+	Patience   int     `json:"patience" yaml:"patience"`
+	MinDelta   float64 `json:"min_delta" yaml:"min_delta"`
+}
+
+// preset ist eine vordefinierte Architektur: der Faltungsteil und die
+// Neuronen der versteckten Dense-Schichten.
+type preset struct {
+	conv   []mlp.LayerSpec
+	hidden []int
+}
+
+// presets sind die Architekturen für -preset.
+var presets = map[string]preset{
+	// LeNet-5 nach LeCun et al. (1998) mit ReLU, Max-Pooling und einem Rand
+	// von 2 in der ersten Faltung, so dass 28x28-Bilder wie die
+	// ursprünglichen 32x32-Bilder auf 16 Feature Maps mit 5x5 Werten führen.
+	"lenet": {
+		conv: []mlp.LayerSpec{
+			{Kind: mlp.Conv2D, Filters: 6, Size: 5, Pad: 2},
+			{Kind: mlp.MaxPool, Size: 2},
+			{Kind: mlp.Conv2D, Filters: 16, Size: 5},
+			{Kind: mlp.MaxPool, Size: 2},
+			{Kind: mlp.Flatten},
+		},
+		hidden: []int{120, 84},
+	},
+}
+
+// presetNames liefert die Namen aller Presets, sortiert.
+func presetNames() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DataConfig enthält die Pfade zu den IDX-Dateien des Datensatzes, leer für
+// die Standardpfade des Datensatzes.
+type DataConfig struct {
+	TrainImages string `json:"train_images" yaml:"train_images"`
+	TrainLabels string `json:"train_labels" yaml:"train_labels"`
+	TestImages  string `json:"test_images" yaml:"test_images"`
+	TestLabels  string `json:"test_labels" yaml:"test_labels"`
+}
+
+// defaultConfig liefert die Standardeinstellungen, die den früher fest
+// eingetragenen Werten entsprechen.
+func defaultConfig() Config {
+	opt := mlp.DefaultOptimizerConfig()
+	sched := mlp.DefaultScheduleConfig(0, 0)
+	// Augmentierung mit üblichen Beträgen, aber ausgeschaltet
+	aug := mnist.DefaultAugmentation()
+	aug.Affine.Prob, aug.Elastic.Prob, aug.Noise.Prob, aug.Erasing.Prob = 0, 0, 0, 0
+	return Config{
+		Epochs:     50,
+		BatchSize:  50,
+		EvalSubset: 10000, // aus Performancegründen nur einen Teil
+		Precision:  mlp.Float64,
+		Validation: 0.1,
+		EarlyStopping: EarlyStoppingConfig{
+			Metric: mlp.MetricLoss,
+		},
+		Augmentation: aug,
+		Optimizer: OptimizerConfig{
+			Name:         "sgd",
+			LearningRate: 0.09,
+			Momentum:     opt.Momentum,
+			Beta1:        opt.Beta1,
+			Beta2:        opt.Beta2,
+			Rho:          opt.Rho,
+			Epsilon:      opt.Epsilon,
+			WeightDecay:  opt.WeightDecay,
+		},
+		Schedule: ScheduleConfig{
+			Name:       "constant",
+			StepSize:   sched.StepSize,
+			Gamma:      sched.Gamma,
+			Period:     sched.Period,
+			PeriodMult: sched.PeriodMult,
+			MinLR:      sched.MinLR,
+			PctStart:   sched.PctStart,
+			Factor:     sched.Factor,
+			Patience:   sched.Patience,
+			MinDelta:   sched.MinDelta,
+		},
+		Dataset:         mnist.MNIST.Name,
+		Output:          "model.bin",
+		Checkpoint:      "checkpoint.bin",
+		CheckpointEvery: 1,
+	}
+}
+
+// registerFlags bindet die Kommandozeilenoptionen an die Felder von cfg.
+func registerFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.Preset, "preset", cfg.Preset, "Vordefinierte Architektur: "+strings.Join(presetNames(), ", ")+" (leer: MLP)")
+	fs.Var((*intList)(&cfg.Layers), "layers", "Neuronen je Schicht inkl. Ein- und Ausgabe, z. B. 784,512,256,10 (leer: 784,512,Anzahl der Klassen)")
+	fs.Var((*stringList)(&cfg.Activations), "activations", "Aktivierung je Schicht, z. B. relu,relu,softmax (leer: ReLU/Softmax)")
+	fs.StringVar(&cfg.Norm, "norm", cfg.Norm, "Normalisierung der versteckten Schichten: "+strings.Join(mlp.NormNames, ", ")+" (leer: keine)")
+	fs.IntVar(&cfg.Epochs, "epochs", cfg.Epochs, "Anzahl der Epochen")
+	fs.IntVar(&cfg.BatchSize, "batch", cfg.BatchSize, "Batch-Größe")
+	fs.IntVar(&cfg.EvalSubset, "eval-subset", cfg.EvalSubset, "Anzahl Trainingsbeispiele für TrainAcc (0: alle)")
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "Seed des Zufallsgenerators (0: zufällig)")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "Anzahl paralleler Goroutinen je Mini-Batch (0: GOMAXPROCS)")
+	fs.StringVar(&cfg.Precision, "precision", cfg.Precision, "Genauigkeit: "+strings.Join(mlp.Precisions, ", "))
+	r := &cfg.Regularization
+	fs.Float64Var(&r.L1, "l1", r.L1, "L1-Gewichtsabnahme")
+	fs.Float64Var(&r.L2, "l2", r.L2, "L2-Gewichtsabnahme")
+	fs.Float64Var(&r.Dropout, "dropout", r.Dropout, "Dropout-Anteil der versteckten Schichten im Training")
+	fs.Float64Var(&r.LabelSmoothing, "label-smoothing", r.LabelSmoothing, "Anteil für Label Smoothing")
+
+	a := &cfg.Augmentation
+	fs.Float64Var(&a.Affine.Prob, "aug-affine", a.Affine.Prob, "Wahrscheinlichkeit einer affinen Transformation je Trainingsbild")
+	fs.Float64Var(&a.Affine.Rotation, "aug-rotation", a.Affine.Rotation, "Maximaler Drehwinkel in Grad")
+	fs.Float64Var(&a.Affine.Shift, "aug-shift", a.Affine.Shift, "Maximale Verschiebung in Pixeln")
+	fs.Float64Var(&a.Affine.Scale, "aug-scale", a.Affine.Scale, "Maximale relative Größenänderung")
+	fs.Float64Var(&a.Affine.Shear, "aug-shear", a.Affine.Shear, "Maximaler Scherwinkel in Grad")
+	fs.Float64Var(&a.Elastic.Prob, "aug-elastic", a.Elastic.Prob, "Wahrscheinlichkeit einer elastischen Verzerrung je Trainingsbild")
+	fs.Float64Var(&a.Elastic.Alpha, "aug-elastic-alpha", a.Elastic.Alpha, "Stärke der elastischen Verzerrung in Pixeln")
+	fs.Float64Var(&a.Elastic.Sigma, "aug-elastic-sigma", a.Elastic.Sigma, "Glättung der elastischen Verzerrung in Pixeln")
+	fs.Float64Var(&a.Noise.Prob, "aug-noise", a.Noise.Prob, "Wahrscheinlichkeit von Gaußschem Rauschen je Trainingsbild")
+	fs.Float64Var(&a.Noise.Std, "aug-noise-std", a.Noise.Std, "Standardabweichung des Rauschens")
+	fs.Float64Var(&a.Erasing.Prob, "aug-erasing", a.Erasing.Prob, "Wahrscheinlichkeit von Random Erasing je Trainingsbild")
+	fs.Float64Var(&a.Erasing.Area, "aug-erasing-area", a.Erasing.Area, "Maximaler Flächenanteil des gelöschten Rechtecks")
+
+	fs.Float64Var(&cfg.Validation, "validation", cfg.Validation, "Anteil der Trainingsdaten für die Validierung (0: keine)")
+
+	e := &cfg.EarlyStopping
+	fs.StringVar(&e.Metric, "early-metric", e.Metric, "Kennzahl der Validierung für Early Stopping: "+mlp.MetricLoss+", "+mlp.MetricAccuracy)
+	fs.IntVar(&e.Patience, "early-patience", e.Patience, "Epochen ohne Verbesserung der Validierung bis zum Abbruch (0: kein Early Stopping)")
+	fs.Float64Var(&e.MinDelta, "early-min-delta", e.MinDelta, "Minimale Verbesserung der Validierung für Early Stopping")
+
+	o := &cfg.Optimizer
+	fs.StringVar(&o.Name, "optimizer", o.Name, "Optimizer: "+strings.Join(mlp.OptimizerNames, ", "))
+	fs.Float64Var(&o.LearningRate, "lr", o.LearningRate, "Lernrate")
+	fs.Float64Var(&o.Momentum, "momentum", o.Momentum, "Momentum für momentum und nesterov")
+	fs.Float64Var(&o.Beta1, "beta1", o.Beta1, "Beta1 für adam und adamw")
+	fs.Float64Var(&o.Beta2, "beta2", o.Beta2, "Beta2 für adam und adamw")
+	fs.Float64Var(&o.Rho, "rho", o.Rho, "Abklingrate für rmsprop")
+	fs.Float64Var(&o.Epsilon, "eps", o.Epsilon, "Epsilon für adagrad, rmsprop, adam und adamw")
+	fs.Float64Var(&o.WeightDecay, "weight-decay", o.WeightDecay, "Entkoppelte Gewichtsabnahme für adamw")
+
+	s := &cfg.Schedule
+	fs.StringVar(&s.Name, "schedule", s.Name, "Lernraten-Schedule: "+strings.Join(mlp.ScheduleNames, ", "))
+	fs.Float64Var(&s.Warmup, "warmup", s.Warmup, "Dauer der linearen Aufwärmphase in Epochen")
+	fs.Float64Var(&s.StepSize, "step-size", s.StepSize, "Epochen zwischen zwei Reduktionen für step")
+	fs.Float64Var(&s.Gamma, "gamma", s.Gamma, "Reduktionsfaktor für step und exponential")
+	fs.Float64Var(&s.Period, "period", s.Period, "Länge der ersten Periode in Epochen für cosine")
+	fs.Float64Var(&s.PeriodMult, "period-mult", s.PeriodMult, "Verlängerungsfaktor je Periode für cosine")
+	fs.Float64Var(&s.MinLR, "min-lr", s.MinLR, "Minimale Lernrate für cosine und plateau")
+	fs.Float64Var(&s.PctStart, "pct-start", s.PctStart, "Anteil der Anstiegsphase für onecycle")
+	fs.Float64Var(&s.Factor, "factor", s.Factor, "Reduktionsfaktor für plateau")
+	fs.IntVar(&s.Patience, "patience", s.Patience, "Epochen ohne Verbesserung der Validierungsgenauigkeit bis zur Reduktion für plateau")
+	fs.Float64Var(&s.MinDelta, "min-delta", s.MinDelta, "Minimale Verbesserung der Validierungsgenauigkeit für plateau")
+
+	fs.StringVar(&cfg.Dataset, "dataset", cfg.Dataset, "Datensatz: "+strings.Join(mnist.DatasetNames(), ", "))
+	d := &cfg.Data
+	fs.StringVar(&d.TrainImages, "train-images", d.TrainImages, "IDX-Datei mit den Trainingsbildern (leer: Standardpfad des Datensatzes)")
+	fs.StringVar(&d.TrainLabels, "train-labels", d.TrainLabels, "IDX-Datei mit den Trainingslabels (leer: Standardpfad des Datensatzes)")
+	fs.StringVar(&d.TestImages, "test-images", d.TestImages, "IDX-Datei mit den Testbildern (leer: Standardpfad des Datensatzes)")
+	fs.StringVar(&d.TestLabels, "test-labels", d.TestLabels, "IDX-Datei mit den Testlabels (leer: Standardpfad des Datensatzes)")
+
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "Ausgabedatei für das Modell")
+	fs.StringVar(&cfg.XLSX, "xlsx", cfg.XLSX, "Excel-Arbeitsmappe mit Konfiguration, Verlauf und Auswertung der Testdaten (leer: keine)")
+	fs.StringVar(&cfg.Checkpoint, "checkpoint", cfg.Checkpoint, "Checkpoint mit dem vollständigen Trainingszustand (leer: keiner)")
+	fs.IntVar(&cfg.CheckpointEvery, "checkpoint-every", cfg.CheckpointEvery, "Checkpoint alle N Epochen schreiben (0: nur bei SIGINT/SIGTERM)")
+	fs.StringVar(&cfg.Resume, "resume", cfg.Resume, "Training aus diesem Checkpoint mit dessen Konfiguration fortsetzen")
+}
+
+// parseConfig ermittelt die Konfiguration aus Standardwerten, Checkpoint,
+// Konfigurationsdatei und Kommandozeilenoptionen args.
+func parseConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	configFile := fs.String("config", "", "YAML- oder JSON-Datei mit der Konfiguration")
+	registerFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *configFile == "" && cfg.Resume == "" {
+		return &cfg, cfg.resolve()
+	}
+
+	// Explizit gesetzte Optionen merken, Checkpoint und Datei über die
+	// Standardwerte laden und die Optionen erneut anwenden, damit sie Vorrang
+	// vor Checkpoint und Datei haben.
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			set[f.Name] = f.Value.String()
+		}
+	})
+	resume := cfg.Resume
+	cfg = defaultConfig()
+	if resume != "" {
+		if err := loadCheckpointConfig(resume, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	if *configFile != "" {
+		if err := loadConfigFile(*configFile, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("Option -%s: %v", name, err)
+		}
+	}
+	return &cfg, cfg.resolve()
+}
+
+// loadConfigFile liest eine YAML- oder JSON-Datei (anhand der Endung .json) nach cfg.
+// Nicht angegebene Felder behalten ihren Wert, unbekannte Felder sind ein Fehler.
+func loadConfigFile(filename string, cfg *Config) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Konfiguration: %v", err)
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Konfiguration %s: %v", filename, err)
+	}
+	return nil
+}
+
+// loadCheckpointConfig liest die in den Metadaten des Checkpoints filename
+// gespeicherte Konfiguration nach cfg.
+func loadCheckpointConfig(filename string, cfg *Config) error {
+	meta, err := modelio.LoadMetadata(filename)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden des Checkpoints: %v", err)
+	}
+	if len(meta.Hyperparameters) == 0 {
+		return fmt.Errorf("Checkpoint %s enthält keine Konfiguration", filename)
+	}
+	if err := json.Unmarshal(meta.Hyperparameters, cfg); err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Konfiguration aus %s: %v", filename, err)
+	}
+	return nil
+}
+
+// save schreibt die vollständig aufgelöste Konfiguration als YAML nach filename.
+func (c *Config) save(filename string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Serialisieren der Konfiguration: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("Fehler beim Schreiben der Konfiguration: %v", err)
+	}
+	return nil
+}
+
+// bestPath liefert den Pfad des besten Modells neben dem Modell output,
+// z. B. model.best.bin für model.bin.
+func bestPath(output string) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + ".best" + ext
+}
+
+// configPath liefert den Pfad der Konfigurationsdatei neben dem Modell output,
+// z. B. model.config.yaml für model.bin.
+func configPath(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".config.yaml"
+}
+
+// resolve ergänzt die nicht angegebenen, vom Datensatz abhängigen
+// Einstellungen (Pfade der Daten und Schichten) und prüft die Konfiguration.
+func (c *Config) resolve() error {
+	info, err := mnist.Lookup(c.Dataset)
+	if err != nil {
+		return err
+	}
+	d := &c.Data
+	files := []struct {
+		path *string
+		name string
+	}{
+		{&d.TrainImages, info.TrainImages},
+		{&d.TrainLabels, info.TrainLabels},
+		{&d.TestImages, info.TestImages},
+		{&d.TestLabels, info.TestLabels},
+	}
+	for _, f := range files {
+		if *f.path == "" {
+			*f.path = info.Path(f.name)
+		}
+	}
+	hidden := []int{512}
+	if c.Preset != "" {
+		p, ok := presets[c.Preset]
+		if !ok {
+			return fmt.Errorf("unbekanntes Preset %q, erlaubt sind %s", c.Preset, strings.Join(presetNames(), ", "))
+		}
+		if len(c.Conv) == 0 {
+			c.Conv = p.conv
+		}
+		hidden = p.hidden
+	}
+	if len(c.Layers) == 0 {
+		c.Layers = append(append([]int{mnist.Rows * mnist.Cols}, hidden...), len(info.Classes))
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if out := c.Layers[len(c.Layers)-1]; out != len(info.Classes) {
+		return fmt.Errorf("die Ausgabeschicht hat %d Neuronen, der Datensatz %s hat %d Klassen", out, info.Name, len(info.Classes))
+	}
+	return nil
+}
+
+// validate prüft die Konfiguration auf offensichtliche Fehler.
+func (c *Config) validate() error {
+	if len(c.Layers) < 2 {
+		return fmt.Errorf("mindestens Eingabe- und Ausgabeschicht erforderlich, layers = %v", c.Layers)
+	}
+	for _, n := range c.Layers {
+		if n <= 0 {
+			return fmt.Errorf("ungültige Schichtgröße in layers = %v", c.Layers)
+		}
+	}
+	if len(c.Activations) != 0 && len(c.Activations) != len(c.Layers)-1 {
+		return fmt.Errorf("%d Aktivierungen für %d Schichten angegeben", len(c.Activations), len(c.Layers)-1)
+	}
+	if c.Norm != "" {
+		if err := mlp.CheckNorm(c.Norm); err != nil {
+			return err
+		}
+	}
+	if c.Epochs <= 0 || c.BatchSize <= 0 {
+		return fmt.Errorf("epochs und batch_size müssen größer 0 sein")
+	}
+	if r := c.Regularization; r.L1 < 0 || r.L2 < 0 {
+		return fmt.Errorf("l1 und l2 dürfen nicht negativ sein")
+	}
+	if r := c.Regularization; r.Dropout < 0 || r.Dropout >= 1 || r.LabelSmoothing < 0 || r.LabelSmoothing >= 1 {
+		return fmt.Errorf("dropout und label_smoothing müssen zwischen 0 und 1 liegen")
+	}
+	if err := c.Augmentation.Check(); err != nil {
+		return err
+	}
+	if c.Validation < 0 || c.Validation >= 1 {
+		return fmt.Errorf("validation muss zwischen 0 und 1 liegen, nicht %g", c.Validation)
+	}
+	if err := c.earlyStopping().Check(); err != nil {
+		return err
+	}
+	if c.EarlyStopping.Patience > 0 && c.Validation == 0 {
+		return fmt.Errorf("Early Stopping erfordert Validierungsdaten (validation > 0)")
+	}
+	if c.CheckpointEvery < 0 {
+		return fmt.Errorf("checkpoint_every darf nicht negativ sein")
+	}
+	if _, err := c.schedule(); err != nil {
+		return err
+	}
+	if err := mlp.CheckPrecision(c.Precision); err != nil {
+		return err
+	}
+	if c.Output == "" {
+		return fmt.Errorf("keine Ausgabedatei angegeben")
+	}
+	return nil
+}
+
+// activations liefert die Aktivierungen von c in der Genauigkeit T oder nil
+// für die Standardwerte.
+func activations[T mlp.Float](c *Config) ([]mlp.Activation[T], error) {
+	if len(c.Activations) == 0 {
+		return nil, nil
+	}
+	acts := make([]mlp.Activation[T], len(c.Activations))
+	for i, name := range c.Activations {
+		act, err := mlp.ParseActivation[T](name)
+		if err != nil {
+			return nil, err
+		}
+		acts[i] = act
+	}
+	return acts, nil
+}
+
+// newOptimizer erzeugt den in c konfigurierten Optimizer für die Genauigkeit T.
+func newOptimizer[T mlp.Float](c *Config) (mlp.Optimizer[T], error) {
+	o := c.Optimizer
+	return mlp.NewOptimizer[T](o.Name, mlp.OptimizerConfig{
+		Momentum:    o.Momentum,
+		Beta1:       o.Beta1,
+		Beta2:       o.Beta2,
+		Rho:         o.Rho,
+		Epsilon:     o.Epsilon,
+		WeightDecay: o.WeightDecay,
+	})
+}
+
+// regularization liefert die konfigurierte Regularisierung.
+func (c *Config) regularization() mlp.Regularization {
+	r := c.Regularization
+	return mlp.Regularization{
+		WeightDecay:    mlp.WeightDecay{L1: r.L1, L2: r.L2},
+		Dropout:        r.Dropout,
+		LabelSmoothing: r.LabelSmoothing,
+	}
+}
+
+// earlyStopping liefert das konfigurierte Early Stopping.
+func (c *Config) earlyStopping() *mlp.EarlyStopping {
+	e := c.EarlyStopping
+	return &mlp.EarlyStopping{Metric: e.Metric, Patience: e.Patience, MinDelta: e.MinDelta}
+}
+
+// schedule erzeugt den konfigurierten Lernraten-Schedule.
+func (c *Config) schedule() (mlp.Schedule, error) {
+	s := c.Schedule
+	return mlp.NewSchedule(s.Name, mlp.ScheduleConfig{
+		Base:         c.Optimizer.LearningRate,
+		Epochs:       c.Epochs,
+		StepSize:     s.StepSize,
+		Gamma:        s.Gamma,
+		Period:       s.Period,
+		PeriodMult:   s.PeriodMult,
+		MinLR:        s.MinLR,
+		PctStart:     s.PctStart,
+		Factor:       s.Factor,
+		Patience:     s.Patience,
+		MinDelta:     s.MinDelta,
+		WarmupEpochs: s.Warmup,
+	})
+}
+
+// intList ist eine kommagetrennte Liste von Ganzzahlen als flag.Value.
+type intList []int
+
+func (l *intList) String() string {
+	if l == nil {
+		return ""
+	}
+	parts := make([]string, len(*l))
+	for i, n := range *l {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intList) Set(s string) error {
+	var list []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		list = append(list, n)
+	}
+	*l = list
+	return nil
+}
+
+// stringList ist eine kommagetrennte Liste von Zeichenketten als flag.Value.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
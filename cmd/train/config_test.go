@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+// writeFile schreibt data in die Datei name in einem temporären Verzeichnis
+// und liefert ihren Pfad.
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestParseConfigPrecedence(t *testing.T) {
+	def := defaultConfig()
+	yamlFile := writeFile(t, "train.yaml", `
+epochs: 7
+batch_size: 20
+validation: 0
+optimizer:
+  name: adam
+`)
+	jsonFile := writeFile(t, "train.json", `{"batch_size": 25, "layers": [784, 64, 10]}`)
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want func(c *Config)
+	}{
+		{"Standardwerte", nil, func(c *Config) {}},
+		{"Option vor Standardwert", []string{"-epochs", "3", "-optimizer", "adam"}, func(c *Config) {
+			c.Epochs, c.Optimizer.Name = 3, "adam"
+		}},
+		// -epochs ist gesetzt, -batch und -validation nicht
+		{"Option vor YAML", []string{"-config", yamlFile, "-epochs", "3"}, func(c *Config) {
+			c.Epochs, c.BatchSize, c.Validation, c.Optimizer.Name = 3, 20, 0, "adam"
+		}},
+		{"Option vor YAML unabhängig von der Reihenfolge", []string{"-epochs", "3", "-config", yamlFile}, func(c *Config) {
+			c.Epochs, c.BatchSize, c.Validation, c.Optimizer.Name = 3, 20, 0, "adam"
+		}},
+		{"JSON vor Standardwert", []string{"-config", jsonFile}, func(c *Config) {
+			c.BatchSize, c.Layers = 25, []int{784, 64, 10}
+		}},
+		{"Option mit Standardwert vor JSON", []string{"-config", jsonFile, "-batch", "50"}, func(c *Config) {
+			c.Layers = []int{784, 64, 10}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseConfig(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := def
+			tc.want(&want)
+			if err := want.resolve(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("Konfiguration\n%+v\nerwartet\n%+v", *got, want)
+			}
+		})
+	}
+	if def.Epochs == 3 || def.Epochs == 7 || def.BatchSize == 20 || def.BatchSize == 25 || def.Validation == 0 || def.Optimizer.Name == "adam" {
+		t.Fatal("die Testwerte müssen sich von den Standardwerten unterscheiden")
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, data, err string
+	}{
+		{"train.yaml", "epochs: 3\nepoch: 4\n", "field epoch not found"},
+		{"train.json", `{"epochs": 3, "epoch": 4}`, `unknown field "epoch"`},
+		{"train.yaml", "epochs: drei\n", "Fehler beim Lesen der Konfiguration"},
+	} {
+		cfg := defaultConfig()
+		err := loadConfigFile(writeFile(t, tc.name, tc.data), &cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s %q: Fehler %v, erwartet %q", tc.name, tc.data, err, tc.err)
+		}
+	}
+	cfg := defaultConfig()
+	if err := loadConfigFile(filepath.Join(t.TempDir(), "fehlt.yaml"), &cfg); err == nil {
+		t.Error("fehlende Datei: kein Fehler")
+	}
+}
+
+func TestLoadCheckpointConfig(t *testing.T) {
+	dir := t.TempDir()
+	saved := defaultConfig()
+	saved.Epochs, saved.BatchSize, saved.Layers = 9, 30, []int{784, 16, 10}
+	hyper, err := json.Marshal(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := mlp.NewMLP[float64](rand.New(rand.NewSource(1)), saved.Layers)
+	checkpoint := filepath.Join(dir, "checkpoint.bin")
+	if err := modelio.Save(checkpoint, model, &modelio.Metadata{Hyperparameters: hyper}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Optionen vor Konfigurationsdatei vor Checkpoint
+	configFile := writeFile(t, "train.yaml", "epochs: 12\n")
+	got, err := parseConfig([]string{"-resume", checkpoint, "-config", configFile, "-batch", "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Epochs != 12 || got.BatchSize != 10 || !slices.Equal(got.Layers, saved.Layers) || got.Resume != checkpoint {
+		t.Errorf("Epochen %d, Batch-Größe %d, Schichten %v, Resume %q", got.Epochs, got.BatchSize, got.Layers, got.Resume)
+	}
+
+	empty := filepath.Join(dir, "leer.bin")
+	if err := modelio.Save(empty, model, nil); err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultConfig()
+	if err := loadCheckpointConfig(empty, &cfg); err == nil || !strings.Contains(err.Error(), "enthält keine Konfiguration") {
+		t.Errorf("Checkpoint ohne Konfiguration: Fehler %v", err)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	cfg, err := parseConfig([]string{
+		"-preset", "lenet", "-norm", "batchnorm", "-activations", "relu,tanh,softmax",
+		"-optimizer", "adamw", "-schedule", "cosine", "-dropout", "0.2", "-aug-affine", "0.5",
+		"-dataset", "fashion-mnist", "-xlsx", "run.xlsx", "-seed", "5",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "model.config.yaml")
+	if err := cfg.save(filename); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseConfig([]string{"-config", filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cfg) {
+		t.Errorf("gelesen\n%+v\ngespeichert\n%+v", *got, *cfg)
+	}
+}
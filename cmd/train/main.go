@@ -0,0 +1,330 @@
+// Das Programm train trainiert ein MLP auf den MNIST-Daten oder einem
+// gleich aufgebauten Datensatz (-dataset) und speichert das Modell. Mit
+// -preset lenet oder einem Faltungsteil in der Konfiguration entsteht ein CNN.
+// Alle Einstellungen können per Kommandozeile oder Konfigurationsdatei
+// (-config) angegeben werden, siehe Config.
+//
+// Ein nach Klassen geschichteter Teil der Trainingsdaten dient der
+// Validierung: Nach jeder Epoche werden Loss und Genauigkeit darauf gemessen,
+// das bisher beste Modell (mit der höchsten Validierungsgenauigkeit) wird als
+// model.best.bin gespeichert und bei ausbleibender Verbesserung endet das
+// Training vorzeitig (Early Stopping). Mit -validation 0 gilt das Modell mit
+// der höchsten Trainingsgenauigkeit (auf -eval-subset Beispielen) als bestes.
+// Die Testdaten werden erst am Ende einmalig für das beste Modell ausgewertet;
+// Loss und Genauigkeit auf den Testdaten fehlen deshalb bewusst in der
+// Ausgabe je Epoche, damit sie keine Entscheidung während des Trainings
+// beeinflussen.
+// Mit -xlsx entsteht dabei zusätzlich eine Excel-Arbeitsmappe mit der
+// Konfiguration, dem Verlauf je Epoche und der Auswertung der Testdaten.
+//
+// Alle checkpoint_every Epochen sowie bei SIGINT/SIGTERM wird ein Checkpoint
+// geschrieben, aus dem train -resume checkpoint.bin das Training bitgenau
+// fortsetzt.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/excel"
+	"grimm.world/mlp_demo/mlp/mnist"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+func main() {
+	cfg, err := parseConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	switch cfg.Precision {
+	case mlp.Float32:
+		train[float32](cfg)
+	default:
+		train[float64](cfg)
+	}
+}
+
+// train führt den Trainingslauf cfg in der Genauigkeit T aus.
+func train[T mlp.Float](cfg *Config) {
+	// einzige Zufallsquelle für Initialisierung und Mischen der Trainingsdaten;
+	// ihr Zustand wird in Checkpoints gespeichert
+	src := mlp.NewSource(cfg.Seed)
+	rng := rand.New(src)
+
+	acts, err := activations[T](cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	optimizer, err := newOptimizer[T](cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	schedule, err := cfg.schedule()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info, err := mnist.Lookup(cfg.Dataset)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Lade %s Trainingsdaten...\n", info.Name)
+	trainData, err := mnist.OpenDataset[T](info, cfg.Data.TrainImages, cfg.Data.TrainLabels)
+	if err != nil {
+		log.Fatal("Fehler beim Laden der Trainingsdaten:", err)
+	}
+
+	fmt.Printf("Lade %s Testdaten...\n", info.Name)
+	test, err := mnist.OpenDataset[T](info, cfg.Data.TestImages, cfg.Data.TestLabels)
+	if err != nil {
+		log.Fatal("Fehler beim Laden der Testdaten:", err)
+	}
+	if n := trainData.Rows * trainData.Cols; n != cfg.Layers[0] || test.Rows*test.Cols != n {
+		log.Fatalf("Bilder mit %dx%d Pixeln passen nicht zur Eingabeschicht mit %d Neuronen", trainData.Rows, trainData.Cols, cfg.Layers[0])
+	}
+
+	// Validierungsdaten aus den Trainingsdaten; die Aufteilung hängt nur vom
+	// Seed ab, nicht vom Zustand von rng, und bleibt beim Fortsetzen gleich.
+	var train, val mlp.Dataset[T] = trainData, nil
+	var early *mlp.EarlyStopping
+	if cfg.Validation > 0 {
+		trainIdx, valIdx := mlp.StratifiedSplit[T](rand.New(rand.NewSource(cfg.Seed)), trainData, cfg.Validation)
+		train = mlp.Subset[T]{D: trainData, Index: trainIdx}
+		val = mlp.Subset[T]{D: trainData, Index: valIdx}
+		fmt.Printf("%d Trainings- und %d Validierungsbeispiele\n", train.Len(), val.Len())
+		early = cfg.earlyStopping()
+	}
+
+	var model *mlp.MLP[T]
+	if len(cfg.Conv) > 0 {
+		in := mlp.Shape{C: 1, H: trainData.Rows, W: trainData.Cols}
+		if model, err = mlp.NewConvNet[T](rng, in, cfg.Conv, cfg.Layers, acts...); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		model = mlp.NewMLP[T](rng, cfg.Layers, acts...)
+	}
+	if cfg.Norm != "" {
+		if err := model.AddNorm(cfg.Norm); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var progress mlp.Progress
+
+	if cfg.Resume != "" {
+		ckpt, err := modelio.LoadCheckpoint[T](cfg.Resume)
+		if err != nil {
+			log.Fatalf("Fehler beim Laden des Checkpoints: %v", err)
+		}
+		if err := ckpt.Restore(src, optimizer, schedule); err != nil {
+			log.Fatalf("Fehler beim Laden des Checkpoints %s: %v", cfg.Resume, err)
+		}
+		model = ckpt.Model
+		cfg.Layers, cfg.Conv = model.Sizes(), model.ConvSpecs()
+		progress = ckpt.Progress
+		fmt.Printf("Setze Training aus %s bei Epoche %d, Batch %d fort\n", cfg.Resume, progress.Epoch, progress.Batch)
+	}
+
+	meta, err := newMetadata(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sizes := cfg.Layers
+	// print MLP hyperparameters
+	if len(model.Conv) > 0 {
+		fmt.Printf("Faltungsteil für Eingaben %v: %v\n", model.Conv[0].In, model.ConvSpecs())
+	}
+	fmt.Printf("MLP mit %d Eingabeneuronen, versteckten Schichten %v und %d Ausgabeneuronen\n",
+		sizes[0], sizes[1:len(sizes)-1], sizes[len(sizes)-1])
+	if cfg.Norm != "" {
+		fmt.Printf("Normalisierung der versteckten Schichten: %s\n", cfg.Norm)
+	}
+	fmt.Printf("Optimizer: %s, Lernrate: %.4f (%s), %d Epochen, Batch-Größe: %d, Seed: %d, Genauigkeit: %s\n",
+		optimizer.Name(), cfg.Optimizer.LearningRate, cfg.Schedule.Name, cfg.Epochs, cfg.BatchSize, cfg.Seed, cfg.Precision)
+	if r := cfg.Regularization; r != (RegularizationConfig{}) {
+		fmt.Printf("Regularisierung: L1 %g, L2 %g, Dropout %g, Label Smoothing %g\n", r.L1, r.L2, r.Dropout, r.LabelSmoothing)
+	}
+	if a := cfg.Augmentation; a.Enabled() {
+		fmt.Printf("Augmentierung (Wahrscheinlichkeiten): affin %g, elastisch %g, Rauschen %g, Random Erasing %g\n",
+			a.Affine.Prob, a.Elastic.Prob, a.Noise.Prob, a.Erasing.Prob)
+	}
+
+	configFile := configPath(cfg.Output)
+	if err := cfg.save(configFile); err != nil {
+		log.Fatal(err)
+	}
+
+	// SIGINT/SIGTERM beenden das Training nach dem laufenden Mini-Batch mit
+	// einem Checkpoint; ein zweites Signal bricht sofort ab.
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		fmt.Printf("\n%v erhalten, beende Training nach dem laufenden Mini-Batch...\n", sig)
+		close(stop)
+	}()
+
+	trainer := &mlp.Trainer[T]{
+		Model:          model,
+		Epochs:         cfg.Epochs,
+		BatchSize:      cfg.BatchSize,
+		LearningRate:   cfg.Optimizer.LearningRate,
+		Schedule:       schedule,
+		Optimizer:      optimizer,
+		Progress:       progress,
+		EvalSubset:     cfg.EvalSubset,
+		Workers:        cfg.Workers,
+		Rand:           rng,
+		Stop:           stop,
+		Regularization: cfg.regularization(),
+	}
+	if early != nil && early.Patience > 0 {
+		trainer.EarlyStopping = early
+	}
+	if cfg.Augmentation.Enabled() {
+		trainer.Augment = func(rng *rand.Rand, x []T) {
+			mnist.Augment(rng, &cfg.Augmentation, x)
+		}
+	}
+	saveCheckpoint := func() {
+		if cfg.Checkpoint == "" {
+			return
+		}
+		if err := modelio.SaveCheckpoint(cfg.Checkpoint, modelio.NewCheckpoint(trainer, meta, src)); err != nil {
+			log.Printf("Fehler beim Speichern des Checkpoints: %v", err)
+		}
+	}
+	trainer.OnEpoch = func(s mlp.EpochStats) {
+		line := fmt.Sprintf("Epoche %d, LR: %.6f, Train: Loss %.4f, Acc(%d) %.2f%%",
+			s.Epoch, s.LR, s.Loss, cfg.EvalSubset, s.TrainAcc*100)
+		if early != nil {
+			line += fmt.Sprintf(" | Val: Loss %.4f, Acc %.2f%%", s.ValLoss, s.ValAcc*100)
+		}
+		fmt.Println(line)
+		meta.TrainAccuracy, meta.ValidationAccuracy = s.TrainAcc, s.ValAcc
+
+		history := trainer.Progress.History
+		if bestEpoch(history, val != nil) == len(history)-1 {
+			if err := modelio.Save(bestPath(cfg.Output), model, meta); err != nil {
+				log.Printf("Fehler beim Speichern des besten Modells: %v", err)
+			}
+		}
+		if n := cfg.CheckpointEvery; n > 0 && ((s.Epoch+1)%n == 0 || s.Epoch+1 == cfg.Epochs) {
+			saveCheckpoint()
+		}
+	}
+
+	err = trainer.Run(train, val)
+	if errors.Is(err, mlp.ErrStopped) {
+		saveCheckpoint()
+		p := trainer.Progress
+		if cfg.Checkpoint != "" {
+			fmt.Printf("Training in Epoche %d nach Batch %d unterbrochen, fortsetzen mit: %s -resume %s\n",
+				p.Epoch, p.Batch, os.Args[0], cfg.Checkpoint)
+		} else {
+			fmt.Printf("Training in Epoche %d nach Batch %d unterbrochen, kein Checkpoint gespeichert\n", p.Epoch, p.Batch)
+		}
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Das Ergebnis ist das beste Modell; nur dieses wird einmalig auf den
+	// Testdaten ausgewertet.
+	history := trainer.Progress.History
+	if len(history) == 0 {
+		log.Fatal("keine Epoche trainiert")
+	}
+	if last := history[len(history)-1]; trainer.EarlyStopping != nil && early.Done(history) {
+		fmt.Printf("Early Stopping nach Epoche %d: Validierungs-%s seit %d Epochen nicht verbessert\n",
+			last.Epoch, early.Metric, early.Patience)
+	}
+	final := history[bestEpoch(history, val != nil)]
+	if trainer.Model, _, err = modelio.Load[T](bestPath(cfg.Output)); err != nil {
+		log.Fatalf("Fehler beim Laden des besten Modells: %v", err)
+	}
+	testLoss, testAcc := trainer.Evaluate(test)
+	fmt.Printf("Test mit Modell aus Epoche %d: Loss %.4f, Acc %.2f%%\n", final.Epoch, testLoss, testAcc*100)
+	meta.TrainAccuracy, meta.ValidationAccuracy, meta.TestAccuracy = final.TrainAcc, final.ValAcc, testAcc
+
+	if err := modelio.Save(cfg.Output, trainer.Model, meta); err != nil {
+		log.Fatalf("Fehler beim Speichern des Modells: %v", err)
+	}
+	fmt.Printf("Modell in %s gespeichert, Konfiguration in %s\n", cfg.Output, configFile)
+
+	if cfg.XLSX != "" {
+		metrics := mlp.NewMetrics(len(meta.Classes), 5, 15)
+		trainer.Metrics(test, metrics)
+		run := &excel.Run{
+			Title:           fmt.Sprintf("%s, Testgenauigkeit %.2f%% (Epoche %d)", cfg.Output, testAcc*100, final.Epoch),
+			Hyperparameters: meta.Hyperparameters,
+			History:         history,
+			Report:          metrics.Report(meta.Classes),
+		}
+		if err := excel.Write(cfg.XLSX, run); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Excel-Bericht in %s gespeichert\n", cfg.XLSX)
+	}
+}
+
+// bestEpoch liefert den Index der besten Epoche in history oder -1 für einen
+// leeren Verlauf. Mit Validierung ist das die mit der höchsten
+// Validierungsgenauigkeit, unabhängig von der Kennzahl des Early Stoppings,
+// sonst wie beim plateau-Schedule die mit der höchsten Trainingsgenauigkeit.
+// Die Testdaten werden dafür nie herangezogen.
+func bestEpoch(history []mlp.EpochStats, validation bool) int {
+	if validation {
+		return (&mlp.EarlyStopping{Metric: mlp.MetricAccuracy}).Best(history)
+	}
+	best := -1
+	for i, s := range history {
+		if best < 0 || s.TrainAcc > history[best].TrainAcc {
+			best = i
+		}
+	}
+	return best
+}
+
+// newMetadata beschreibt das in cfg konfigurierte Modell für die Modelldatei.
+func newMetadata(cfg *Config) (*modelio.Metadata, error) {
+	meta := modelio.NewMetadata()
+	meta.Seed = cfg.Seed
+	meta.InputShape = []int{mnist.Rows, mnist.Cols}
+	meta.Normalization = &modelio.Normalization{Scale: mnist.Scale, Mean: 0, Std: 1}
+	info, err := mnist.Lookup(cfg.Dataset)
+	if err != nil {
+		return nil, err
+	}
+	meta.Classes = info.Classes
+	meta.Dataset = info.Name
+	if reg := cfg.regularization(); reg != (mlp.Regularization{}) {
+		meta.Regularization = &reg
+	}
+
+	if meta.Hyperparameters, err = json.Marshal(cfg); err != nil {
+		return nil, fmt.Errorf("Fehler beim Serialisieren der Konfiguration: %v", err)
+	}
+	d := cfg.Data
+	if meta.DatasetHash, err = mnist.Hash(d.TrainImages, d.TrainLabels, d.TestImages, d.TestLabels); err != nil {
+		return nil, fmt.Errorf("Fehler beim Berechnen des Hashs der Daten: %v", err)
+	}
+	return meta, nil
+}
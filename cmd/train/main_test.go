@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+func TestBestEpoch(t *testing.T) {
+	history := []mlp.EpochStats{
+		{Epoch: 0, TrainAcc: 0.90, ValAcc: 0.88},
+		{Epoch: 1, TrainAcc: 0.95, ValAcc: 0.91},
+		{Epoch: 2, TrainAcc: 0.97, ValAcc: 0.90},
+		{Epoch: 3, TrainAcc: 0.97, ValAcc: 0.91},
+	}
+	for _, tc := range []struct {
+		validation bool
+		want       int
+	}{
+		// bei Gleichstand bleibt die frühere Epoche die beste
+		{true, 1},
+		{false, 2},
+	} {
+		if got := bestEpoch(history, tc.validation); got != tc.want {
+			t.Errorf("Validierung %v: beste Epoche %d, erwartet %d", tc.validation, got, tc.want)
+		}
+	}
+	if got := bestEpoch(nil, false); got != -1 {
+		t.Errorf("leerer Verlauf: %d, erwartet -1", got)
+	}
+}
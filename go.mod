@@ -2,7 +2,11 @@ module grimm.world/mlp_demo
 
 go 1.22.6
 
-require github.com/xuri/excelize/v2 v2.9.0
+require (
+	github.com/xuri/excelize/v2 v2.9.0
+	golang.org/x/image v0.18.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
@@ -0,0 +1,125 @@
+// Package evaldata lädt ein gespeichertes Modell zusammen mit den Testdaten
+// seines Datensatzes und wertet es darauf aus. Es enthält die gemeinsamen
+// Schritte der Programme evaluate und errors, damit beide dieselben Daten
+// wählen und dieselben Prüfungen vornehmen.
+package evaldata
+
+import (
+	"fmt"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/mnist"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+// Files sind die Dateien einer Auswertung: das Modell mit seinen Metadaten,
+// der Datensatz und die Bilder und Labels.
+type Files struct {
+	Model          string
+	Meta           *modelio.Metadata
+	Info           *mnist.Info
+	Images, Labels string
+}
+
+// Resolve lädt die Metadaten des Modells modelFile und ergänzt die übrigen
+// Angaben: Ohne dataset gilt der Datensatz des Modells (ohne Angabe MNIST),
+// ohne imageFile und labelFile gelten dessen Testdaten.
+func Resolve(modelFile, dataset, imageFile, labelFile string) (*Files, error) {
+	meta, err := modelio.LoadMetadata(modelFile)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden des Modells: %v", err)
+	}
+	if dataset == "" {
+		dataset = meta.Dataset
+	}
+	if dataset == "" {
+		dataset = mnist.MNIST.Name
+	}
+	info, err := mnist.Lookup(dataset)
+	if err != nil {
+		return nil, err
+	}
+	if imageFile == "" {
+		imageFile = info.Path(info.TestImages)
+	}
+	if labelFile == "" {
+		labelFile = info.Path(info.TestLabels)
+	}
+	return &Files{Model: modelFile, Meta: meta, Info: info, Images: imageFile, Labels: labelFile}, nil
+}
+
+// Set ist ein geladenes Modell mit den Daten, auf denen es ausgewertet wird.
+type Set[T mlp.Float] struct {
+	Model *mlp.MLP[T]
+	Meta  *modelio.Metadata
+	Data  *mnist.Dataset[T]
+	// Classes sind die Namen der Klassen, aus den Metadaten des Modells oder
+	// sonst die des Datensatzes.
+	Classes []string
+}
+
+// Load lädt Modell und Daten in der Genauigkeit T und prüft, ob die Bilder
+// zur Eingabe und die Klassen des Datensatzes zur Ausgabe des Modells passen.
+// Die Pixel werden wie bei der Inferenz (modelio.Metadata.Normalize) mit der
+// Normalisierung aus den Metadaten des Modells umgerechnet.
+func Load[T mlp.Float](f *Files) (*Set[T], error) {
+	model, meta, err := modelio.Load[T](f.Model)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden des Modells: %v", err)
+	}
+	data, err := mnist.OpenDataset[T](f.Info, f.Images, f.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden der Daten: %v", err)
+	}
+	if n := data.Rows * data.Cols; n != model.InputDim() {
+		return nil, fmt.Errorf("Bilder mit %d Pixeln, das Modell erwartet %d Eingaben", n, model.InputDim())
+	}
+	classes := model.Layers[len(model.Layers)-1].OutputDim()
+	if classes != len(f.Info.Classes) {
+		return nil, fmt.Errorf("Das Modell hat %d Ausgaben, der Datensatz %s %d Klassen", classes, f.Info.Name, len(f.Info.Classes))
+	}
+	// Pixel wie bei der Inferenz mit der Normalisierung des Modells
+	var gray [256]float64
+	for v := range gray {
+		gray[v] = float64(v)
+	}
+	data.SetPixelValues([256]float64(meta.Normalize(gray[:])))
+
+	names := meta.Classes
+	if names == nil {
+		names = f.Info.Classes
+	}
+	return &Set[T]{Model: model, Meta: meta, Data: data, Classes: names}, nil
+}
+
+// Result ist das Ergebnis von Evaluate: der Bericht und die Bilder des
+// Datensatzes wie mnist.Dataset.Pixels mit ihrer Höhe und Breite.
+type Result struct {
+	*mlp.Report
+	Pixels     []byte
+	Rows, Cols int
+}
+
+// Evaluate lädt Modell und Daten in der Genauigkeit des Modells und wertet das
+// Modell mit workers Goroutinen aus, mit der Top-k-Genauigkeit bis topK und
+// bins Konfidenzintervallen für den Kalibrierungsfehler.
+func Evaluate(f *Files, topK, bins, workers int) (*Result, error) {
+	switch f.Meta.Precision {
+	case mlp.Float32:
+		return evaluate[float32](f, topK, bins, workers)
+	case mlp.Float64:
+		return evaluate[float64](f, topK, bins, workers)
+	}
+	return nil, mlp.CheckPrecision(f.Meta.Precision)
+}
+
+func evaluate[T mlp.Float](f *Files, topK, bins, workers int) (*Result, error) {
+	s, err := Load[T](f)
+	if err != nil {
+		return nil, err
+	}
+	metrics := mlp.NewMetrics(len(f.Info.Classes), topK, bins)
+	t := &mlp.Trainer[T]{Model: s.Model, Workers: workers}
+	t.Metrics(s.Data, metrics)
+	return &Result{Report: metrics.Report(s.Classes), Pixels: s.Data.Pixels, Rows: s.Data.Rows, Cols: s.Data.Cols}, nil
+}
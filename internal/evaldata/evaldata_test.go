@@ -0,0 +1,122 @@
+package evaldata
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/mnist"
+	"grimm.world/mlp_demo/mlp/modelio"
+)
+
+// fixture schreibt n zufällige 28x28-Bilder mit den Labels i % 10 und ein
+// float32-Modell mit den Schichtgrößen sizes in ein temporäres Verzeichnis.
+func fixture(t *testing.T, n int, sizes []int, meta *modelio.Metadata) (modelFile, imageFile, labelFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(1))
+	pixels := make([]byte, n*mnist.Rows*mnist.Cols)
+	rng.Read(pixels)
+	labels := make([]byte, n)
+	for i := range labels {
+		labels[i] = byte(i % 10)
+	}
+	modelFile = filepath.Join(dir, "model.bin")
+	imageFile = filepath.Join(dir, "images.idx")
+	labelFile = filepath.Join(dir, "labels.idx")
+	if err := mnist.WriteImages(imageFile, pixels, mnist.Rows, mnist.Cols); err != nil {
+		t.Fatal(err)
+	}
+	if err := mnist.WriteLabels(labelFile, labels); err != nil {
+		t.Fatal(err)
+	}
+	if err := modelio.Save(modelFile, mlp.NewMLP[float32](rng, sizes), meta); err != nil {
+		t.Fatal(err)
+	}
+	return modelFile, imageFile, labelFile
+}
+
+func TestResolve(t *testing.T) {
+	modelFile, _, _ := fixture(t, 1, []int{784, 10}, &modelio.Metadata{Dataset: mnist.FashionMNIST.Name})
+	f, err := Resolve(modelFile, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := mnist.FashionMNIST
+	if f.Info != info || f.Images != info.Path(info.TestImages) || f.Labels != info.Path(info.TestLabels) {
+		t.Errorf("Datensatz %s, Dateien %s und %s, erwartet die Testdaten von %s", f.Info.Name, f.Images, f.Labels, info.Name)
+	}
+	if f.Meta.Precision != mlp.Float32 {
+		t.Errorf("Genauigkeit %q", f.Meta.Precision)
+	}
+
+	// Angaben auf der Kommandozeile gehen vor
+	f, err = Resolve(modelFile, mnist.KMNIST.Name, "a.idx", "b.idx")
+	if err != nil || f.Info != mnist.KMNIST || f.Images != "a.idx" || f.Labels != "b.idx" {
+		t.Errorf("Resolve = %+v, %v", f, err)
+	}
+	if _, err := Resolve(modelFile, "unbekannt", "", ""); err == nil {
+		t.Error("unbekannter Datensatz: kein Fehler")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	modelFile, imageFile, labelFile := fixture(t, 20, []int{784, 10}, nil)
+	f, err := Resolve(modelFile, "", imageFile, labelFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Evaluate(f, 3, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Samples != 20 || len(r.TopK) != 3 || len(r.Calibration) != 5 || len(r.Classes) != 10 {
+		t.Errorf("Bericht mit %d Beispielen, %d Top-k-Werten, %d Intervallen, %d Klassen", r.Samples, len(r.TopK), len(r.Calibration), len(r.Classes))
+	}
+	if len(r.Pixels) != 20*mnist.Rows*mnist.Cols || r.Rows != mnist.Rows || r.Cols != mnist.Cols {
+		t.Errorf("%d Pixel in Bildern %dx%d", len(r.Pixels), r.Rows, r.Cols)
+	}
+}
+
+func TestLoadChecksModel(t *testing.T) {
+	for _, tc := range []struct {
+		sizes []int
+		err   string
+	}{
+		{[]int{16, 10}, "das Modell erwartet 16 Eingaben"},
+		{[]int{784, 5}, "Das Modell hat 5 Ausgaben, der Datensatz mnist 10 Klassen"},
+	} {
+		modelFile, imageFile, labelFile := fixture(t, 2, tc.sizes, nil)
+		f, err := Resolve(modelFile, "", imageFile, labelFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load[float32](f); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: Fehler %v, erwartet %q", tc.sizes, err, tc.err)
+		}
+	}
+}
+
+func TestLoadNormalizes(t *testing.T) {
+	norm := &modelio.Normalization{Scale: 1.0 / 255, Mean: 0.1307, Std: 0.3081}
+	modelFile, imageFile, labelFile := fixture(t, 3, []int{784, 10}, &modelio.Metadata{Normalization: norm})
+	f, err := Resolve(modelFile, "", imageFile, labelFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load[float32](f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := make([]float32, mnist.Rows*mnist.Cols), make([]float32, 10)
+	s.Data.Example(2, x, y)
+	pixels := s.Data.Pixels[2*len(x) : 3*len(x)]
+	for i, p := range pixels {
+		want := float32((float64(p)*norm.Scale - norm.Mean) / norm.Std)
+		if x[i] != want {
+			t.Fatalf("Pixel %d mit Grauwert %d: %g, erwartet %g", i, p, x[i], want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Activation ist eine Aktivierungsfunktion, die auf die Ausgabe z = W*x + b
+// einer Schicht angewendet wird.
+type Activation[T Float] interface {
+	// Name liefert den Namen, unter dem die Aktivierung im Modell gespeichert
+	// wird und den ParseActivation versteht.
+	Name() string
+	// Forward berechnet a = f(z).
+	Forward(z, a []T)
+	// Backward berechnet dz = dLoss/dz aus z, a = f(z) und da = dLoss/da.
+	Backward(z, a, da, dz []T)
+}
+
+// ParseActivation liefert die Aktivierung zum Namen name. Parametrisierte
+// Aktivierungen akzeptieren den Parameter nach einem Doppelpunkt,
+// z. B. "leaky_relu:0.1" oder "elu:1".
+func ParseActivation[T Float](name string) (Activation[T], error) {
+	base, param, hasParam := strings.Cut(strings.ToLower(strings.TrimSpace(name)), ":")
+	alpha := 0.0
+	if hasParam {
+		var err error
+		alpha, err = strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ungültiger Parameter für Aktivierung %q: %v", name, err)
+		}
+	}
+
+	var act Activation[T]
+	switch base {
+	case "relu":
+		act = ReLU[T]{}
+	case "leaky_relu":
+		if !hasParam {
+			alpha = 0.01
+		}
+		return LeakyReLU[T]{Alpha: alpha}, nil
+	case "elu":
+		if !hasParam {
+			alpha = 1
+		}
+		return ELU[T]{Alpha: alpha}, nil
+	case "sigmoid":
+		act = Sigmoid[T]{}
+	case "tanh":
+		act = Tanh[T]{}
+	case "gelu":
+		act = GELU[T]{}
+	case "silu", "swish":
+		act = SiLU[T]{}
+	case "softmax":
+		act = Softmax[T]{}
+	case "identity", "linear":
+		act = Identity[T]{}
+	default:
+		return nil, fmt.Errorf("unbekannte Aktivierung %q", name)
+	}
+	if hasParam {
+		return nil, fmt.Errorf("Aktivierung %q hat keinen Parameter", base)
+	}
+	return act, nil
+}
+
+// ReLU ist max(0, z).
+type ReLU[T Float] struct{}
+
+func (ReLU[T]) Name() string { return "relu" }
+
+func (ReLU[T]) Forward(z, a []T) {
+	for i, v := range z {
+		a[i] = relu(v)
+	}
+}
+
+func (ReLU[T]) Backward(z, a, da, dz []T) {
+	for i, v := range z {
+		dz[i] = da[i] * reluDerivative(v)
+	}
+}
+
+func relu[T Float](x T) T {
+	if x > 0 {
+		return x
+	}
+	return 0
+}
+
+func reluDerivative[T Float](x T) T {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+// LeakyReLU ist z für z > 0, sonst Alpha*z.
+type LeakyReLU[T Float] struct {
+	Alpha float64
+}
+
+func (l LeakyReLU[T]) Name() string { return "leaky_relu:" + formatParam(l.Alpha) }
+
+func (l LeakyReLU[T]) Forward(z, a []T) {
+	for i, v := range z {
+		if v > 0 {
+			a[i] = v
+		} else {
+			a[i] = T(l.Alpha) * v
+		}
+	}
+}
+
+func (l LeakyReLU[T]) Backward(z, a, da, dz []T) {
+	for i, v := range z {
+		if v > 0 {
+			dz[i] = da[i]
+		} else {
+			dz[i] = T(l.Alpha) * da[i]
+		}
+	}
+}
+
+// ELU ist z für z > 0, sonst Alpha*(exp(z)-1).
+type ELU[T Float] struct {
+	Alpha float64
+}
+
+func (e ELU[T]) Name() string { return "elu:" + formatParam(e.Alpha) }
+
+func (e ELU[T]) Forward(z, a []T) {
+	for i, v := range z {
+		if v > 0 {
+			a[i] = v
+		} else {
+			a[i] = T(e.Alpha * math.Expm1(float64(v)))
+		}
+	}
+}
+
+func (e ELU[T]) Backward(z, a, da, dz []T) {
+	for i, v := range z {
+		if v > 0 {
+			dz[i] = da[i]
+		} else {
+			// f'(z) = Alpha*exp(z) = f(z) + Alpha
+			dz[i] = da[i] * (a[i] + T(e.Alpha))
+		}
+	}
+}
+
+// Sigmoid ist 1/(1+exp(-z)).
+type Sigmoid[T Float] struct{}
+
+func (Sigmoid[T]) Name() string { return "sigmoid" }
+
+func (Sigmoid[T]) Forward(z, a []T) {
+	for i, v := range z {
+		a[i] = sigmoid(v)
+	}
+}
+
+func (Sigmoid[T]) Backward(z, a, da, dz []T) {
+	for i := range z {
+		dz[i] = da[i] * a[i] * (1 - a[i])
+	}
+}
+
+func sigmoid[T Float](x T) T {
+	return 1 / (1 + exp(-x))
+}
+
+// Tanh ist der Tangens hyperbolicus.
+type Tanh[T Float] struct{}
+
+func (Tanh[T]) Name() string { return "tanh" }
+
+func (Tanh[T]) Forward(z, a []T) {
+	for i, v := range z {
+		a[i] = T(math.Tanh(float64(v)))
+	}
+}
+
+func (Tanh[T]) Backward(z, a, da, dz []T) {
+	for i := range z {
+		dz[i] = da[i] * (1 - a[i]*a[i])
+	}
+}
+
+// GELU ist z*Φ(z) mit der Verteilungsfunktion Φ der Standardnormalverteilung
+// (exakte Variante, nicht die tanh-Näherung).
+type GELU[T Float] struct{}
+
+func (GELU[T]) Name() string { return "gelu" }
+
+func (GELU[T]) Forward(z, a []T) {
+	for i, v := range z {
+		a[i] = v * T(normCDF(float64(v)))
+	}
+}
+
+func (GELU[T]) Backward(z, a, da, dz []T) {
+	for i, v := range z {
+		// f'(z) = Φ(z) + z*φ(z)
+		x := float64(v)
+		pdf := math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+		dz[i] = da[i] * T(normCDF(x)+x*pdf)
+	}
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
+
+// SiLU (auch Swish) ist z*sigmoid(z).
+type SiLU[T Float] struct{}
+
+func (SiLU[T]) Name() string { return "silu" }
+
+func (SiLU[T]) Forward(z, a []T) {
+	for i, v := range z {
+		a[i] = v * sigmoid(v)
+	}
+}
+
+func (SiLU[T]) Backward(z, a, da, dz []T) {
+	for i, v := range z {
+		s := sigmoid(v)
+		dz[i] = da[i] * s * (1 + v*(1-s))
+	}
+}
+
+// Identity lässt z unverändert.
+type Identity[T Float] struct{}
+
+func (Identity[T]) Name() string { return "identity" }
+
+func (Identity[T]) Forward(z, a []T) {
+	copy(a, z)
+}
+
+func (Identity[T]) Backward(z, a, da, dz []T) {
+	copy(dz, da)
+}
+
+// Softmax normiert z zu einer Wahrscheinlichkeitsverteilung. In der
+// Ausgabeschicht kombiniert Backward sie mit dem Cross-Entropy-Loss zu a - y.
+type Softmax[T Float] struct{}
+
+func (Softmax[T]) Name() string { return "softmax" }
+
+func (Softmax[T]) Forward(z, a []T) {
+	softmax(z, a)
+}
+
+func (Softmax[T]) Backward(z, a, da, dz []T) {
+	// dz_i = a_i * (da_i - sum_j a_j*da_j)
+	s := dot(a, da)
+	for i := range a {
+		dz[i] = a[i] * (da[i] - s)
+	}
+}
+
+// formatParam formatiert den Parameter einer Aktivierung für Name.
+func formatParam(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
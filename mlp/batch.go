@@ -0,0 +1,347 @@
+package mlp
+
+import "math/rand"
+
+// Batch ist der Arbeitsspeicher für Forward- und Backward-Pass eines ganzen
+// Mini-Batches. Jede Zeile der Matrizen gehört zu einem Beispiel. Ein Batch
+// wird über viele Mini-Batches hinweg wiederverwendet, so dass im Training
+// keine Allokationen pro Beispiel anfallen.
+type Batch[T Float] struct {
+	// Z[l] ist die Eingabe der Aktivierung von Dense-Schicht l, A[l] die
+	// Eingabe von Dense-Schicht l; A[0] ist also ohne Faltungsteil die Eingabe
+	// des Netzes und A[len(A)-1] die Ausgabe.
+	Z, A []*Matrix[T]
+	// delta[l] ist dLoss/dZ[l], dA[l] ist dLoss/dA[l].
+	delta, dA []*Matrix[T]
+	// norm[l] ist der Arbeitsspeicher der Normalisierung von Schicht l, nil
+	// ohne Normalisierung. Z[l] ist dann deren Ausgabe.
+	norm []*normWork[T]
+
+	// Faltungsteil: in ist die Eingabe des Netzes, convZ[i] die Eingabe der
+	// Aktivierung von Faltungsschicht i (nil außer bei Conv2D), convA[i] ihre
+	// Ausgabe und convDA[i] = dLoss/dconvA[i]. Die Ausgabe der letzten
+	// Schicht ist A[0], ihr Gradient dA[0].
+	in                   *Matrix[T]
+	convZ, convA, convDA []*Matrix[T]
+	convWork             []*convWork[T]
+
+	// Train schaltet den Trainingsmodus ein: BatchNorm normalisiert dann mit
+	// den Statistiken des Batches statt mit den gleitenden Statistiken.
+	Train bool
+
+	// Dropout (nur im Training, siehe SetDropout): Anteil p, Zufallsquelle
+	// rng, mask[l] mit den Faktoren für A[l] und act[l] mit A[l] vor dem
+	// Dropout für den Backward-Pass der Aktivierung.
+	p         float64
+	src       *Source
+	rng       *rand.Rand
+	mask, act []*Matrix[T]
+}
+
+// NewBatch erzeugt einen Arbeitsspeicher für bis zu rows Beispiele.
+func (m *MLP[T]) NewBatch(rows int) *Batch[T] {
+	b := &Batch[T]{
+		Z:     make([]*Matrix[T], len(m.Layers)),
+		A:     make([]*Matrix[T], len(m.Layers)+1),
+		delta: make([]*Matrix[T], len(m.Layers)),
+		dA:    make([]*Matrix[T], len(m.Layers)),
+		norm:  make([]*normWork[T], len(m.Layers)),
+	}
+	b.A[0] = NewMatrix[T](rows, m.Layers[0].InputDim())
+	for l, layer := range m.Layers {
+		b.Z[l] = NewMatrix[T](rows, layer.OutputDim())
+		b.A[l+1] = NewMatrix[T](rows, layer.OutputDim())
+		b.delta[l] = NewMatrix[T](rows, layer.OutputDim())
+		b.dA[l] = NewMatrix[T](rows, layer.InputDim())
+		if layer.Norm != nil {
+			b.norm[l] = newNormWork[T](rows, layer.OutputDim())
+		}
+	}
+
+	if c := len(m.Conv); c > 0 {
+		b.in = NewMatrix[T](rows, m.Conv[0].In.Size())
+		b.convZ = make([]*Matrix[T], c)
+		b.convA = make([]*Matrix[T], c)
+		b.convDA = make([]*Matrix[T], c)
+		b.convWork = make([]*convWork[T], c)
+		for i, layer := range m.Conv {
+			if layer.Kind == Conv2D {
+				b.convZ[i] = NewMatrix[T](rows, layer.Out.Size())
+			}
+			b.convA[i] = NewMatrix[T](rows, layer.Out.Size())
+			// Gradienten erst bei Bedarf anlegen, z. B. nicht für Evaluate
+			b.convDA[i] = NewMatrix[T](0, layer.Out.Size())
+			b.convWork[i] = newConvWork(layer)
+		}
+		b.convA[c-1], b.convDA[c-1] = b.A[0], b.dA[0]
+	}
+	return b
+}
+
+// input liefert die Eingabematrix des Netzes.
+func (b *Batch[T]) input() *Matrix[T] {
+	if b.in != nil {
+		return b.in
+	}
+	return b.A[0]
+}
+
+// Input liefert die Eingabematrix des Batches mit rows Zeilen, in die die
+// Beispiele vor ForwardBatch kopiert werden.
+func (b *Batch[T]) Input(rows int) *Matrix[T] {
+	x := b.input()
+	x.SetRows(rows)
+	return x
+}
+
+// SetDropout schaltet für die folgenden Aufrufe von ForwardBatch Dropout mit
+// dem Anteil p auf die Aktivierungen der versteckten Schichten ein (p = 0
+// schaltet es aus). Die Masken werden aus einer mit seed initialisierten
+// Zufallsquelle gezogen, so dass das Ergebnis nur von seed abhängt.
+func (b *Batch[T]) SetDropout(p float64, seed int64) {
+	b.p = p
+	if p == 0 {
+		return
+	}
+	if b.src == nil {
+		b.src = NewSource(seed)
+		b.rng = rand.New(b.src)
+		b.mask = make([]*Matrix[T], len(b.A))
+		b.act = make([]*Matrix[T], len(b.A))
+		for l := 1; l < len(b.A)-1; l++ {
+			b.mask[l] = NewMatrix[T](b.A[l].Rows, b.A[l].Cols)
+			b.act[l] = NewMatrix[T](b.A[l].Rows, b.A[l].Cols)
+		}
+	}
+	b.src.Seed(seed)
+}
+
+// updateRunningStats führt die gleitenden Statistiken aller BatchNorm-Schichten
+// mit den Statistiken der Batches batches nach, die zusammen einen Mini-Batch
+// im Trainingsmodus bilden.
+func (m *MLP[T]) updateRunningStats(batches []*Batch[T]) {
+	for l, layer := range m.Layers {
+		if layer.Norm == nil || layer.Norm.Kind != BatchNorm {
+			continue
+		}
+		works := make([]*normWork[T], len(batches))
+		for i, b := range batches {
+			works[i] = b.norm[l]
+		}
+		layer.Norm.updateRunningStats(works)
+	}
+}
+
+// Output liefert die Ausgabe des Netzes nach ForwardBatch.
+func (b *Batch[T]) Output() *Matrix[T] {
+	return b.A[len(b.A)-1]
+}
+
+// ForwardBatch berechnet den Forward-Pass für alle Zeilen von b.Input und
+// liefert die Ausgabe des Netzes (eine Zeile je Beispiel).
+func (m *MLP[T]) ForwardBatch(b *Batch[T]) *Matrix[T] {
+	m.forwardShards([]*Batch[T]{b}, serial)
+	return b.Output()
+}
+
+// forwardShards berechnet den Forward-Pass für die Shards batches eines
+// Mini-Batches, run (z. B. Trainer.parallel) verteilt die Shards auf die
+// Worker. Die Schichten werden nacheinander für alle Shards berechnet, denn
+// BatchNorm normalisiert im Training mit den Statistiken aller Shards.
+func (m *MLP[T]) forwardShards(batches []*Batch[T], run func(n int, fn func(i int))) {
+	n := len(batches)
+	run(n, func(s int) {
+		b := batches[s]
+		m.forwardConv(b, b.input().Rows)
+	})
+	for l, layer := range m.Layers {
+		run(n, func(s int) { m.forwardLinear(batches[s], l) })
+		if layer.Norm != nil {
+			works := make([]*normWork[T], n)
+			outs := make([]*Matrix[T], n)
+			for s, b := range batches {
+				works[s], outs[s] = b.norm[l], b.Z[l]
+			}
+			layer.Norm.forward(works, outs, batches[0].Train)
+		}
+		run(n, func(s int) { m.forwardActivation(batches[s], l) })
+	}
+}
+
+// forwardLinear berechnet Z[l] = A[l] * W^T + b von Dense-Schicht l, bei einer
+// Normalisierung deren Eingabe norm[l].pre.
+func (m *MLP[T]) forwardLinear(b *Batch[T], l int) {
+	layer := m.Layers[l]
+	rows := b.input().Rows
+	z := b.Z[l]
+	z.SetRows(rows)
+	lin := z
+	if layer.Norm != nil {
+		lin = b.norm[l].pre
+		lin.SetRows(rows)
+	}
+	MulABt(lin, b.A[l], layer.W)
+	for i := 0; i < rows; i++ {
+		axpy(1, layer.B, lin.Row(i))
+	}
+}
+
+// forwardActivation berechnet A[l+1] = Act(Z[l]) von Dense-Schicht l und
+// wendet auf versteckte Schichten Dropout an.
+func (m *MLP[T]) forwardActivation(b *Batch[T], l int) {
+	layer := m.Layers[l]
+	rows := b.input().Rows
+	z, a := b.Z[l], b.A[l+1]
+	a.SetRows(rows)
+	for i := 0; i < rows; i++ {
+		layer.Act.Forward(z.Row(i), a.Row(i))
+	}
+
+	// Dropout auf versteckten Schichten, A vorher für Act' sichern
+	if b.p > 0 && l < len(m.Layers)-1 {
+		act, mask := b.act[l+1], b.mask[l+1]
+		act.SetRows(rows)
+		mask.SetRows(rows)
+		copy(act.Data, a.Data)
+		dropout(b.rng, b.p, a.Data, mask.Data)
+	}
+}
+
+// serial führt fn für 0 bis n-1 nacheinander aus, wie Trainer.parallel mit
+// einem Worker.
+func serial(n int, fn func(i int)) {
+	for i := 0; i < n; i++ {
+		fn(i)
+	}
+}
+
+// forwardConv berechnet den Forward-Pass des Faltungsteils bis A[0] für die
+// ersten rows Beispiele von b.in.
+func (m *MLP[T]) forwardConv(b *Batch[T], rows int) {
+	for i, layer := range m.Conv {
+		x, a := b.in, b.convA[i]
+		if i > 0 {
+			x = b.convA[i-1]
+		}
+		a.SetRows(rows)
+		if b.convZ[i] != nil {
+			b.convZ[i].SetRows(rows)
+		}
+		var z []T
+		for r := 0; r < rows; r++ {
+			if b.convZ[i] != nil {
+				z = b.convZ[i].Row(r)
+			}
+			layer.forward(x.Row(r), z, a.Row(r), b.convWork[i])
+		}
+	}
+}
+
+// backwardConv berechnet nach BackwardBatch bis dA[0] die Gradienten des
+// Faltungsteils und addiert ihre Summe über alle Beispiele zu grads.
+func (m *MLP[T]) backwardConv(b *Batch[T], grads Gradients[T]) {
+	rows := b.in.Rows
+	for i := len(m.Conv) - 1; i >= 0; i-- {
+		layer := m.Conv[i]
+		x := b.in
+		var dx *Matrix[T]
+		if i > 0 {
+			x, dx = b.convA[i-1], b.convDA[i-1]
+			dx.SetRows(rows)
+		}
+		var z, dxRow []T
+		for r := 0; r < rows; r++ {
+			if b.convZ[i] != nil {
+				z = b.convZ[i].Row(r)
+			}
+			if dx != nil {
+				dxRow = dx.Row(r)
+			}
+			layer.backward(x.Row(r), z, b.convA[i].Row(r), b.convDA[i].Row(r), dxRow, grads.Conv[i], b.convWork[i])
+		}
+	}
+}
+
+// BackwardBatch berechnet nach ForwardBatch die Gradienten für die One-Hot-Labels
+// y (eine Zeile je Beispiel) und addiert ihre Summe über alle Beispiele zu grads.
+func (m *MLP[T]) BackwardBatch(b *Batch[T], y *Matrix[T], grads Gradients[T]) {
+	m.backwardShards([]*Batch[T]{b}, []*Matrix[T]{y}, []Gradients[T]{grads}, serial)
+}
+
+// backwardShards berechnet nach forwardShards die Gradienten der Shards
+// batches für die Labels ys und addiert die Summe über die Beispiele von
+// batches[i] zu grads[i].
+func (m *MLP[T]) backwardShards(batches []*Batch[T], ys []*Matrix[T], grads []Gradients[T], run func(n int, fn func(i int))) {
+	n := len(batches)
+	last := len(m.Layers) - 1
+	run(n, func(s int) {
+		b := batches[s]
+		dZ := b.delta[last]
+		dZ.SetRows(b.A[0].Rows)
+		out := b.Output()
+		for i := 0; i < dZ.Rows; i++ {
+			m.outputDelta(b.Z[last].Row(i), out.Row(i), ys[s].Row(i), dZ.Row(i))
+		}
+	})
+
+	for l := last; l >= 0; l-- {
+		// durch die Normalisierung: dZ = dLoss/d(A * W^T + b)
+		if norm := m.Layers[l].Norm; norm != nil {
+			works := make([]*normWork[T], n)
+			dOuts := make([]*Matrix[T], n)
+			gs := make([]*Norm[T], n)
+			for s, b := range batches {
+				works[s], dOuts[s], gs[s] = b.norm[l], b.delta[l], grads[s].Layers[l].Norm
+			}
+			norm.backward(works, dOuts, batches[0].Train, gs)
+		}
+		run(n, func(s int) { m.backwardLayer(batches[s], l, grads[s]) })
+	}
+}
+
+// backwardLayer berechnet aus dLoss/dZ[l] (nach der Normalisierung) die
+// Gradienten von Dense-Schicht l und dLoss/dZ[l-1] bzw. die Gradienten des
+// Faltungsteils.
+func (m *MLP[T]) backwardLayer(b *Batch[T], l int, grads Gradients[T]) {
+	rows := b.A[0].Rows
+	layer := m.Layers[l]
+	dZ := b.delta[l]
+	g := grads.Layers[l]
+	if layer.Norm != nil {
+		dZ = b.norm[l].dPre
+	}
+
+	// dW += dZ^T * A, dB += Spaltensummen von dZ
+	MulAtBAdd(g.W, dZ, b.A[l])
+	for i := 0; i < rows; i++ {
+		axpy(1, dZ.Row(i), g.B)
+	}
+
+	if l == 0 && len(m.Conv) == 0 {
+		return
+	}
+
+	// dZ der vorherigen Schicht = Act'(Z) * (dZ * W)
+	dA := b.dA[l]
+	dA.SetRows(rows)
+	MulAB(dA, dZ, layer.W)
+	if l == 0 {
+		m.backwardConv(b, grads)
+		return
+	}
+	dPrev := b.delta[l-1]
+	dPrev.SetRows(rows)
+	prev := m.Layers[l-1].Act
+	a := b.A[l]
+	if b.p > 0 {
+		// Gradient durch die Dropout-Maske, Act' mit A vor dem Dropout
+		mask := b.mask[l]
+		for i := range dA.Data {
+			dA.Data[i] *= mask.Data[i]
+		}
+		a = b.act[l]
+	}
+	for i := 0; i < rows; i++ {
+		prev.Backward(b.Z[l-1].Row(i), a.Row(i), dA.Row(i), dPrev.Row(i))
+	}
+}
@@ -0,0 +1,216 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// batchOf kopiert die Beispiele X in den Eingabespeicher von b und die Labels
+// Y in eine neue Matrix.
+func batchOf[T Float](b *Batch[T], X, Y [][]T) *Matrix[T] {
+	x := b.Input(len(X))
+	y := NewMatrix[T](len(Y), len(Y[0]))
+	for i := range X {
+		copy(x.Row(i), X[i])
+		copy(y.Row(i), Y[i])
+	}
+	return y
+}
+
+// perturbNorms fügt allen versteckten Schichten von m eine Normalisierung kind
+// mit zufälligen Parametern und gleitenden Statistiken hinzu.
+func perturbNorms(t *testing.T, m *MLP[float64], kind string, rng *rand.Rand) {
+	if err := m.AddNorm(kind); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range m.Layers[:len(m.Layers)-1] {
+		n := l.Norm
+		for i := range n.Gamma {
+			n.Gamma[i] = 0.5 + rng.Float64()
+			n.Beta[i] = rng.NormFloat64()
+			if n.RunMean != nil {
+				n.RunMean[i] = 0.1 * rng.NormFloat64()
+				n.RunVar[i] = 0.5 + rng.Float64()
+			}
+		}
+	}
+}
+
+func TestBatchMatchesPerSample(t *testing.T) {
+	X, Y := syntheticData(23, 12, 4, 1)
+	for _, norm := range []string{"", BatchNorm, LayerNorm} {
+		for _, acts := range [][]Activation[float64]{
+			nil,
+			{Tanh[float64]{}, Sigmoid[float64]{}, Softmax[float64]{}},
+			{LeakyReLU[float64]{Alpha: 0.1}, GELU[float64]{}, Sigmoid[float64]{}},
+		} {
+			rng := rand.New(rand.NewSource(3))
+			m := NewMLP(rng, []int{12, 9, 7, 4}, acts...)
+			if norm != "" {
+				perturbNorms(t, m, norm, rng)
+			}
+
+			want := m.NewGradients()
+			for i := range X {
+				zs, as := m.Forward(X[i])
+				want.Add(m.Backward(zs, as, Y[i]))
+			}
+
+			b := m.NewBatch(len(X))
+			y := batchOf(b, X, Y)
+			out := m.ForwardBatch(b)
+			for i := range X {
+				_, as := m.Forward(X[i])
+				for j, v := range as[len(as)-1] {
+					if math.Abs(out.At(i, j)-v) > 1e-12 {
+						t.Fatalf("%q %v: Ausgabe (%d, %d) = %g, erwartet %g", norm, m.Activations(), i, j, out.At(i, j), v)
+					}
+				}
+			}
+			got := m.NewGradients()
+			m.BackwardBatch(b, y, got)
+
+			gv := got.vectors()
+			for k, v := range want.vectors() {
+				for i := range v {
+					if math.Abs(gv[k][i]-v[i]) > 1e-12 {
+						t.Fatalf("%q %v: Gradient %d[%d] = %g, erwartet %g", norm, m.Activations(), k, i, gv[k][i], v[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestNormTrainingGradients vergleicht die Gradienten von BackwardBatch im
+// Trainingsmodus, in dem BatchNorm die Statistiken des Batches verwendet, mit
+// Differenzenquotienten des Loss.
+func TestNormTrainingGradients(t *testing.T) {
+	X, Y := syntheticData(16, 12, 4, 1)
+	for _, norm := range NormNames {
+		rng := rand.New(rand.NewSource(3))
+		m := NewMLP(rng, []int{12, 9, 7, 4}, Tanh[float64]{}, ReLU[float64]{}, Softmax[float64]{})
+		perturbNorms(t, m, norm, rng)
+		for _, l := range m.Layers {
+			for i := range l.W.Data {
+				l.W.Data[i] *= 30
+			}
+		}
+
+		b := m.NewBatch(len(X))
+		b.Train = true
+		y := batchOf(b, X, Y)
+		loss := func() float64 {
+			out := m.ForwardBatch(b)
+			var sum float64
+			for i := 0; i < out.Rows; i++ {
+				sum += crossEntropyLoss(y.Row(i), out.Row(i))
+			}
+			return sum
+		}
+		loss()
+		grads := m.NewGradients()
+		m.BackwardBatch(b, y, grads)
+
+		checkGradients(t, m.Params(grads), loss)
+	}
+}
+
+func TestGEMM(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(rows, cols int) *Matrix[float64] {
+		m := NewMatrix[float64](rows, cols)
+		for i := range m.Data {
+			m.Data[i] = rng.NormFloat64()
+		}
+		return m
+	}
+	// Dimensionen, die nicht durch die Blockgrößen teilbar sind
+	a, b := random(7, 5), random(70, 5)
+
+	c := NewMatrix[float64](7, 70)
+	MulABt(c, a, b)
+	d := NewMatrix[float64](7, 5)
+	MulAB(d, c, b)
+	e := random(5, 5)
+	e0 := append([]float64(nil), e.Data...)
+	MulAtBAdd(e, a, d)
+
+	for i := 0; i < 7; i++ {
+		for j := 0; j < 70; j++ {
+			if want := dot(a.Row(i), b.Row(j)); math.Abs(c.At(i, j)-want) > 1e-12 {
+				t.Fatalf("MulABt: (%d, %d) = %g, erwartet %g", i, j, c.At(i, j), want)
+			}
+		}
+		for j := 0; j < 5; j++ {
+			var want float64
+			for p := 0; p < 70; p++ {
+				want += c.At(i, p) * b.At(p, j)
+			}
+			if math.Abs(d.At(i, j)-want) > 1e-9 {
+				t.Fatalf("MulAB: (%d, %d) = %g, erwartet %g", i, j, d.At(i, j), want)
+			}
+		}
+	}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			want := e0[i*5+j]
+			for p := 0; p < 7; p++ {
+				want += a.At(p, i) * d.At(p, j)
+			}
+			if math.Abs(e.At(i, j)-want) > 1e-9 {
+				t.Fatalf("MulAtBAdd: (%d, %d) = %g, erwartet %g", i, j, e.At(i, j), want)
+			}
+		}
+	}
+}
+
+// benchBatch ist die Größe des Mini-Batches in den Benchmarks, das Netz hat
+// die Form des Standard-MNIST-Modells.
+const benchBatch = 64
+
+func benchSetup[T Float]() (*MLP[T], [][]T, [][]T) {
+	X, Y := syntheticData(benchBatch, 784, 10, 1)
+	m := NewMLP[T](rand.New(rand.NewSource(1)), []int{784, 512, 10})
+	return m, ConvertRows[T](X), ConvertRows[T](Y)
+}
+
+// BenchmarkTrainStepPerSample misst einen Trainingsschritt mit Forward und
+// Backward pro Beispiel.
+func BenchmarkTrainStepPerSample(b *testing.B) {
+	m, X, Y := benchSetup[float64]()
+	grads := m.NewGradients()
+	for n := 0; n < b.N; n++ {
+		grads.Zero()
+		for i := range X {
+			zs, as := m.Forward(X[i])
+			grads.Add(m.Backward(zs, as, Y[i]))
+		}
+	}
+}
+
+// BenchmarkTrainStepBatched misst denselben Trainingsschritt mit
+// ForwardBatch und BackwardBatch.
+func BenchmarkTrainStepBatched(b *testing.B) {
+	benchTrainStepBatched[float64](b)
+}
+
+// BenchmarkTrainStepBatched32 misst den Trainingsschritt mit ForwardBatch
+// und BackwardBatch in float32.
+func BenchmarkTrainStepBatched32(b *testing.B) {
+	benchTrainStepBatched[float32](b)
+}
+
+func benchTrainStepBatched[T Float](b *testing.B) {
+	m, X, Y := benchSetup[T]()
+	grads := m.NewGradients()
+	batch := m.NewBatch(benchBatch)
+	y := batchOf(batch, X, Y)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		grads.Zero()
+		m.ForwardBatch(batch)
+		m.BackwardBatch(batch, y, grads)
+	}
+}
@@ -0,0 +1,387 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Shape ist die Form der Ein- oder Ausgabe einer Faltungsschicht: C Kanäle
+// (Feature Maps) mit je H x W Werten. Die Werte liegen kanalweise und darin
+// zeilenweise hintereinander (CHW); ein MNIST-Bild hat also die Form
+// {1, 28, 28} und dieselbe Reihenfolge wie der Eingabevektor eines MLP.
+type Shape struct {
+	C int `json:"c" yaml:"c"`
+	H int `json:"h" yaml:"h"`
+	W int `json:"w" yaml:"w"`
+}
+
+// Size liefert die Anzahl der Werte.
+func (s Shape) Size() int {
+	return s.C * s.H * s.W
+}
+
+func (s Shape) String() string {
+	return fmt.Sprintf("%dx%dx%d", s.C, s.H, s.W)
+}
+
+// Arten der Schichten im Faltungsteil, wie sie z. B. in Modelldateien
+// gespeichert werden.
+const (
+	Conv2D  = "conv2d"
+	MaxPool = "maxpool"
+	AvgPool = "avgpool"
+	Flatten = "flatten"
+)
+
+// ConvKinds sind die Arten aller Schichten im Faltungsteil.
+var ConvKinds = []string{Conv2D, MaxPool, AvgPool, Flatten}
+
+// LayerSpec beschreibt eine Schicht des Faltungsteils, z. B. in der
+// Konfiguration oder den Metadaten eines Modells.
+type LayerSpec struct {
+	Kind string `json:"kind" yaml:"kind"`
+	// Filters ist die Anzahl der Filter (Ausgabekanäle) von Conv2D.
+	Filters int `json:"filters,omitempty" yaml:"filters,omitempty"`
+	// Size ist die Kantenlänge der Filter bzw. des Pooling-Fensters.
+	Size int `json:"size,omitempty" yaml:"size,omitempty"`
+	// Stride ist die Schrittweite, 0 bedeutet 1 bei Conv2D und Size beim
+	// Pooling.
+	Stride int `json:"stride,omitempty" yaml:"stride,omitempty"`
+	// Pad ist die Breite des Rands aus Nullen um die Eingabe von Conv2D.
+	Pad int `json:"pad,omitempty" yaml:"pad,omitempty"`
+	// Activation ist die Aktivierung von Conv2D, leer für ReLU.
+	Activation string `json:"activation,omitempty" yaml:"activation,omitempty"`
+}
+
+func (s LayerSpec) String() string {
+	switch s.Kind {
+	case Conv2D:
+		return fmt.Sprintf("%s(%d, %dx%d, stride %d, pad %d, %s)", s.Kind, s.Filters, s.Size, s.Size, s.Stride, s.Pad, s.Activation)
+	case MaxPool, AvgPool:
+		return fmt.Sprintf("%s(%dx%d, stride %d)", s.Kind, s.Size, s.Size, s.Stride)
+	}
+	return s.Kind
+}
+
+// ConvLayer ist eine Schicht des Faltungsteils eines Netzes (siehe MLP.Conv).
+// Je nach Kind ist sie
+//
+//   - Conv2D: eine Faltung der Eingabe mit Out.C Filtern der Größe
+//     In.C x Size x Size, gefolgt von der Aktivierung Act,
+//   - MaxPool bzw. AvgPool: das Maximum bzw. der Mittelwert jedes Fensters von
+//     Size x Size Werten, je Kanal,
+//   - Flatten: der Übergang zu den Dense-Schichten. Die Werte bleiben
+//     unverändert, nur die Form wird flach.
+type ConvLayer[T Float] struct {
+	Kind    string
+	In, Out Shape
+	// Size, Stride und Pad wie in LayerSpec, Stride ist immer gesetzt.
+	Size, Stride, Pad int
+	// W enthält bei Conv2D einen Filter je Zeile (Out.C x In.C*Size*Size), B
+	// den Bias je Filter; sonst sind beide nil.
+	W   *Matrix[T]
+	B   []T
+	Act Activation[T]
+}
+
+// NewConvLayers erzeugt die Schichten specs eines Faltungsteils für Eingaben
+// der Form in. Die letzte Schicht muss Flatten sein. Die Gewichte von Conv2D
+// werden mit rng nach He et al. (2015) initialisiert, bei rng == nil sind sie
+// 0, z. B. um sie anschließend aus einer Datei zu laden.
+func NewConvLayers[T Float](rng *rand.Rand, in Shape, specs []LayerSpec) ([]*ConvLayer[T], error) {
+	if len(specs) == 0 || specs[len(specs)-1].Kind != Flatten {
+		return nil, fmt.Errorf("der Faltungsteil muss mit %s enden", Flatten)
+	}
+	if in.C <= 0 || in.H <= 0 || in.W <= 0 {
+		return nil, fmt.Errorf("ungültige Eingabeform %v", in)
+	}
+	layers := make([]*ConvLayer[T], len(specs))
+	for i, s := range specs {
+		if s.Kind == Flatten && i < len(specs)-1 {
+			return nil, fmt.Errorf("Faltungsschicht %d: %s ist nur als letzte Schicht erlaubt", i, Flatten)
+		}
+		l, err := newConvLayer[T](rng, in, s)
+		if err != nil {
+			return nil, fmt.Errorf("Faltungsschicht %d: %v", i, err)
+		}
+		layers[i] = l
+		in = l.Out
+	}
+	return layers, nil
+}
+
+// newConvLayer erzeugt die Schicht s für Eingaben der Form in.
+func newConvLayer[T Float](rng *rand.Rand, in Shape, s LayerSpec) (*ConvLayer[T], error) {
+	l := &ConvLayer[T]{Kind: s.Kind, In: in, Size: s.Size, Stride: s.Stride, Pad: s.Pad}
+	switch s.Kind {
+	case Flatten:
+		l.Out = Shape{C: in.Size(), H: 1, W: 1}
+		return l, nil
+	case Conv2D:
+		if s.Filters <= 0 {
+			return nil, fmt.Errorf("%s erfordert filters > 0", s.Kind)
+		}
+		if l.Stride == 0 {
+			l.Stride = 1
+		}
+		name := s.Activation
+		if name == "" {
+			name = "relu"
+		}
+		act, err := ParseActivation[T](name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := act.(Softmax[T]); ok {
+			return nil, fmt.Errorf("softmax ist im Faltungsteil nicht erlaubt")
+		}
+		l.Act = act
+	case MaxPool, AvgPool:
+		if s.Filters != 0 || s.Pad != 0 || s.Activation != "" {
+			return nil, fmt.Errorf("%s hat weder filters noch pad noch activation", s.Kind)
+		}
+		if l.Stride == 0 {
+			l.Stride = s.Size
+		}
+	default:
+		return nil, fmt.Errorf("unbekannte Schicht %q, erlaubt sind %v", s.Kind, ConvKinds)
+	}
+
+	if l.Size <= 0 || l.Stride <= 0 || l.Pad < 0 {
+		return nil, fmt.Errorf("ungültige Größe %d, Schrittweite %d oder Rand %d", l.Size, l.Stride, l.Pad)
+	}
+	h, w := in.H+2*l.Pad-l.Size, in.W+2*l.Pad-l.Size
+	if h < 0 || w < 0 {
+		return nil, fmt.Errorf("Fenster %dx%d ist größer als die Eingabe %v", l.Size, l.Size, in)
+	}
+	l.Out = Shape{C: in.C, H: h/l.Stride + 1, W: w/l.Stride + 1}
+	if s.Kind != Conv2D {
+		return l, nil
+	}
+
+	l.Out.C = s.Filters
+	fanIn := in.C * l.Size * l.Size
+	l.W = NewMatrix[T](s.Filters, fanIn)
+	l.B = make([]T, s.Filters)
+	if rng != nil {
+		std := math.Sqrt(2 / float64(fanIn))
+		for i := range l.W.Data {
+			l.W.Data[i] = T(rng.NormFloat64() * std)
+		}
+	}
+	return l, nil
+}
+
+// Spec liefert die Beschreibung der Schicht, aus der NewConvLayers sie erzeugt.
+func (l *ConvLayer[T]) Spec() LayerSpec {
+	s := LayerSpec{Kind: l.Kind}
+	switch l.Kind {
+	case Conv2D:
+		s.Filters, s.Size, s.Stride, s.Pad, s.Activation = l.Out.C, l.Size, l.Stride, l.Pad, l.Act.Name()
+	case MaxPool, AvgPool:
+		s.Size, s.Stride = l.Size, l.Stride
+	}
+	return s
+}
+
+// newGradients liefert eine Schicht derselben Form mit 0 als Parametern, die
+// die Gradienten aufnimmt.
+func (l *ConvLayer[T]) newGradients() *ConvLayer[T] {
+	g := &ConvLayer[T]{Kind: l.Kind, In: l.In, Out: l.Out, Size: l.Size, Stride: l.Stride, Pad: l.Pad}
+	if l.W != nil {
+		g.W = NewMatrix[T](l.W.Rows, l.W.Cols)
+		g.B = make([]T, len(l.B))
+	}
+	return g
+}
+
+// convWork ist der Arbeitsspeicher von Conv2D für ein Beispiel: cols enthält
+// je Ausgabeposition die Werte unter dem Filter (im2col), zt die Ausgabe je
+// Position und Filter bzw. deren Gradienten, dz den Gradienten der Eingabe der
+// Aktivierung und dcols den Gradienten von cols.
+type convWork[T Float] struct {
+	cols, zt, dcols *Matrix[T]
+	dz              []T
+}
+
+// newConvWork liefert den Arbeitsspeicher für l, nil außer bei Conv2D.
+func newConvWork[T Float](l *ConvLayer[T]) *convWork[T] {
+	if l.Kind != Conv2D {
+		return nil
+	}
+	positions := l.Out.H * l.Out.W
+	return &convWork[T]{
+		cols:  NewMatrix[T](positions, l.W.Cols),
+		zt:    NewMatrix[T](positions, l.Out.C),
+		dcols: NewMatrix[T](positions, l.W.Cols),
+		dz:    make([]T, l.Out.Size()),
+	}
+}
+
+// forward berechnet die Ausgabe a eines Beispiels aus der Eingabe x. Bei
+// Conv2D wird die Eingabe der Aktivierung nach z geschrieben, sonst wird z
+// nicht verwendet.
+func (l *ConvLayer[T]) forward(x, z, a []T, w *convWork[T]) {
+	switch l.Kind {
+	case Conv2D:
+		// Z^T = cols * W^T, dann je Filter transponiert mit Bias
+		l.im2col(x, w.cols)
+		MulABt(w.zt, w.cols, l.W)
+		positions := w.zt.Rows
+		for f, b := range l.B {
+			zf := z[f*positions : (f+1)*positions]
+			for p := range zf {
+				zf[p] = w.zt.Data[p*w.zt.Cols+f] + b
+			}
+		}
+		l.Act.Forward(z, a)
+	case MaxPool, AvgPool:
+		l.pool(x, a, nil, nil)
+	case Flatten:
+		copy(a, x)
+	}
+}
+
+// backward berechnet aus x, z und a = forward(x) sowie da = dLoss/da eines
+// Beispiels dx = dLoss/dx, falls dx nicht nil ist, und addiert die
+// Gradienten von W und B zu g.
+func (l *ConvLayer[T]) backward(x, z, a, da, dx []T, g *ConvLayer[T], w *convWork[T]) {
+	switch l.Kind {
+	case Conv2D:
+		l.Act.Backward(z, a, da, w.dz)
+		positions := w.zt.Rows
+		for f := range l.B {
+			dzf := w.dz[f*positions : (f+1)*positions]
+			for p, v := range dzf {
+				g.B[f] += v
+				w.zt.Data[p*w.zt.Cols+f] = v
+			}
+		}
+		// dW += dZ * cols, dcols = dZ^T * W
+		l.im2col(x, w.cols)
+		MulAtBAdd(g.W, w.zt, w.cols)
+		if dx != nil {
+			MulAB(w.dcols, w.zt, l.W)
+			l.col2im(w.dcols, dx)
+		}
+	case MaxPool, AvgPool:
+		if dx != nil {
+			l.pool(x, a, da, dx)
+		}
+	case Flatten:
+		if dx != nil {
+			copy(dx, da)
+		}
+	}
+}
+
+// im2col schreibt für jede Ausgabeposition die Werte von x unter dem Filter
+// als Zeile nach cols, in der Reihenfolge der Gewichte eines Filters. Werte
+// im Rand sind 0.
+func (l *ConvLayer[T]) im2col(x []T, cols *Matrix[T]) {
+	in, k := l.In, l.Size
+	p := 0
+	for oy := 0; oy < l.Out.H; oy++ {
+		for ox := 0; ox < l.Out.W; ox++ {
+			row := cols.Row(p)
+			j := 0
+			for c := 0; c < in.C; c++ {
+				xc := x[c*in.H*in.W : (c+1)*in.H*in.W]
+				for ky := 0; ky < k; ky++ {
+					iy := oy*l.Stride + ky - l.Pad
+					for kx := 0; kx < k; kx++ {
+						ix := ox*l.Stride + kx - l.Pad
+						if iy >= 0 && iy < in.H && ix >= 0 && ix < in.W {
+							row[j] = xc[iy*in.W+ix]
+						} else {
+							row[j] = 0
+						}
+						j++
+					}
+				}
+			}
+			p++
+		}
+	}
+}
+
+// col2im ist die Umkehrung von im2col für Gradienten: dx ist die Summe der
+// Beiträge aller Zeilen von dcols.
+func (l *ConvLayer[T]) col2im(dcols *Matrix[T], dx []T) {
+	clear(dx)
+	in, k := l.In, l.Size
+	p := 0
+	for oy := 0; oy < l.Out.H; oy++ {
+		for ox := 0; ox < l.Out.W; ox++ {
+			row := dcols.Row(p)
+			j := 0
+			for c := 0; c < in.C; c++ {
+				dxc := dx[c*in.H*in.W : (c+1)*in.H*in.W]
+				for ky := 0; ky < k; ky++ {
+					iy := oy*l.Stride + ky - l.Pad
+					for kx := 0; kx < k; kx++ {
+						ix := ox*l.Stride + kx - l.Pad
+						if iy >= 0 && iy < in.H && ix >= 0 && ix < in.W {
+							dxc[iy*in.W+ix] += row[j]
+						}
+						j++
+					}
+				}
+			}
+			p++
+		}
+	}
+}
+
+// pool berechnet das Pooling von x nach a. Ist da nicht nil, wird stattdessen
+// dx = dLoss/dx aus da = dLoss/da berechnet: bei MaxPool erhält das erste
+// Maximum jedes Fensters den Gradienten, bei AvgPool alle Werte zu gleichen
+// Teilen.
+func (l *ConvLayer[T]) pool(x, a, da, dx []T) {
+	in, out, k := l.In, l.Out, l.Size
+	if da != nil {
+		clear(dx)
+	}
+	scale := 1 / T(k*k)
+	for c := 0; c < in.C; c++ {
+		xc := x[c*in.H*in.W : (c+1)*in.H*in.W]
+		for oy := 0; oy < out.H; oy++ {
+			for ox := 0; ox < out.W; ox++ {
+				o := (c*out.H+oy)*out.W + ox
+				y0, x0 := oy*l.Stride, ox*l.Stride
+				if l.Kind == AvgPool {
+					var sum T
+					for ky := 0; ky < k; ky++ {
+						for kx := 0; kx < k; kx++ {
+							i := (y0+ky)*in.W + x0 + kx
+							if da != nil {
+								dx[c*in.H*in.W+i] += da[o] * scale
+							} else {
+								sum += xc[i]
+							}
+						}
+					}
+					if da == nil {
+						a[o] = sum * scale
+					}
+					continue
+				}
+
+				best := y0*in.W + x0
+				for ky := 0; ky < k; ky++ {
+					for kx := 0; kx < k; kx++ {
+						if i := (y0+ky)*in.W + x0 + kx; xc[i] > xc[best] {
+							best = i
+						}
+					}
+				}
+				if da != nil {
+					dx[c*in.H*in.W+best] += da[o]
+				} else {
+					a[o] = xc[best]
+				}
+			}
+		}
+	}
+}
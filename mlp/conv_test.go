@@ -0,0 +1,170 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testConvNet erzeugt ein kleines Netz mit allen Arten von Faltungsschichten
+// für Eingaben der Form 2x7x7.
+func testConvNet(t *testing.T, rng *rand.Rand) *MLP[float64] {
+	in := Shape{C: 2, H: 7, W: 7}
+	conv := []LayerSpec{
+		{Kind: Conv2D, Filters: 3, Size: 3, Pad: 1, Activation: "tanh"},
+		{Kind: MaxPool, Size: 2},
+		{Kind: Conv2D, Filters: 4, Size: 2, Activation: "sigmoid"},
+		{Kind: AvgPool, Size: 2, Stride: 1},
+		{Kind: Flatten},
+	}
+	m, err := NewConvNet[float64](rng, in, conv, []int{in.Size(), 5, 3}, Tanh[float64]{}, Softmax[float64]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range m.Conv {
+		for i := range l.B {
+			l.B[i] = 0.1 * rng.NormFloat64()
+		}
+	}
+	return m
+}
+
+func TestConvShapes(t *testing.T) {
+	lenet := []LayerSpec{
+		{Kind: Conv2D, Filters: 6, Size: 5, Pad: 2},
+		{Kind: MaxPool, Size: 2},
+		{Kind: Conv2D, Filters: 16, Size: 5},
+		{Kind: AvgPool, Size: 2},
+		{Kind: Flatten},
+	}
+	layers, err := NewConvLayers[float64](nil, Shape{C: 1, H: 28, W: 28}, lenet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Shape{{6, 28, 28}, {6, 14, 14}, {16, 10, 10}, {16, 5, 5}, {400, 1, 1}}
+	for i, l := range layers {
+		if l.Out != want[i] {
+			t.Errorf("Schicht %d: Ausgabe %v, erwartet %v", i, l.Out, want[i])
+		}
+	}
+	if s := layers[1].Spec(); s.Stride != 2 {
+		t.Errorf("Schrittweite von maxpool ist %d, erwartet 2", s.Stride)
+	}
+
+	for name, specs := range map[string][]LayerSpec{
+		"ohne Flatten":         lenet[:4],
+		"Flatten in der Mitte": {{Kind: Flatten}, {Kind: Flatten}},
+		"Softmax":              {{Kind: Conv2D, Filters: 2, Size: 3, Activation: "softmax"}, {Kind: Flatten}},
+		"Filter zu groß":       {{Kind: Conv2D, Filters: 2, Size: 29}, {Kind: Flatten}},
+		"unbekannt":            {{Kind: "dropout"}, {Kind: Flatten}},
+	} {
+		if _, err := NewConvLayers[float64](nil, Shape{C: 1, H: 28, W: 28}, specs); err == nil {
+			t.Errorf("%s: kein Fehler", name)
+		}
+	}
+}
+
+// TestConvGradients vergleicht die Gradienten von BackwardBatch mit denen von
+// Backward für einzelne Beispiele und mit Differenzenquotienten des Loss.
+func TestConvGradients(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	m := testConvNet(t, rng)
+	X, Y := syntheticData(5, m.InputDim(), 3, 2)
+
+	want := m.NewGradients()
+	for i := range X {
+		zs, as := m.Forward(X[i])
+		want.Add(m.Backward(zs, as, Y[i]))
+	}
+
+	b := m.NewBatch(len(X))
+	y := batchOf(b, X, Y)
+	loss := func() float64 {
+		out := m.ForwardBatch(b)
+		var sum float64
+		for i := 0; i < out.Rows; i++ {
+			sum += crossEntropyLoss(y.Row(i), out.Row(i))
+		}
+		return sum
+	}
+	loss()
+	grads := m.NewGradients()
+	m.BackwardBatch(b, y, grads)
+
+	wv := want.vectors()
+	params := m.Params(grads)
+	for k, p := range params {
+		for i := range p.Value {
+			if math.Abs(wv[k][i]-p.Grad[i]) > 1e-12 {
+				t.Fatalf("Parameter %d[%d]: BackwardBatch %g, Backward %g", k, i, p.Grad[i], wv[k][i])
+			}
+		}
+	}
+	checkGradients(t, params, loss)
+}
+
+// blobData erzeugt n verrauschte Bilder der Form 2x7x7 mit einem hellen
+// 2x2-Fleck an zufälliger Stelle, für Klasse 0 in Kanal 0, für Klasse 1 in
+// Kanal 1 und für Klasse 2 in beiden Kanälen.
+func blobData(n int, seed int64) (X, Y [][]float64) {
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		x := make([]float64, 2*7*7)
+		for j := range x {
+			x[j] = 0.2 * rng.Float64()
+		}
+		class := rng.Intn(3)
+		py, px := rng.Intn(6), rng.Intn(6)
+		for c := 0; c < 2; c++ {
+			if class != 2 && class != c {
+				continue
+			}
+			for _, d := range []int{0, 1, 7, 8} {
+				x[c*49+py*7+px+d] = 1
+			}
+		}
+		y := make([]float64, 3)
+		y[class] = 1
+		X = append(X, x)
+		Y = append(Y, y)
+	}
+	return X, Y
+}
+
+// TestConvTraining prüft, dass ein Faltungsnetz unabhängig von der Anzahl der
+// Worker bitgenau gleich trainiert wird und lernt.
+func TestConvTraining(t *testing.T) {
+	X, Y := blobData(120, 4)
+	run := func(workers int) (*MLP[float64], float64) {
+		m := testConvNet(t, rand.New(rand.NewSource(1)))
+		tr := &Trainer[float64]{
+			Model:        m,
+			Epochs:       80,
+			BatchSize:    40,
+			LearningRate: 0.01,
+			Optimizer:    &Adam[float64]{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8},
+			Workers:      workers,
+			Rand:         rand.New(rand.NewSource(2)),
+		}
+		if err := tr.Run(Slices[float64]{X: X, Y: Y}, nil); err != nil {
+			t.Fatal(err)
+		}
+		_, acc := tr.Evaluate(Slices[float64]{X: X, Y: Y})
+		return m, acc
+	}
+	a, acc := run(1)
+	b, _ := run(4)
+	for i, l := range a.Conv {
+		if l.W == nil {
+			continue
+		}
+		for j, v := range l.W.Data {
+			if v != b.Conv[i].W.Data[j] {
+				t.Fatalf("Faltungsschicht %d: Gewicht %d unterscheidet sich: %g != %g", i, j, v, b.Conv[i].W.Data[j])
+			}
+		}
+	}
+	if acc < 0.9 {
+		t.Errorf("Genauigkeit nach dem Training %.2f, erwartet mindestens 0.9", acc)
+	}
+}
@@ -0,0 +1,76 @@
+package mlp
+
+// Dataset ist eine Menge von Beispielen mit wahlfreiem Zugriff, die Trainer
+// mini-batch-weise in der Reihenfolge einer Permutation durchläuft. Die
+// Beispiele werden erst beim Zugriff in die Genauigkeit T umgewandelt, ein
+// Dataset kann die Daten also kompakt halten (z. B. Pixel als uint8).
+// Example wird parallel aus mehreren Goroutinen aufgerufen.
+type Dataset[T Float] interface {
+	// Len liefert die Anzahl der Beispiele.
+	Len() int
+	// Example schreibt die Eingabe des Beispiels i nach x und sein
+	// One-Hot-Label nach y.
+	Example(i int, x, y []T)
+	// Class liefert die Klasse des Beispiels i, also den Index der 1 im
+	// One-Hot-Label.
+	Class(i int) int
+}
+
+// Slices ist ein Dataset aus den Eingaben X und den One-Hot-Labels Y im
+// Speicher.
+type Slices[T Float] struct {
+	X, Y [][]T
+}
+
+func (s Slices[T]) Len() int {
+	return len(s.X)
+}
+
+func (s Slices[T]) Example(i int, x, y []T) {
+	copy(x, s.X[i])
+	copy(y, s.Y[i])
+}
+
+func (s Slices[T]) Class(i int) int {
+	return argmax(s.Y[i])
+}
+
+// Subset ist der Dataset aus den Beispielen Index von D in dieser
+// Reihenfolge, z. B. die Validierungsdaten aus StratifiedSplit.
+type Subset[T Float] struct {
+	D     Dataset[T]
+	Index []int
+}
+
+func (s Subset[T]) Len() int {
+	return len(s.Index)
+}
+
+func (s Subset[T]) Example(i int, x, y []T) {
+	s.D.Example(s.Index[i], x, y)
+}
+
+func (s Subset[T]) Class(i int) int {
+	return s.D.Class(s.Index[i])
+}
+
+// Head liefert die ersten n Beispiele von d, alle, wenn d weniger hat.
+func Head[T Float](d Dataset[T], n int) Dataset[T] {
+	if n >= d.Len() {
+		return d
+	}
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return Subset[T]{D: d, Index: idx}
+}
+
+// readBatch schreibt die Beispiele idx von d in die Zeilen von x und y.
+func readBatch[T Float](d Dataset[T], idx []int, x, y *Matrix[T]) {
+	x.SetRows(len(idx))
+	y.SetRows(len(idx))
+	for k, i := range idx {
+		d.Example(i, x.Row(k), y.Row(k))
+	}
+}
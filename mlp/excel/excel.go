@@ -0,0 +1,418 @@
+// Package excel schreibt Trainingsläufe und Auswertungen als
+// Excel-Arbeitsmappe (.xlsx), damit sie sich in Excel analysieren lassen:
+// Hyperparameter, Verlauf je Epoche mit Diagrammen, Konfusionsmatrix als
+// Heatmap, Kennzahlen je Klasse und die falsch klassifizierten Beispiele.
+package excel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// Run ist der Inhalt einer Arbeitsmappe. Für leere Felder entfallen die
+// zugehörigen Tabellenblätter.
+type Run struct {
+	// Title steht über den Hyperparametern, z. B. der Name der Modelldatei.
+	Title string
+	// Hyperparameters ist die Konfiguration als JSON-Objekt, z. B.
+	// modelio.Metadata.Hyperparameters. Verschachtelte Objekte ergeben
+	// Schlüssel wie optimizer.lr.
+	Hyperparameters json.RawMessage
+	// History ist der Verlauf des Trainings je Epoche. Er enthält nur die
+	// Zahlen aus mlp.EpochStats: Loss und Genauigkeit auf den Trainingsdaten
+	// und auf den Validierungsdaten, keine Testgenauigkeit je Epoche.
+	History []mlp.EpochStats
+	// Report ist die Auswertung auf den Testdaten nach dem Training.
+	Report *mlp.Report
+}
+
+// Namen der Tabellenblätter
+const (
+	sheetParams    = "Hyperparameter"
+	sheetHistory   = "Verlauf"
+	sheetConfusion = "Konfusionsmatrix"
+	sheetMetrics   = "Kennzahlen"
+	sheetErrors    = "Fehler"
+)
+
+// Write schreibt run als Arbeitsmappe nach filename.
+func Write(filename string, run *Run) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	w := &workbook{File: f}
+	if err := w.init(); err != nil {
+		return err
+	}
+
+	// Das erste Blatt existiert in einer neuen Arbeitsmappe bereits.
+	if err := f.SetSheetName(f.GetSheetName(0), sheetParams); err != nil {
+		return err
+	}
+	if err := w.params(run); err != nil {
+		return fmt.Errorf("Blatt %s: %v", sheetParams, err)
+	}
+	if len(run.History) > 0 {
+		if err := w.history(run.History); err != nil {
+			return fmt.Errorf("Blatt %s: %v", sheetHistory, err)
+		}
+	}
+	if r := run.Report; r != nil {
+		if err := w.confusion(r); err != nil {
+			return fmt.Errorf("Blatt %s: %v", sheetConfusion, err)
+		}
+		if err := w.metrics(r); err != nil {
+			return fmt.Errorf("Blatt %s: %v", sheetMetrics, err)
+		}
+		if err := w.errors(r); err != nil {
+			return fmt.Errorf("Blatt %s: %v", sheetErrors, err)
+		}
+	}
+	if err := f.SaveAs(filename); err != nil {
+		return fmt.Errorf("Fehler beim Schreiben von %s: %v", filename, err)
+	}
+	return nil
+}
+
+// workbook ist eine Arbeitsmappe mit den gemeinsamen Zellformaten.
+type workbook struct {
+	*excelize.File
+	bold, percent, decimal int
+}
+
+func (w *workbook) init() error {
+	var err error
+	if w.bold, err = w.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return err
+	}
+	// Format 10 ist das in Excel eingebaute 0.00%.
+	if w.percent, err = w.NewStyle(&excelize.Style{NumFmt: 10}); err != nil {
+		return err
+	}
+	w.decimal, err = w.NewStyle(&excelize.Style{CustomNumFmt: ptr("0.0000")})
+	return err
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// cell liefert den Namen der Zelle in Spalte col und Zeile row, beide ab 1.
+func cell(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
+
+// header schreibt die fette Kopfzeile values in Zeile row ab Spalte col.
+func (w *workbook) header(sheet string, col, row int, values ...string) error {
+	if err := w.SetSheetRow(sheet, cell(col, row), &values); err != nil {
+		return err
+	}
+	return w.SetCellStyle(sheet, cell(col, row), cell(col+len(values)-1, row), w.bold)
+}
+
+// style setzt das Format der Spalten col bis lastCol in den Zeilen from bis to.
+func (w *workbook) style(sheet string, col, lastCol, from, to, style int) error {
+	return w.SetCellStyle(sheet, cell(col, from), cell(lastCol, to), style)
+}
+
+// params schreibt die Hyperparameter als Liste aus Schlüssel und Wert.
+func (w *workbook) params(run *Run) error {
+	sheet := sheetParams
+	row := 1
+	if run.Title != "" {
+		if err := w.SetCellValue(sheet, "A1", run.Title); err != nil {
+			return err
+		}
+		if err := w.SetCellStyle(sheet, "A1", "A1", w.bold); err != nil {
+			return err
+		}
+		row = 3
+	}
+	if err := w.header(sheet, 1, row, "Parameter", "Wert"); err != nil {
+		return err
+	}
+	var params []param
+	if len(run.Hyperparameters) > 0 {
+		if err := flatten("", run.Hyperparameters, &params); err != nil {
+			return fmt.Errorf("Hyperparameter: %v", err)
+		}
+	}
+	for _, p := range params {
+		row++
+		if err := w.SetSheetRow(sheet, cell(1, row), &[]any{p.key, p.value}); err != nil {
+			return err
+		}
+	}
+	return w.SetColWidth(sheet, "A", "B", 28)
+}
+
+// param ist ein Hyperparameter mit dem Pfad key im JSON-Objekt.
+type param struct {
+	key   string
+	value any
+}
+
+// flatten hängt die Blätter des JSON-Werts data unter dem Pfad prefix in der
+// Reihenfolge von data an params an. Zahlen bleiben Zahlen, damit Excel damit
+// rechnen kann; Listen werden als JSON-Text übernommen.
+func flatten(prefix string, data json.RawMessage, params *[]param) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		switch v.(type) {
+		case []any, map[string]any:
+			v = string(data)
+		case nil:
+			v = ""
+		}
+		*params = append(*params, param{prefix, v})
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if err := flatten(key, v, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// history schreibt den Verlauf je Epoche mit je einem Liniendiagramm für den
+// Loss und die Genauigkeit. Die Spalten sind die Trainings- und
+// Validierungszahlen aus history; die Testdaten werden nur einmal am Ende
+// ausgewertet und erscheinen daher nicht je Epoche. Ohne Validierung entfallen
+// deren Spalten in den Diagrammen.
+func (w *workbook) history(history []mlp.EpochStats) error {
+	sheet := sheetHistory
+	if _, err := w.NewSheet(sheet); err != nil {
+		return err
+	}
+	if err := w.header(sheet, 1, 1, "Epoche", "Lernrate", "Loss", "Train-Genauigkeit", "Val-Loss", "Val-Genauigkeit"); err != nil {
+		return err
+	}
+	validation := false
+	for i, s := range history {
+		row := []any{s.Epoch, s.LR, s.Loss, s.TrainAcc, s.ValLoss, s.ValAcc}
+		if err := w.SetSheetRow(sheet, cell(1, i+2), &row); err != nil {
+			return err
+		}
+		validation = validation || s.ValLoss != 0
+	}
+	last := len(history) + 1
+	if err := w.style(sheet, 3, 6, 2, last, w.decimal); err != nil {
+		return err
+	}
+	for _, col := range []int{4, 6} {
+		if err := w.style(sheet, col, col, 2, last, w.percent); err != nil {
+			return err
+		}
+	}
+	if err := w.SetColWidth(sheet, "A", "F", 16); err != nil {
+		return err
+	}
+
+	series := func(cols ...int) []excelize.ChartSeries {
+		var s []excelize.ChartSeries
+		for _, col := range cols {
+			c, _ := excelize.ColumnNumberToName(col)
+			s = append(s, excelize.ChartSeries{
+				Name:       fmt.Sprintf("'%s'!$%s$1", sheet, c),
+				Categories: fmt.Sprintf("'%s'!$A$2:$A$%d", sheet, last),
+				Values:     fmt.Sprintf("'%s'!$%s$2:$%s$%d", sheet, c, c, last),
+				Marker:     excelize.ChartMarker{Symbol: "none"},
+			})
+		}
+		return s
+	}
+	lossCols, accCols := []int{3}, []int{4}
+	if validation {
+		lossCols, accCols = append(lossCols, 5), append(accCols, 6)
+	}
+	charts := []struct {
+		cell, title string
+		series      []excelize.ChartSeries
+		format      string
+	}{
+		{"H2", "Loss", series(lossCols...), "0.00"},
+		{"H20", "Genauigkeit", series(accCols...), "0%"},
+	}
+	for _, c := range charts {
+		err := w.AddChart(sheet, c.cell, &excelize.Chart{
+			Type:      excelize.Line,
+			Series:    c.series,
+			Title:     []excelize.RichTextRun{{Text: c.title}},
+			Legend:    excelize.ChartLegend{Position: "bottom"},
+			XAxis:     excelize.ChartAxis{Title: []excelize.RichTextRun{{Text: "Epoche"}}},
+			YAxis:     excelize.ChartAxis{MajorGridLines: true, NumFmt: excelize.ChartNumFmt{CustomNumFmt: c.format}},
+			Dimension: excelize.ChartDimension{Width: 640, Height: 320},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confusion schreibt die Konfusionsmatrix mit einer Farbskala: Einträge bis
+// zum 90. Perzentil, also die Verwechslungen, gehen von Weiß nach Gelb, die
+// großen Einträge der Diagonale von Gelb nach Grün.
+func (w *workbook) confusion(r *mlp.Report) error {
+	sheet := sheetConfusion
+	if _, err := w.NewSheet(sheet); err != nil {
+		return err
+	}
+	if err := w.header(sheet, 1, 1, append([]string{"wahr \\ vorhergesagt"}, r.Classes...)...); err != nil {
+		return err
+	}
+	k := len(r.Classes)
+	for i, row := range r.Confusion {
+		values := []any{r.Classes[i]}
+		for _, v := range row {
+			values = append(values, v)
+		}
+		if err := w.SetSheetRow(sheet, cell(1, i+2), &values); err != nil {
+			return err
+		}
+	}
+	if err := w.style(sheet, 1, 1, 2, k+1, w.bold); err != nil {
+		return err
+	}
+	if err := w.SetConditionalFormat(sheet, cell(2, 2)+":"+cell(k+1, k+1), []excelize.ConditionalFormatOptions{{
+		Type:     "3_color_scale",
+		Criteria: "=",
+		MinType:  "min",
+		MidType:  "percentile",
+		MidValue: "90",
+		MaxType:  "max",
+		MinColor: "#FFFFFF",
+		MidColor: "#FFEB84",
+		MaxColor: "#63BE7B",
+	}}); err != nil {
+		return err
+	}
+	if err := w.SetColWidth(sheet, "A", "A", 20); err != nil {
+		return err
+	}
+	return w.SetPanes(sheet, &excelize.Panes{Freeze: true, XSplit: 1, YSplit: 1, TopLeftCell: "B2", ActivePane: "bottomRight"})
+}
+
+// metrics schreibt Precision, Recall und F1 je Klasse mit ihren Mitteln und
+// darunter die Gesamtkennzahlen.
+func (w *workbook) metrics(r *mlp.Report) error {
+	sheet := sheetMetrics
+	if _, err := w.NewSheet(sheet); err != nil {
+		return err
+	}
+	if err := w.header(sheet, 1, 1, "Klasse", "Precision", "Recall", "F1", "Support"); err != nil {
+		return err
+	}
+	rows := make([][]any, 0, len(r.PerClass)+2)
+	for _, c := range r.PerClass {
+		rows = append(rows, []any{c.Class, c.Precision, c.Recall, c.F1, c.Support})
+	}
+	rows = append(rows,
+		[]any{"Makro", r.Macro.Precision, r.Macro.Recall, r.Macro.F1, r.Samples},
+		[]any{"Mikro", r.Micro.Precision, r.Micro.Recall, r.Micro.F1, r.Samples},
+	)
+	for i, row := range rows {
+		if err := w.SetSheetRow(sheet, cell(1, i+2), &row); err != nil {
+			return err
+		}
+	}
+	last := len(rows) + 1
+	if err := w.style(sheet, 2, 4, 2, last, w.decimal); err != nil {
+		return err
+	}
+	if err := w.style(sheet, 1, 1, last-1, last, w.bold); err != nil {
+		return err
+	}
+
+	row := last + 2
+	if err := w.header(sheet, 1, row, "Kennzahl", "Wert"); err != nil {
+		return err
+	}
+	type entry struct {
+		name  string
+		value any
+		style int
+	}
+	summary := []entry{
+		{"Beispiele", r.Samples, 0},
+		{"Genauigkeit", r.Accuracy, w.percent},
+	}
+	for k, acc := range r.TopK[1:] {
+		summary = append(summary, entry{"Top-" + strconv.Itoa(k+2), acc, w.percent})
+	}
+	summary = append(summary,
+		entry{"Log-Loss", r.LogLoss, w.decimal},
+		entry{fmt.Sprintf("ECE (%d Intervalle)", len(r.Calibration)), r.ECE, w.decimal},
+	)
+	for _, s := range summary {
+		row++
+		if err := w.SetSheetRow(sheet, cell(1, row), &[]any{s.name, s.value}); err != nil {
+			return err
+		}
+		if s.style != 0 {
+			if err := w.style(sheet, 2, 2, row, row, s.style); err != nil {
+				return err
+			}
+		}
+	}
+	return w.SetColWidth(sheet, "A", "E", 18)
+}
+
+// errors schreibt die falsch klassifizierten Beispiele mit Filter in der
+// Kopfzeile, z. B. um alle als 9 erkannten 4en zu finden.
+func (w *workbook) errors(r *mlp.Report) error {
+	sheet := sheetErrors
+	if _, err := w.NewSheet(sheet); err != nil {
+		return err
+	}
+	if err := w.header(sheet, 1, 1, "Index", "wahr", "vorhergesagt", "Konfidenz"); err != nil {
+		return err
+	}
+	for i, e := range r.Misclassified {
+		row := []any{e.Index, r.Classes[e.True], r.Classes[e.Predicted], e.Confidence}
+		if err := w.SetSheetRow(sheet, cell(1, i+2), &row); err != nil {
+			return err
+		}
+	}
+	last := len(r.Misclassified) + 1
+	// Ohne Fehler gäbe es keine Zeilen, ein Bereich D2:D1 würde die
+	// Kopfzeile formatieren.
+	if len(r.Misclassified) > 0 {
+		if err := w.style(sheet, 4, 4, 2, last, w.percent); err != nil {
+			return err
+		}
+	}
+	if err := w.AutoFilter(sheet, "A1:"+cell(4, last), nil); err != nil {
+		return err
+	}
+	if err := w.SetColWidth(sheet, "A", "D", 14); err != nil {
+		return err
+	}
+	return w.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
@@ -0,0 +1,131 @@
+package excel
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+func TestFlatten(t *testing.T) {
+	var params []param
+	data := `{"layers": [784, 10], "optimizer": {"name": "adam", "lr": 0.001}, "norm": null, "empty": {}, "resume": ""}`
+	if err := flatten("", json.RawMessage(data), &params); err != nil {
+		t.Fatal(err)
+	}
+	want := []param{
+		{"layers", "[784, 10]"},
+		{"optimizer.name", "adam"},
+		{"optimizer.lr", 0.001},
+		{"norm", ""},
+		{"resume", ""},
+	}
+	if !slices.Equal(params, want) {
+		t.Errorf("flatten = %v, erwartet %v", params, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m := mlp.NewMetrics(3, 2, 5)
+	m.Add(0, []float64{0.7, 0.2, 0.1})
+	m.Add(1, []float64{0.6, 0.3, 0.1})
+	m.Add(2, []float64{0.1, 0.1, 0.8})
+	report := m.Report([]string{"A", "B", "C"})
+	report.Misclassified = []mlp.Misclassified{{Index: 1, True: 1, Predicted: 0, Confidence: 0.6}}
+	run := &Run{
+		Title:           "Test",
+		Hyperparameters: json.RawMessage(`{"epochs": 2, "optimizer": {"lr": 0.1}}`),
+		History: []mlp.EpochStats{
+			{Epoch: 0, LR: 0.1, Loss: 1.2, TrainAcc: 0.5, ValLoss: 1.1, ValAcc: 0.6},
+			{Epoch: 1, LR: 0.1, Loss: 0.8, TrainAcc: 0.7, ValLoss: 0.9, ValAcc: 0.65},
+		},
+		Report: report,
+	}
+	filename := filepath.Join(t.TempDir(), "run.xlsx")
+	if err := Write(filename, run); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want := []string{sheetParams, sheetHistory, sheetConfusion, sheetMetrics, sheetErrors}
+	if got := f.GetSheetList(); !slices.Equal(got, want) {
+		t.Errorf("Blätter %v, erwartet %v", got, want)
+	}
+	for _, c := range []struct{ sheet, cell, want string }{
+		{sheetParams, "A5", "optimizer.lr"},
+		{sheetParams, "B5", "0.1"},
+		{sheetHistory, "C3", "0.8"},
+		{sheetConfusion, "B3", "1"},
+		{sheetConfusion, "A4", "C"},
+		{sheetMetrics, "A6", "Mikro"},
+		{sheetErrors, "C2", "A"},
+	} {
+		got, err := f.GetCellValue(c.sheet, c.cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("%s!%s = %q, erwartet %q", c.sheet, c.cell, got, c.want)
+		}
+	}
+	formats, err := f.GetConditionalFormats(sheetConfusion)
+	if err != nil || len(formats["B2:D4"]) != 1 {
+		t.Errorf("Heatmap der Konfusionsmatrix fehlt: %v, %v", formats, err)
+	}
+
+	// Die Diagramme liegen als eigene Teile in der Datei.
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	charts := 0
+	for _, zf := range z.File {
+		if matched, _ := filepath.Match("xl/charts/chart*.xml", zf.Name); matched {
+			charts++
+		}
+	}
+	if charts != 2 {
+		t.Errorf("%d Diagramme, erwartet 2", charts)
+	}
+}
+
+func TestWriteWithoutErrors(t *testing.T) {
+	m := mlp.NewMetrics(2, 1, 5)
+	m.Add(0, []float64{0.9, 0.1})
+	m.Add(1, []float64{0.2, 0.8})
+	filename := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := Write(filename, &Run{Title: "Test", Report: m.Report([]string{"A", "B"})}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// Ohne Fehler hat die Spalte Konfidenz nur die Kopfzeile, die wie die
+	// übrigen Überschriften formatiert bleibt.
+	for _, c := range []string{"A1", "D1"} {
+		id, err := f.GetCellStyle(sheetErrors, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		style, err := f.GetStyle(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if style.Font == nil || !style.Font.Bold || style.NumFmt != 0 {
+			t.Errorf("%s!%s: Schrift %+v, Zahlenformat %d, erwartet fette Überschrift", sheetErrors, c, style.Font, style.NumFmt)
+		}
+	}
+}
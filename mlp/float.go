@@ -0,0 +1,62 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// Float ist der Gleitkommatyp, in dem ein Modell rechnet und seine Parameter
+// speichert. float32 halbiert gegenüber float64 Speicherbedarf und
+// Speicherbandbreite für Gewichte und Trainingsdaten.
+type Float interface {
+	~float32 | ~float64
+}
+
+// Namen der Genauigkeiten, wie sie z. B. in Modelldateien gespeichert werden.
+const (
+	Float32 = "float32"
+	Float64 = "float64"
+)
+
+// Precisions sind die Namen aller unterstützten Genauigkeiten.
+var Precisions = []string{Float32, Float64}
+
+// Precision liefert den Namen der Genauigkeit von T.
+func Precision[T Float]() string {
+	var x T
+	if unsafe.Sizeof(x) == 4 {
+		return Float32
+	}
+	return Float64
+}
+
+// CheckPrecision prüft, ob name eine unterstützte Genauigkeit ist.
+func CheckPrecision(name string) error {
+	switch name {
+	case Float32, Float64:
+		return nil
+	}
+	return fmt.Errorf("unbekannte Genauigkeit %q, erlaubt sind %v", name, Precisions)
+}
+
+// Convert wandelt die Elemente von x in den Typ T um.
+func Convert[T, S Float](x []S) []T {
+	y := make([]T, len(x))
+	for i, v := range x {
+		y[i] = T(v)
+	}
+	return y
+}
+
+// ConvertRows wandelt die Elemente aller Zeilen von x in den Typ T um.
+func ConvertRows[T, S Float](x [][]S) [][]T {
+	y := make([][]T, len(x))
+	for i, row := range x {
+		y[i] = Convert[T](row)
+	}
+	return y
+}
+
+func exp[T Float](x T) T  { return T(math.Exp(float64(x))) }
+func sqrt[T Float](x T) T { return T(math.Sqrt(float64(x))) }
@@ -0,0 +1,124 @@
+package mlp
+
+// Blockgrößen der Matrixmultiplikation. Ein Block von gemmBlockRows Zeilen
+// wird gemeinsam verarbeitet, so dass jede geladene Zeile des anderen Operanden
+// mehrfach aus dem L1-Cache genutzt wird. gemmBlockCols Zeilen von B bilden
+// einen Block, der für alle Zeilenblöcke von A im L2-Cache bleibt.
+const (
+	gemmBlockRows = 4
+	gemmBlockCols = 64
+)
+
+// MulABt berechnet c = a * b^T für a (m x k), b (n x k) und c (m x n).
+// Das ist der Forward-Pass einer Dense-Schicht: Z = X * W^T.
+func MulABt[T Float](c, a, b *Matrix[T]) {
+	if a.Cols != b.Cols || c.Rows != a.Rows || c.Cols != b.Rows {
+		panic("mlp: MulABt: Dimensionen passen nicht")
+	}
+	k := a.Cols
+	for j0 := 0; j0 < b.Rows; j0 += gemmBlockCols {
+		j1 := min(j0+gemmBlockCols, b.Rows)
+		i := 0
+		for ; i+gemmBlockRows <= a.Rows; i += gemmBlockRows {
+			a0, a1, a2, a3 := a.Row(i), a.Row(i+1), a.Row(i+2), a.Row(i+3)
+			for j := j0; j < j1; j++ {
+				bj := b.Row(j)[:k]
+				var s0, s1, s2, s3 T
+				for p, bv := range bj {
+					s0 += a0[p] * bv
+					s1 += a1[p] * bv
+					s2 += a2[p] * bv
+					s3 += a3[p] * bv
+				}
+				c.Data[i*c.Cols+j] = s0
+				c.Data[(i+1)*c.Cols+j] = s1
+				c.Data[(i+2)*c.Cols+j] = s2
+				c.Data[(i+3)*c.Cols+j] = s3
+			}
+		}
+		for ; i < a.Rows; i++ {
+			ai := a.Row(i)
+			for j := j0; j < j1; j++ {
+				c.Data[i*c.Cols+j] = dot(ai, b.Row(j))
+			}
+		}
+	}
+}
+
+// MulAB berechnet c = a * b für a (m x k), b (k x n) und c (m x n).
+// Das ist die Rückführung des Gradienten auf die Eingabe: dX = dZ * W.
+func MulAB[T Float](c, a, b *Matrix[T]) {
+	if a.Cols != b.Rows || c.Rows != a.Rows || c.Cols != b.Cols {
+		panic("mlp: MulAB: Dimensionen passen nicht")
+	}
+	c.Zero()
+	i := 0
+	for ; i+gemmBlockRows <= a.Rows; i += gemmBlockRows {
+		c0, c1, c2, c3 := c.Row(i), c.Row(i+1), c.Row(i+2), c.Row(i+3)
+		for p := 0; p < a.Cols; p++ {
+			v0, v1, v2, v3 := a.At(i, p), a.At(i+1, p), a.At(i+2, p), a.At(i+3, p)
+			bp := b.Row(p)
+			c0, c1, c2, c3 := c0[:len(bp)], c1[:len(bp)], c2[:len(bp)], c3[:len(bp)]
+			for j, bv := range bp {
+				c0[j] += v0 * bv
+				c1[j] += v1 * bv
+				c2[j] += v2 * bv
+				c3[j] += v3 * bv
+			}
+		}
+	}
+	for ; i < a.Rows; i++ {
+		ci := c.Row(i)
+		for p := 0; p < a.Cols; p++ {
+			axpy(a.At(i, p), b.Row(p), ci)
+		}
+	}
+}
+
+// MulAtBAdd berechnet c += a^T * b für a (k x m), b (k x n) und c (m x n).
+// Das ist der Gradient der Gewichte einer Dense-Schicht, summiert über den
+// Mini-Batch: dW += dZ^T * X.
+func MulAtBAdd[T Float](c, a, b *Matrix[T]) {
+	if a.Rows != b.Rows || c.Rows != a.Cols || c.Cols != b.Cols {
+		panic("mlp: MulAtBAdd: Dimensionen passen nicht")
+	}
+	i := 0
+	for ; i+gemmBlockRows <= c.Rows; i += gemmBlockRows {
+		c0, c1, c2, c3 := c.Row(i), c.Row(i+1), c.Row(i+2), c.Row(i+3)
+		for p := 0; p < a.Rows; p++ {
+			v0, v1, v2, v3 := a.At(p, i), a.At(p, i+1), a.At(p, i+2), a.At(p, i+3)
+			bp := b.Row(p)
+			c0, c1, c2, c3 := c0[:len(bp)], c1[:len(bp)], c2[:len(bp)], c3[:len(bp)]
+			for j, bv := range bp {
+				c0[j] += v0 * bv
+				c1[j] += v1 * bv
+				c2[j] += v2 * bv
+				c3[j] += v3 * bv
+			}
+		}
+	}
+	for ; i < c.Rows; i++ {
+		ci := c.Row(i)
+		for p := 0; p < a.Rows; p++ {
+			axpy(a.At(p, i), b.Row(p), ci)
+		}
+	}
+}
+
+// dot liefert das Skalarprodukt von x und y.
+func dot[T Float](x, y []T) T {
+	y = y[:len(x)]
+	var s T
+	for i, v := range x {
+		s += v * y[i]
+	}
+	return s
+}
+
+// axpy berechnet y += alpha * x.
+func axpy[T Float](alpha T, x, y []T) {
+	y = y[:len(x)]
+	for i, v := range x {
+		y[i] += alpha * v
+	}
+}
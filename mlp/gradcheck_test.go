@@ -0,0 +1,365 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkGradients vergleicht die analytischen Gradienten aller Parameter in
+// params mit zentralen Differenzenquotienten von loss. loss muss den Loss mit
+// den aktuellen Werten der Parameter neu berechnen. Da nur Param verwendet
+// wird, lässt sich so jede Schicht prüfen, deren Parameter MLP.Params liefert,
+// und jede Aktivierung mit Param{Value: z, Grad: dz}.
+func checkGradients(t *testing.T, params []Param[float64], loss func() float64) {
+	t.Helper()
+	const h = 1e-6
+	for k, p := range params {
+		for i := range p.Value {
+			v := p.Value[i]
+			p.Value[i] = v + h
+			lp := loss()
+			p.Value[i] = v - h
+			lm := loss()
+			p.Value[i] = v
+			num := (lp - lm) / (2 * h)
+			if math.Abs(num-p.Grad[i]) > 1e-6*math.Max(1, math.Abs(num)) {
+				t.Fatalf("Parameter %d[%d]: Gradient %g, Differenzenquotient %g", k, i, p.Grad[i], num)
+			}
+		}
+	}
+}
+
+// tinyNet erzeugt ein kleines MLP mit Gewichten und Biases der
+// Standardabweichung 0.5, damit alle Aktivierungen in ihrem nichtlinearen
+// Bereich arbeiten.
+func tinyNet(rng *rand.Rand, sizes []int, acts ...Activation[float64]) *MLP[float64] {
+	m := NewMLP(rng, sizes, acts...)
+	for _, l := range m.Layers {
+		for i := range l.W.Data {
+			l.W.Data[i] = 0.5 * rng.NormFloat64()
+		}
+		for i := range l.B {
+			l.B[i] = 0.5 * rng.NormFloat64()
+		}
+	}
+	return m
+}
+
+func equalVector(got, want []float64, tol float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func TestActivationGradients(t *testing.T) {
+	for _, act := range []Activation[float64]{
+		ReLU[float64]{},
+		LeakyReLU[float64]{Alpha: 0.1},
+		ELU[float64]{Alpha: 1.5},
+		Sigmoid[float64]{},
+		Tanh[float64]{},
+		GELU[float64]{},
+		SiLU[float64]{},
+		Identity[float64]{},
+		Softmax[float64]{},
+	} {
+		t.Run(act.Name(), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			z, da := make([]float64, 7), make([]float64, 7)
+			for i := range z {
+				z[i] = 2 * rng.NormFloat64()
+				da[i] = rng.NormFloat64()
+			}
+			a, dz := make([]float64, len(z)), make([]float64, len(z))
+			act.Forward(z, a)
+			act.Backward(z, a, da, dz)
+
+			// Loss = da · Act(z), also dLoss/da = da
+			loss := func() float64 {
+				act.Forward(z, a)
+				return dot(da, a)
+			}
+			checkGradients(t, []Param[float64]{{Value: z, Grad: dz}}, loss)
+		})
+	}
+}
+
+func TestSoftmax(t *testing.T) {
+	e := math.E
+	for _, tc := range []struct {
+		name string
+		z    []float64
+		want []float64
+	}{
+		{"gleich", []float64{0, 0, 0, 0}, []float64{0.25, 0.25, 0.25, 0.25}},
+		{"einzeln", []float64{-3}, []float64{1}},
+		{"aufsteigend", []float64{0, 1, 2}, []float64{1 / (1 + e + e*e), e / (1 + e + e*e), e * e / (1 + e + e*e)}},
+		{"verschoben", []float64{1000, 1001, 1002}, []float64{1 / (1 + e + e*e), e / (1 + e + e*e), e * e / (1 + e + e*e)}},
+		{"kein Überlauf", []float64{1000, -1000}, []float64{1, 0}},
+		{"negativ", []float64{-1, -1, math.Log(2) - 1}, []float64{0.25, 0.25, 0.5}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := make([]float64, len(tc.z))
+			softmax(tc.z, a)
+			if !equalVector(a, tc.want, 1e-12) {
+				t.Errorf("softmax(%v) = %v, erwartet %v", tc.z, a, tc.want)
+			}
+		})
+	}
+}
+
+func TestCrossEntropyLoss(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		yTrue, yPre []float64
+		want        float64
+	}{
+		{"richtig", []float64{0, 1, 0}, []float64{0, 1, 0}, 0},
+		{"halb", []float64{1, 0}, []float64{0.5, 0.5}, math.Ln2},
+		{"falsch", []float64{1, 0, 0}, []float64{0.1, 0.2, 0.7}, -math.Log(0.1)},
+		{"null begrenzt", []float64{0, 1}, []float64{1, 0}, -math.Log(1e-12)},
+		{"geglättet", []float64{0.9, 0.1}, []float64{0.8, 0.2}, -0.9*math.Log(0.8) - 0.1*math.Log(0.2)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := crossEntropyLoss(tc.yTrue, tc.yPre); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("crossEntropyLoss(%v, %v) = %g, erwartet %g", tc.yTrue, tc.yPre, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("Gradient", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(2))
+		z, yPred := make([]float64, 5), make([]float64, 5)
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		softmax(z, yPred)
+		yTrue := []float64{0.05, 0.05, 0.8, 0.05, 0.05}
+		loss := func() float64 { return crossEntropyLoss(yTrue, yPred) }
+		checkGradients(t, []Param[float64]{{Value: yPred, Grad: crossEntropyGrad(yTrue, yPred)}}, loss)
+	})
+}
+
+func TestForward(t *testing.T) {
+	sig := func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+	// Softmax von (0.5, 3.5)
+	p := 1 / (1 + math.Exp(3))
+	for _, tc := range []struct {
+		name  string
+		acts  []Activation[float64]
+		x     []float64
+		wantZ [][]float64
+		wantA [][]float64
+	}{
+		{
+			name:  "relu/identity",
+			acts:  []Activation[float64]{ReLU[float64]{}, Identity[float64]{}},
+			x:     []float64{1, 2},
+			wantZ: [][]float64{{-1, 3.5}, {0.5, 3.5}},
+			wantA: [][]float64{{1, 2}, {0, 3.5}, {0.5, 3.5}},
+		},
+		{
+			name:  "relu/softmax",
+			acts:  []Activation[float64]{ReLU[float64]{}, Softmax[float64]{}},
+			x:     []float64{1, 2},
+			wantZ: [][]float64{{-1, 3.5}, {0.5, 3.5}},
+			wantA: [][]float64{{1, 2}, {0, 3.5}, {p, 1 - p}},
+		},
+		{
+			name:  "sigmoid/identity",
+			acts:  []Activation[float64]{Sigmoid[float64]{}, Identity[float64]{}},
+			x:     []float64{0, 0},
+			wantZ: [][]float64{{0, -1}, {0.5 + 0.5, -0.5 + sig(-1)}},
+			wantA: [][]float64{{0, 0}, {0.5, sig(-1)}, {1, -0.5 + sig(-1)}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMLP(rand.New(rand.NewSource(1)), []int{2, 2, 2}, tc.acts...)
+			copy(m.Layers[0].W.Data, []float64{1, -1, 0.5, 2})
+			copy(m.Layers[0].B, []float64{0, -1})
+			copy(m.Layers[1].W.Data, []float64{1, 0, -1, 1})
+			copy(m.Layers[1].B, []float64{0.5, 0})
+
+			zs, as := m.Forward(tc.x)
+			if len(zs) != len(tc.wantZ) || len(as) != len(tc.wantA) {
+				t.Fatalf("%d z- und %d a-Vektoren, erwartet %d und %d", len(zs), len(as), len(tc.wantZ), len(tc.wantA))
+			}
+			for i := range zs {
+				if !equalVector(zs[i], tc.wantZ[i], 1e-12) {
+					t.Errorf("zs[%d] = %v, erwartet %v", i, zs[i], tc.wantZ[i])
+				}
+			}
+			for i := range as {
+				if !equalVector(as[i], tc.wantA[i], 1e-12) {
+					t.Errorf("as[%d] = %v, erwartet %v", i, as[i], tc.wantA[i])
+				}
+			}
+			if got, want := m.Predict(tc.x), argmax(tc.wantA[2]); got != want {
+				t.Errorf("Predict = %d, erwartet %d", got, want)
+			}
+		})
+	}
+}
+
+// TestBackward prüft die Gradienten von Backward für einzelne Beispiele mit
+// Differenzenquotienten des Loss auf kleinen zufälligen Netzen.
+func TestBackward(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		net  func(t *testing.T, rng *rand.Rand) *MLP[float64]
+	}{
+		{"relu/softmax", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			return tinyNet(rng, []int{4, 5, 3})
+		}},
+		{"tanh/sigmoid", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			return tinyNet(rng, []int{4, 5, 3}, Tanh[float64]{}, Sigmoid[float64]{})
+		}},
+		{"gelu/silu/softmax", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			return tinyNet(rng, []int{4, 6, 5, 3}, GELU[float64]{}, SiLU[float64]{}, Softmax[float64]{})
+		}},
+		{"elu/leaky_relu/softmax", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			return tinyNet(rng, []int{4, 6, 5, 3}, ELU[float64]{Alpha: 1}, LeakyReLU[float64]{Alpha: 0.1}, Softmax[float64]{})
+		}},
+		{"layernorm", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			m := tinyNet(rng, []int{4, 6, 5, 3})
+			perturbNorms(t, m, LayerNorm, rng)
+			return m
+		}},
+		{"batchnorm", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			m := tinyNet(rng, []int{4, 6, 5, 3}, Tanh[float64]{}, ReLU[float64]{}, Softmax[float64]{})
+			perturbNorms(t, m, BatchNorm, rng)
+			return m
+		}},
+		{"conv", func(t *testing.T, rng *rand.Rand) *MLP[float64] {
+			return testConvNet(t, rng)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(7))
+			m := tc.net(t, rng)
+			X, Y := syntheticData(3, m.InputDim(), 3, 8)
+
+			grads := m.NewGradients()
+			for i := range X {
+				zs, as := m.Forward(X[i])
+				grads.Add(m.Backward(zs, as, Y[i]))
+			}
+			loss := func() float64 {
+				var sum float64
+				for i := range X {
+					_, as := m.Forward(X[i])
+					sum += crossEntropyLoss(Y[i], as[len(as)-1])
+				}
+				return sum
+			}
+			checkGradients(t, m.Params(grads), loss)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	// Modell mit W = (1, -2), b = 0.5 und Gradienten dW = (0.5, -1), db = 2,
+	// Lernrate 0.1
+	for _, tc := range []struct {
+		name  string
+		opt   Optimizer[float64]
+		decay WeightDecay
+		steps int
+		wantW []float64
+		wantB float64
+	}{
+		{"ohne Optimizer", nil, WeightDecay{}, 1, []float64{0.95, -1.9}, 0.3},
+		{"sgd", &SGD[float64]{}, WeightDecay{}, 2, []float64{0.9, -1.8}, 0.1},
+		{"L2", nil, WeightDecay{L2: 0.1}, 1, []float64{0.94, -1.88}, 0.3},
+		{"L1", nil, WeightDecay{L1: 0.1}, 1, []float64{0.94, -1.89}, 0.3},
+		// v = 0.9*v + dW, W -= 0.1*v: insgesamt W -= 0.29 * dW
+		{"momentum", &SGD[float64]{Momentum: 0.9}, WeightDecay{}, 2, []float64{0.855, -1.71}, -0.08},
+		// W -= 0.1 * (dW + 0.9*v) mit v = dW
+		{"nesterov", &SGD[float64]{Momentum: 0.9, Nesterov: true}, WeightDecay{}, 1, []float64{0.905, -1.81}, 0.12},
+		// im ersten Schritt ist m/c1 = dW und v/c2 = dW², also W -= 0.1 * sign(dW)
+		{"adam", &Adam[float64]{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8}, WeightDecay{}, 1, []float64{0.9, -1.9}, 0.4},
+		// s = dW² nach dem ersten und 2dW² nach dem zweiten Schritt, also
+		// W -= 0.1 * sign(dW) * (1 + 1/√2)
+		{"adagrad", &AdaGrad[float64]{Epsilon: 1e-8}, WeightDecay{}, 2, []float64{1 - 0.1 - 0.1/math.Sqrt2, -2 + 0.1 + 0.1/math.Sqrt2}, 0.5 - 0.1 - 0.1/math.Sqrt2},
+		// s = 0.1 * dW², also W -= 0.1 * sign(dW) / √0.1
+		{"rmsprop", &RMSProp[float64]{Rho: 0.9, Epsilon: 1e-8}, WeightDecay{}, 1, []float64{1 - math.Sqrt(0.1), -2 + math.Sqrt(0.1)}, 0.5 - math.Sqrt(0.1)},
+		// W -= 0.1 * 0.1 * W vor dem Adam-Schritt, nicht für den Bias
+		{"adamw", &AdamW[float64]{Adam: Adam[float64]{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8}, WeightDecay: 0.1}, WeightDecay{}, 1, []float64{0.89, -1.88}, 0.4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMLP(rand.New(rand.NewSource(1)), []int{2, 1}, Identity[float64]{})
+			copy(m.Layers[0].W.Data, []float64{1, -2})
+			m.Layers[0].B[0] = 0.5
+			for range tc.steps {
+				// Update addiert die Gewichtsabnahme zu den Gradienten, daher
+				// für jeden Schritt neu
+				grads := m.NewGradients()
+				copy(grads.Layers[0].W.Data, []float64{0.5, -1})
+				grads.Layers[0].B[0] = 2
+				m.Update(grads, tc.opt, 0.1, tc.decay)
+			}
+			if w := m.Layers[0].W.Data; !equalVector(w, tc.wantW, 1e-7) {
+				t.Errorf("W = %v, erwartet %v", w, tc.wantW)
+			}
+			if b := m.Layers[0].B[0]; math.Abs(b-tc.wantB) > 1e-7 {
+				t.Errorf("B = %g, erwartet %g", b, tc.wantB)
+			}
+		})
+	}
+}
+
+func TestNewOptimizerChecksConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*OptimizerConfig)
+		ok     bool
+	}{
+		{"sgd", func(c *OptimizerConfig) { c.Momentum = -1 }, true},
+		{"momentum", func(c *OptimizerConfig) { c.Momentum = -0.1 }, false},
+		{"nesterov", func(c *OptimizerConfig) { c.Momentum = 1 }, false},
+		{"adagrad", func(c *OptimizerConfig) { c.Epsilon = 0 }, false},
+		{"rmsprop", func(c *OptimizerConfig) { c.Rho = 1.5 }, false},
+		{"rmsprop", func(c *OptimizerConfig) { c.Beta1 = 1 }, true},
+		{"adam", func(c *OptimizerConfig) { c.Beta1 = 1 }, false},
+		{"adam", func(c *OptimizerConfig) { c.Beta2 = 1.01 }, false},
+		{"adam", func(c *OptimizerConfig) { c.Epsilon = -1e-8 }, false},
+		{"adamw", func(c *OptimizerConfig) { c.WeightDecay = -0.01 }, false},
+		{"adamw", func(c *OptimizerConfig) {}, true},
+	} {
+		cfg := DefaultOptimizerConfig()
+		tc.modify(&cfg)
+		_, err := NewOptimizer[float64](tc.name, cfg)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s mit %+v: Fehler %v, erwartet ok = %v", tc.name, cfg, err, tc.ok)
+		}
+	}
+}
+
+func TestOptimizerNameWithoutMomentum(t *testing.T) {
+	cfg := DefaultOptimizerConfig()
+	cfg.Momentum = 0
+	for _, name := range []string{"sgd", "momentum", "nesterov"} {
+		opt, err := NewOptimizer[float64](name, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opt.Name() != name {
+			t.Errorf("Optimizer %s mit Momentum 0 heißt %q", name, opt.Name())
+		}
+		// der Zustand passt zum selben Optimizer, aber nicht zu einem anderen
+		restored, _ := NewOptimizer[float64](name, cfg)
+		if err := restored.SetState(opt.State()); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if other, _ := NewOptimizer[float64]("adam", cfg); other.SetState(opt.State()) == nil {
+			t.Errorf("%s: Zustand passt zu adam", name)
+		}
+	}
+}
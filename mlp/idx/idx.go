@@ -0,0 +1,307 @@
+// Package idx liest und schreibt Dateien im IDX-Format, in dem z. B. die
+// MNIST-Daten verteilt werden: ein Tensor beliebiger Dimension mit Elementen
+// eines festen Typs, big-endian gespeichert. Mit gzip komprimierte Dateien
+// werden beim Lesen automatisch erkannt.
+//
+// Die Magic Number am Anfang einer Datei besteht aus zwei Null-Bytes, dem Typ
+// der Elemente und der Anzahl der Dimensionen. Darauf folgen die Dimensionen
+// als uint32 und die Elemente zeilenweise (die letzte Dimension läuft am
+// schnellsten).
+package idx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Type ist der Typ der Elemente eines Tensors.
+type Type byte
+
+// Die Typen des IDX-Formats.
+const (
+	UByte  Type = 0x08 // vorzeichenloses Byte
+	Byte   Type = 0x09 // Byte mit Vorzeichen
+	Short  Type = 0x0B // int16
+	Int    Type = 0x0C // int32
+	Float  Type = 0x0D // float32
+	Double Type = 0x0E // float64
+)
+
+// Size liefert die Größe eines Elements in Bytes, 0 für unbekannte Typen.
+func (t Type) Size() int {
+	switch t {
+	case UByte, Byte:
+		return 1
+	case Short:
+		return 2
+	case Int, Float:
+		return 4
+	case Double:
+		return 8
+	}
+	return 0
+}
+
+func (t Type) String() string {
+	switch t {
+	case UByte:
+		return "ubyte"
+	case Byte:
+		return "byte"
+	case Short:
+		return "short"
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Double:
+		return "double"
+	}
+	return fmt.Sprintf("Typ 0x%02x", byte(t))
+}
+
+// Tensor ist der Inhalt einer IDX-Datei. Data enthält die Elemente wie in der
+// Datei (big-endian), für UByte also direkt die Werte.
+type Tensor struct {
+	Type Type
+	Dims []int
+	Data []byte
+}
+
+// MaxSize ist die größte Datenmenge eines Tensors in Bytes, die New und Read
+// akzeptieren. Sie begrenzt den Speicherbedarf bei beschädigten Headern; die
+// größten Dateien von EMNIST haben etwa 550 MB.
+const MaxSize = 1 << 32
+
+// New erzeugt einen mit 0 gefüllten Tensor mit dem Elementtyp typ und den
+// Dimensionen dims.
+func New(typ Type, dims ...int) (*Tensor, error) {
+	size, err := dataSize(typ, dims)
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{Type: typ, Dims: append([]int(nil), dims...), Data: make([]byte, size)}, nil
+}
+
+// dataSize prüft typ und dims und liefert die Größe der Daten in Bytes.
+func dataSize(typ Type, dims []int) (int, error) {
+	if typ.Size() == 0 {
+		return 0, fmt.Errorf("unbekannter IDX-Typ 0x%02x", byte(typ))
+	}
+	if len(dims) > 255 {
+		return 0, fmt.Errorf("%d Dimensionen, IDX erlaubt höchstens 255", len(dims))
+	}
+	size := int64(typ.Size())
+	for _, d := range dims {
+		if d < 0 || int64(d) > math.MaxUint32 {
+			return 0, fmt.Errorf("ungültige Dimension %d", d)
+		}
+		// Vergleich vor der Multiplikation, damit size nicht überläuft
+		if d > 0 && size > MaxSize/int64(d) {
+			return 0, fmt.Errorf("Tensor mit Dimensionen %v vom Typ %v ist größer als %d Bytes", dims, typ, int64(MaxSize))
+		}
+		size *= int64(d)
+	}
+	if size > math.MaxInt {
+		return 0, fmt.Errorf("Tensor mit Dimensionen %v vom Typ %v ist zu groß", dims, typ)
+	}
+	return int(size), nil
+}
+
+// Magic liefert die Magic Number des Tensors, z. B. 2051 (0x00000803) für
+// MNIST-Bilder.
+func (t *Tensor) Magic() int {
+	return int(t.Type)<<8 | len(t.Dims)
+}
+
+// Len liefert die Anzahl der Elemente.
+func (t *Tensor) Len() int {
+	return len(t.Data) / t.Type.Size()
+}
+
+// At liefert das Element i (in Speicherreihenfolge) als float64.
+func (t *Tensor) At(i int) float64 {
+	switch t.Type {
+	case UByte:
+		return float64(t.Data[i])
+	case Byte:
+		return float64(int8(t.Data[i]))
+	case Short:
+		return float64(int16(binary.BigEndian.Uint16(t.Data[2*i:])))
+	case Int:
+		return float64(int32(binary.BigEndian.Uint32(t.Data[4*i:])))
+	case Float:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(t.Data[4*i:])))
+	case Double:
+		return math.Float64frombits(binary.BigEndian.Uint64(t.Data[8*i:]))
+	}
+	panic("idx: unbekannter Typ " + t.Type.String())
+}
+
+// Set setzt das Element i auf v. Bei ganzzahligen Typen wird v abgeschnitten.
+func (t *Tensor) Set(i int, v float64) {
+	switch t.Type {
+	case UByte:
+		t.Data[i] = uint8(v)
+	case Byte:
+		t.Data[i] = uint8(int8(v))
+	case Short:
+		binary.BigEndian.PutUint16(t.Data[2*i:], uint16(int16(v)))
+	case Int:
+		binary.BigEndian.PutUint32(t.Data[4*i:], uint32(int32(v)))
+	case Float:
+		binary.BigEndian.PutUint32(t.Data[4*i:], math.Float32bits(float32(v)))
+	case Double:
+		binary.BigEndian.PutUint64(t.Data[8*i:], math.Float64bits(v))
+	default:
+		panic("idx: unbekannter Typ " + t.Type.String())
+	}
+}
+
+// Float64s liefert alle Elemente als float64.
+func (t *Tensor) Float64s() []float64 {
+	v := make([]float64, t.Len())
+	for i := range v {
+		v[i] = t.At(i)
+	}
+	return v
+}
+
+// Read liest einen Tensor aus r. Mit gzip komprimierte Daten werden am
+// gzip-Header erkannt und entpackt.
+func Read(r io.Reader) (*Tensor, error) {
+	br := bufio.NewReader(r)
+	if head, err := br.Peek(2); err == nil && head[0] == 0x1f && head[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Entpacken: %v", err)
+		}
+		defer zr.Close()
+		return read(zr)
+	}
+	return read(br)
+}
+
+// read liest einen unkomprimierten Tensor aus r.
+func read(r io.Reader) (*Tensor, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, fmt.Errorf("Fehler beim Lesen des IDX-Headers: %v", err)
+	}
+	if magic[0] != 0 || magic[1] != 0 {
+		return nil, fmt.Errorf("ungültige Magic Number 0x%x, keine IDX-Datei", magic)
+	}
+	typ, ndims := Type(magic[2]), int(magic[3])
+	if typ.Size() == 0 {
+		return nil, fmt.Errorf("unbekannter IDX-Typ 0x%02x", magic[2])
+	}
+
+	header := make([]byte, 4*ndims)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("Fehler beim Lesen des IDX-Headers: %v", err)
+	}
+	dims := make([]int, ndims)
+	for i := range dims {
+		dims[i] = int(binary.BigEndian.Uint32(header[4*i:]))
+	}
+	size, err := dataSize(typ, dims)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tensor{Type: typ, Dims: dims}
+	if t.Data, err = readData(r, size); err != nil {
+		return nil, fmt.Errorf("Fehler beim Lesen der Daten (%d Bytes für Dimensionen %v erwartet): %v", size, dims, err)
+	}
+	if n, _ := io.CopyN(io.Discard, r, 1); n > 0 {
+		return nil, fmt.Errorf("zusätzliche Daten nach %d Bytes für Dimensionen %v", len(t.Data), dims)
+	}
+	return t, nil
+}
+
+// readData liest size Bytes aus r. Der Puffer wächst erst mit den gelesenen
+// Daten, so dass eine zu kurze Datei mit riesigen Dimensionen im Header zu
+// einem Fehler statt zu einer großen Allokation führt.
+func readData(r io.Reader, size int) ([]byte, error) {
+	const chunk = 1 << 20
+	data := make([]byte, 0, min(size, chunk))
+	for len(data) < size {
+		if len(data) == cap(data) {
+			data = slices.Grow(data, min(size-len(data), len(data)))
+		}
+		n, err := io.ReadFull(r, data[len(data):min(cap(data), size)])
+		data = data[:len(data)+n]
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// ReadFile liest einen Tensor aus der Datei filename, unkomprimiert oder mit
+// gzip komprimiert.
+func ReadFile(filename string) (*Tensor, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return t, nil
+}
+
+// Write schreibt t unkomprimiert nach w.
+func Write(w io.Writer, t *Tensor) error {
+	size, err := dataSize(t.Type, t.Dims)
+	if err != nil {
+		return err
+	}
+	if size != len(t.Data) {
+		return fmt.Errorf("%d Bytes Daten passen nicht zu Dimensionen %v vom Typ %v", len(t.Data), t.Dims, t.Type)
+	}
+
+	header := make([]byte, 4+4*len(t.Dims))
+	header[2], header[3] = byte(t.Type), byte(len(t.Dims))
+	for i, d := range t.Dims {
+		binary.BigEndian.PutUint32(header[4+4*i:], uint32(d))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(t.Data)
+	return err
+}
+
+// WriteFile schreibt t in die Datei filename, bei der Endung .gz mit gzip
+// komprimiert.
+func WriteFile(filename string, t *Tensor) error {
+	var buf bytes.Buffer
+	if strings.HasSuffix(strings.ToLower(filename), ".gz") {
+		zw := gzip.NewWriter(&buf)
+		if err := Write(zw, t); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	} else if err := Write(&buf, t); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Fehler beim Schreiben von %s: %v", filename, err)
+	}
+	return nil
+}
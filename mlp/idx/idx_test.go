@@ -0,0 +1,128 @@
+package idx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	types := []struct {
+		typ    Type
+		values []float64
+	}{
+		{UByte, []float64{0, 1, 128, 200, 255}},
+		{Byte, []float64{-128, -3, 0, 5, 127}},
+		{Short, []float64{-32768, -300, 0, 1000, 32767}},
+		{Int, []float64{-1 << 31, -70000, 0, 70000, 1<<31 - 1}},
+		{Float, []float64{-1.5, 0, 0.25, 3, 65536}},
+		{Double, []float64{-1e300, -0.1, 0, 1.0 / 3, 1e-300}},
+	}
+	dir := t.TempDir()
+
+	for _, tc := range types {
+		for _, dims := range [][]int{{5}, {5, 1}, {1, 5, 1, 1}} {
+			want, err := New(tc.typ, dims...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range tc.values {
+				want.Set(i, v)
+			}
+			for _, name := range []string{"t.idx", "t.idx.gz"} {
+				filename := filepath.Join(dir, name)
+				if err := WriteFile(filename, want); err != nil {
+					t.Fatal(err)
+				}
+				got, err := ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Type != tc.typ || len(got.Dims) != len(dims) || got.Magic() != int(tc.typ)<<8|len(dims) {
+					t.Fatalf("%v %v %s: Typ %v, Dimensionen %v", tc.typ, dims, name, got.Type, got.Dims)
+				}
+				for i, v := range tc.values {
+					if got.At(i) != v {
+						t.Errorf("%v %v %s: Element %d ist %g, erwartet %g", tc.typ, dims, name, i, got.At(i), v)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMNISTHeader(t *testing.T) {
+	// Header einer MNIST-Bilddatei mit 2 Bildern der Größe 2x3
+	data := []byte{0, 0, 8, 3, 0, 0, 0, 2, 0, 0, 0, 2, 0, 0, 0, 3}
+	data = append(data, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+	got, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Magic() != 2051 || got.Len() != 12 || got.Dims[1] != 2 || got.Dims[2] != 3 {
+		t.Errorf("Magic %d, Dimensionen %v", got.Magic(), got.Dims)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("geschrieben %v, erwartet %v", buf.Bytes(), data)
+	}
+
+	for name, bad := range map[string][]byte{
+		"zu kurz":          data[:len(data)-1],
+		"zu lang":          append(append([]byte{}, data...), 0),
+		"unbekannter Typ":  append([]byte{0, 0, 7, 3}, data[4:]...),
+		"keine IDX-Datei":  append([]byte{1, 0, 8, 3}, data[4:]...),
+		"fehlender Header": data[:10],
+	} {
+		if _, err := Read(bytes.NewReader(bad)); err == nil {
+			t.Errorf("%s: kein Fehler", name)
+		}
+	}
+}
+
+// header liefert den Header einer IDX-Datei mit Elementen vom Typ UByte und
+// den Dimensionen dims.
+func header(dims ...uint32) []byte {
+	h := []byte{0, 0, byte(UByte), byte(len(dims))}
+	for _, d := range dims {
+		h = binary.BigEndian.AppendUint32(h, d)
+	}
+	return h
+}
+
+func TestCorruptHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"65536³", header(65536, 65536, 65536), "größer als"},
+		{"Überlauf", header(0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF), "größer als"},
+		// 2 GiB laut Header, aber nur wenige Bytes Daten
+		{"zu kurz", append(header(65536, 32768), 1, 2, 3), "Fehler beim Lesen der Daten"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := Read(bytes.NewReader(tc.data))
+			runtime.ReadMemStats(&after)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Fehler %v, erwartet %q", err, tc.err)
+			}
+			if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+				t.Errorf("%d Bytes alloziert", n)
+			}
+		})
+	}
+
+	if _, err := New(UByte, 65536, 65536, 65536); err == nil {
+		t.Error("New mit 2^48 Bytes: kein Fehler")
+	}
+}
@@ -0,0 +1,71 @@
+package mlp
+
+import "fmt"
+
+// Matrix ist eine dicht und zeilenweise (row-major) gespeicherte Matrix.
+// Element (i, j) liegt in Data[i*Cols+j].
+type Matrix[T Float] struct {
+	Rows, Cols int
+	Data       []T
+}
+
+// NewMatrix erzeugt eine mit 0 gefüllte rows x cols Matrix.
+func NewMatrix[T Float](rows, cols int) *Matrix[T] {
+	return &Matrix[T]{Rows: rows, Cols: cols, Data: make([]T, rows*cols)}
+}
+
+// MatrixFromRows erzeugt eine Matrix aus gleich langen Zeilen.
+func MatrixFromRows[T Float](rows [][]T) (*Matrix[T], error) {
+	if len(rows) == 0 {
+		return NewMatrix[T](0, 0), nil
+	}
+	m := NewMatrix[T](len(rows), len(rows[0]))
+	for i, row := range rows {
+		if len(row) != m.Cols {
+			return nil, fmt.Errorf("Zeile %d hat Länge %d, erwartet %d", i, len(row), m.Cols)
+		}
+		copy(m.Row(i), row)
+	}
+	return m, nil
+}
+
+// At liefert das Element (i, j).
+func (m *Matrix[T]) At(i, j int) T {
+	return m.Data[i*m.Cols+j]
+}
+
+// Set setzt das Element (i, j) auf v.
+func (m *Matrix[T]) Set(i, j int, v T) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// Row liefert Zeile i als Slice, das sich den Speicher mit der Matrix teilt.
+func (m *Matrix[T]) Row(i int) []T {
+	return m.Data[i*m.Cols : (i+1)*m.Cols]
+}
+
+// ToRows liefert eine Kopie der Matrix als Slice von Zeilen.
+func (m *Matrix[T]) ToRows() [][]T {
+	rows := make([][]T, m.Rows)
+	for i := range rows {
+		rows[i] = append([]T(nil), m.Row(i)...)
+	}
+	return rows
+}
+
+// SetRows ändert die Anzahl der Zeilen auf rows und verwendet dabei den
+// vorhandenen Speicher wieder, solange er ausreicht. Der Inhalt ist danach
+// undefiniert.
+func (m *Matrix[T]) SetRows(rows int) {
+	n := rows * m.Cols
+	if cap(m.Data) < n {
+		m.Data = make([]T, n)
+	}
+	m.Data = m.Data[:n]
+	m.Rows = rows
+}
+
+// Zero setzt alle Elemente auf 0.
+func (m *Matrix[T]) Zero() {
+	clear(m.Data)
+}
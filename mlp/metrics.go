@@ -0,0 +1,294 @@
+package mlp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Metrics sammelt die Vorhersagen eines Klassifikators für einen ausführlichen
+// Bericht: Konfusionsmatrix, Top-k-Genauigkeit, Log-Loss und Kalibrierung.
+// Beispiele werden mit Add hinzugefügt, Report berechnet daraus die Kennzahlen.
+type Metrics struct {
+	// confusion[i][j] ist die Anzahl der Beispiele der Klasse i, die als j
+	// vorhergesagt wurden.
+	confusion [][]int
+	// topK[k] ist die Anzahl der Beispiele, deren Klasse unter den k+1
+	// wahrscheinlichsten ist.
+	topK []int
+	// bins teilt die Konfidenz (die größte Wahrscheinlichkeit) in gleich
+	// breite Intervalle von 0 bis 1.
+	bins    []calibrationStats
+	logLoss float64
+	n       int
+	// misclassified erfasst nur Trainer.Metrics, da Add den Index des
+	// Beispiels nicht kennt.
+	misclassified []Misclassified
+}
+
+type calibrationStats struct {
+	count      int
+	confidence float64
+	correct    int
+}
+
+// NewMetrics erzeugt leere Metrics für classes Klassen, die die
+// Top-k-Genauigkeit für k = 1 bis topK und die Kalibrierung in bins
+// Intervallen erfassen.
+func NewMetrics(classes, topK, bins int) *Metrics {
+	m := &Metrics{
+		confusion: make([][]int, classes),
+		topK:      make([]int, max(1, min(topK, classes))),
+		bins:      make([]calibrationStats, max(1, bins)),
+	}
+	for i := range m.confusion {
+		m.confusion[i] = make([]int, classes)
+	}
+	return m
+}
+
+// Add fügt ein Beispiel der Klasse class mit den vorhergesagten
+// Wahrscheinlichkeiten probs hinzu.
+func (m *Metrics) Add(class int, probs []float64) {
+	pred := argmax(probs)
+	m.confusion[class][pred]++
+	m.n++
+
+	// Rang der wahren Klasse; bei Gleichstand zählt wie bei argmax die
+	// kleinere Klasse als wahrscheinlicher.
+	rank := 0
+	for j, p := range probs {
+		if p > probs[class] || (p == probs[class] && j < class) {
+			rank++
+		}
+	}
+	for k := rank; k < len(m.topK); k++ {
+		m.topK[k]++
+	}
+
+	m.logLoss -= math.Log(probs[class] + 1e-12)
+
+	conf := probs[pred]
+	b := &m.bins[min(int(conf*float64(len(m.bins))), len(m.bins)-1)]
+	b.count++
+	b.confidence += conf
+	if pred == class {
+		b.correct++
+	}
+}
+
+// Merge addiert die Beispiele von o zu m. Beide müssen mit denselben
+// Parametern erzeugt worden sein.
+func (m *Metrics) Merge(o *Metrics) {
+	for i, row := range o.confusion {
+		for j, v := range row {
+			m.confusion[i][j] += v
+		}
+	}
+	for k, v := range o.topK {
+		m.topK[k] += v
+	}
+	for i, b := range o.bins {
+		m.bins[i].count += b.count
+		m.bins[i].confidence += b.confidence
+		m.bins[i].correct += b.correct
+	}
+	m.logLoss += o.logLoss
+	m.n += o.n
+	m.misclassified = append(m.misclassified, o.misclassified...)
+}
+
+// Report enthält die Kennzahlen einer Auswertung. Anteile liegen zwischen 0
+// und 1.
+type Report struct {
+	Samples int `json:"samples"`
+	// Classes sind die Namen der Klassen.
+	Classes []string `json:"classes"`
+	// Confusion[i][j] ist die Anzahl der Beispiele der Klasse i, die als j
+	// vorhergesagt wurden.
+	Confusion [][]int      `json:"confusion"`
+	PerClass  []ClassScore `json:"per_class"`
+	// Macro ist das ungewichtete Mittel über die Klassen, Micro berechnet die
+	// Kennzahlen aus den über alle Klassen summierten Zählern. Da jedes
+	// Beispiel genau eine Klasse hat, sind beim Mikro-Mittel Precision,
+	// Recall und F1 gleich der Genauigkeit.
+	Macro    Scores  `json:"macro"`
+	Micro    Scores  `json:"micro"`
+	Accuracy float64 `json:"accuracy"`
+	// TopK[k-1] ist der Anteil der Beispiele, deren Klasse unter den k
+	// wahrscheinlichsten ist.
+	TopK []float64 `json:"top_k"`
+	// LogLoss ist der mittlere Cross-Entropy-Loss.
+	LogLoss float64 `json:"log_loss"`
+	// ECE ist der erwartete Kalibrierungsfehler: der mit der Anzahl der
+	// Beispiele gewichtete mittlere Abstand von Konfidenz und Genauigkeit in
+	// den Intervallen von Calibration.
+	ECE         float64          `json:"ece"`
+	Calibration []CalibrationBin `json:"calibration"`
+	// Misclassified sind die falsch klassifizierten Beispiele aufsteigend nach
+	// Index, nur bei Auswertung mit Trainer.Metrics.
+	Misclassified []Misclassified `json:"misclassified,omitempty"`
+}
+
+// Scores sind Precision, Recall und F1 einer Klasse oder eines Mittels.
+type Scores struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// ClassScore sind die Kennzahlen einer Klasse. Support ist die Anzahl ihrer
+// Beispiele.
+type ClassScore struct {
+	Class string `json:"class"`
+	Scores
+	Support int `json:"support"`
+}
+
+// CalibrationBin beschreibt die Beispiele, deren Konfidenz im Intervall
+// [Lower, Upper) liegt, mit ihrer mittleren Konfidenz und Genauigkeit.
+type CalibrationBin struct {
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Count      int     `json:"count"`
+	Confidence float64 `json:"confidence"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// Misclassified ist ein falsch klassifiziertes Beispiel mit seinem Index im
+// Dataset, der wahren und der vorhergesagten Klasse und der Wahrscheinlichkeit
+// der Vorhersage.
+type Misclassified struct {
+	Index      int     `json:"index"`
+	True       int     `json:"true"`
+	Predicted  int     `json:"predicted"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Confusion ist ein Paar aus wahrer und vorhergesagter Klasse mit der Anzahl
+// der so verwechselten Beispiele.
+type Confusion struct {
+	True      string `json:"true"`
+	Predicted string `json:"predicted"`
+	Count     int    `json:"count"`
+}
+
+// Report berechnet die Kennzahlen. classes sind die Namen der Klassen; fehlen
+// sie, werden die Klassen nummeriert. Precision, Recall und F1 sind 0, wenn
+// sie nicht definiert sind, z. B. die Precision einer nie vorhergesagten
+// Klasse.
+func (m *Metrics) Report(classes []string) *Report {
+	k := len(m.confusion)
+	r := &Report{
+		Samples:   m.n,
+		Classes:   make([]string, k),
+		Confusion: make([][]int, k),
+		PerClass:  make([]ClassScore, k),
+		TopK:      make([]float64, len(m.topK)),
+	}
+	r.Misclassified = append(r.Misclassified, m.misclassified...)
+	var tp, fp, fn int
+	for i := 0; i < k; i++ {
+		r.Classes[i] = strconv.Itoa(i)
+		if i < len(classes) {
+			r.Classes[i] = classes[i]
+		}
+		r.Confusion[i] = append([]int(nil), m.confusion[i]...)
+
+		support, predicted := 0, 0
+		for j := 0; j < k; j++ {
+			support += m.confusion[i][j]
+			predicted += m.confusion[j][i]
+		}
+		c := m.confusion[i][i]
+		s := newScores(c, predicted-c, support-c)
+		r.PerClass[i] = ClassScore{Class: r.Classes[i], Scores: s, Support: support}
+		r.Macro.Precision += s.Precision / float64(k)
+		r.Macro.Recall += s.Recall / float64(k)
+		r.Macro.F1 += s.F1 / float64(k)
+		tp, fp, fn = tp+c, fp+predicted-c, fn+support-c
+	}
+	r.Micro = newScores(tp, fp, fn)
+	if m.n == 0 {
+		return r
+	}
+	r.Accuracy = float64(tp) / float64(m.n)
+	for i, v := range m.topK {
+		r.TopK[i] = float64(v) / float64(m.n)
+	}
+	r.LogLoss = m.logLoss / float64(m.n)
+
+	width := 1 / float64(len(m.bins))
+	for i, b := range m.bins {
+		bin := CalibrationBin{Lower: float64(i) * width, Upper: float64(i+1) * width, Count: b.count}
+		if b.count > 0 {
+			bin.Confidence = b.confidence / float64(b.count)
+			bin.Accuracy = float64(b.correct) / float64(b.count)
+			r.ECE += float64(b.count) / float64(m.n) * math.Abs(bin.Accuracy-bin.Confidence)
+		}
+		r.Calibration = append(r.Calibration, bin)
+	}
+	return r
+}
+
+// newScores berechnet Precision, Recall und F1 aus den richtig positiven,
+// falsch positiven und falsch negativen Beispielen.
+func newScores(tp, fp, fn int) Scores {
+	var s Scores
+	if tp+fp > 0 {
+		s.Precision = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		s.Recall = float64(tp) / float64(tp+fn)
+	}
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+	return s
+}
+
+// TopConfusions liefert die n häufigsten Verwechslungen, also die größten
+// Einträge der Konfusionsmatrix außerhalb der Diagonale, absteigend sortiert.
+func (r *Report) TopConfusions(n int) []Confusion {
+	var c []Confusion
+	for i, row := range r.Confusion {
+		for j, v := range row {
+			if i != j && v > 0 {
+				c = append(c, Confusion{True: r.Classes[i], Predicted: r.Classes[j], Count: v})
+			}
+		}
+	}
+	sort.SliceStable(c, func(a, b int) bool { return c[a].Count > c[b].Count })
+	if len(c) > n {
+		c = c[:n]
+	}
+	return c
+}
+
+// Metrics wertet das Modell wie Evaluate auf d aus und fügt alle Beispiele zu
+// m hinzu, die falsch klassifizierten zusätzlich mit ihrem Index in d. Das
+// Ergebnis hängt nicht von t.Workers ab.
+func (t *Trainer[T]) Metrics(d Dataset[T], m *Metrics) {
+	classes := len(m.confusion)
+	parts := make([]*Metrics, t.chunks(d))
+	t.forChunks(d, func(c int, y, out *Matrix[T]) {
+		part := NewMetrics(classes, len(m.topK), len(m.bins))
+		probs := make([]float64, out.Cols)
+		for k := 0; k < out.Rows; k++ {
+			for j, v := range out.Row(k) {
+				probs[j] = float64(v)
+			}
+			class, pred := argmax(y.Row(k)), argmax(probs)
+			part.Add(class, probs)
+			if pred != class {
+				part.misclassified = append(part.misclassified, Misclassified{
+					Index: c*evalChunk + k, True: class, Predicted: pred, Confidence: probs[pred],
+				})
+			}
+		}
+		parts[c] = part
+	})
+	for _, part := range parts {
+		m.Merge(part)
+	}
+}
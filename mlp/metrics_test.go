@@ -0,0 +1,115 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMetricsReport(t *testing.T) {
+	m := NewMetrics(3, 2, 2)
+	for _, ex := range []struct {
+		class int
+		probs []float64
+	}{
+		{0, []float64{0.9, 0.05, 0.05}},
+		{0, []float64{0.6, 0.3, 0.1}},
+		{0, []float64{0.3, 0.6, 0.1}},
+		{1, []float64{0.1, 0.8, 0.1}},
+		{1, []float64{0.2, 0.2, 0.6}},
+		{2, []float64{0.4, 0.3, 0.3}},
+	} {
+		m.Add(ex.class, ex.probs)
+	}
+	r := m.Report([]string{"a", "b"})
+
+	want := [][]int{{2, 1, 0}, {0, 1, 1}, {1, 0, 0}}
+	for i := range want {
+		for j := range want[i] {
+			if r.Confusion[i][j] != want[i][j] {
+				t.Fatalf("Konfusionsmatrix %v, erwartet %v", r.Confusion, want)
+			}
+		}
+	}
+	if r.Classes[0] != "a" || r.Classes[2] != "2" {
+		t.Errorf("Klassen %q", r.Classes)
+	}
+
+	near := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %g, erwartet %g", name, got, want)
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		got  ClassScore
+		want Scores
+	}{
+		{"a", r.PerClass[0], Scores{Precision: 2.0 / 3, Recall: 2.0 / 3, F1: 2.0 / 3}},
+		{"b", r.PerClass[1], Scores{Precision: 0.5, Recall: 0.5, F1: 0.5}},
+		{"2", r.PerClass[2], Scores{}},
+	} {
+		near(tc.name+" Precision", tc.got.Precision, tc.want.Precision)
+		near(tc.name+" Recall", tc.got.Recall, tc.want.Recall)
+		near(tc.name+" F1", tc.got.F1, tc.want.F1)
+	}
+	if r.PerClass[0].Support != 3 || r.PerClass[2].Support != 1 {
+		t.Errorf("Support %d und %d, erwartet 3 und 1", r.PerClass[0].Support, r.PerClass[2].Support)
+	}
+	near("Makro-F1", r.Macro.F1, (2.0/3+0.5)/3)
+	near("Mikro-Precision", r.Micro.Precision, 0.5)
+	near("Mikro-F1", r.Micro.F1, 0.5)
+	near("Genauigkeit", r.Accuracy, 0.5)
+	near("Top-1", r.TopK[0], 0.5)
+	// bei Gleichstand zählt die kleinere Klasse als wahrscheinlicher
+	near("Top-2", r.TopK[1], 4.0/6)
+	near("Log-Loss", r.LogLoss, -(math.Log(0.9)+math.Log(0.6)+math.Log(0.3)+math.Log(0.8)+math.Log(0.2)+math.Log(0.3))/6)
+
+	// Konfidenz < 0.5: 0.4 (falsch); >= 0.5: 0.9, 0.6, 0.6, 0.8, 0.6 (3 richtig)
+	near("Intervall 1", r.Calibration[1].Confidence, 3.5/5)
+	near("ECE", r.ECE, 1.0/6*0.4+5.0/6*math.Abs(0.6-0.7))
+
+	top := r.TopConfusions(2)
+	if len(top) != 2 || top[0] != (Confusion{True: "a", Predicted: "b", Count: 1}) {
+		t.Errorf("häufigste Verwechslungen %v", top)
+	}
+}
+
+// TestTrainerMetrics prüft, dass Metrics zu Evaluate passt und nicht von der
+// Anzahl der Worker abhängt.
+func TestTrainerMetrics(t *testing.T) {
+	X, Y := syntheticData(600, 12, 4, 1)
+	d := Slices[float64]{X: X, Y: Y}
+	m := tinyNet(rand.New(rand.NewSource(2)), []int{12, 8, 4})
+
+	var reports []*Report
+	for _, workers := range []int{1, 3} {
+		tr := &Trainer[float64]{Model: m, Workers: workers}
+		metrics := NewMetrics(4, 3, 10)
+		tr.Metrics(d, metrics)
+		reports = append(reports, metrics.Report(nil))
+	}
+	r := reports[0]
+	loss, acc := (&Trainer[float64]{Model: m}).Evaluate(d)
+	if r.Samples != len(X) || math.Abs(r.Accuracy-acc) > 1e-12 || math.Abs(r.TopK[0]-acc) > 1e-12 {
+		t.Errorf("%d Beispiele, Genauigkeit %g, Top-1 %g, erwartet %d und %g", r.Samples, r.Accuracy, r.TopK[0], len(X), acc)
+	}
+	if math.Abs(r.LogLoss-loss) > 1e-9 {
+		t.Errorf("Log-Loss %g, Evaluate %g", r.LogLoss, loss)
+	}
+	if r.TopK[2] < r.TopK[1] || r.TopK[1] < r.TopK[0] {
+		t.Errorf("Top-k nicht monoton: %v", r.TopK)
+	}
+	if len(r.Misclassified) != r.Samples-int(math.Round(acc*float64(len(X)))) {
+		t.Errorf("%d falsch klassifizierte Beispiele bei Genauigkeit %g", len(r.Misclassified), acc)
+	}
+	for _, e := range r.Misclassified {
+		if e.True != argmax(Y[e.Index]) || e.Predicted != m.Predict(X[e.Index]) || e.True == e.Predicted {
+			t.Fatalf("Beispiel %d: %+v, Label %d, Vorhersage %d", e.Index, e, argmax(Y[e.Index]), m.Predict(X[e.Index]))
+		}
+	}
+	if o := reports[1]; o.LogLoss != r.LogLoss || o.ECE != r.ECE || len(o.Misclassified) != len(r.Misclassified) {
+		t.Errorf("mit 3 Workern Log-Loss %g und ECE %g, mit einem %g und %g", o.LogLoss, o.ECE, r.LogLoss, r.ECE)
+	}
+}
@@ -0,0 +1,483 @@
+// Package mlp implementiert ein einfaches Multi-Layer Perceptron (MLP) mit beliebig
+// vielen vollständig verbundenen Schichten. Jede Schicht hat ihre eigene
+// Aktivierungsfunktion; standardmäßig ReLU in den versteckten Schichten und
+// Softmax in der Ausgabeschicht. Optional steht davor ein Faltungsteil aus
+// Conv2D-, Pooling- und Flatten-Schichten (z. B. LeNet), siehe NewConvNet.
+//
+// Das Paket enthält nur die Modelllogik. Laden der MNIST-Daten und Speichern bzw.
+// Laden der Modellparameter befinden sich in den Unterpaketen mnist und modelio.
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+//--------------------------------------------------------
+// Softmax und Loss
+//--------------------------------------------------------
+
+// softmax für die Ausgabeschicht, schreibt das Ergebnis nach a
+func softmax[T Float](z, a []T) {
+	maxZ := T(math.Inf(-1))
+	for _, val := range z {
+		if val > maxZ {
+			maxZ = val
+		}
+	}
+
+	var sum T
+	for i, val := range z {
+		ev := exp(val - maxZ)
+		a[i] = ev
+		sum += ev
+	}
+
+	for i := range a {
+		a[i] /= sum
+	}
+}
+
+// crossEntropyLoss berechnet den Cross-Entropy-Loss.
+func crossEntropyLoss[T Float](yTrue, yPred []T) float64 {
+	// yTrue ist One-Hot, yPred Softmax.
+	var loss float64
+	for i := range yTrue {
+		// Vermeide log(0) durch Hinzufügen einer kleinen Konstante.
+		loss -= float64(yTrue[i]) * math.Log(float64(yPred[i])+1e-12)
+	}
+	return loss
+}
+
+// crossEntropyGrad berechnet dLoss/dyPred des Cross-Entropy-Loss.
+func crossEntropyGrad[T Float](yTrue, yPred []T) []T {
+	grad := make([]T, len(yTrue))
+	for i := range yTrue {
+		grad[i] = T(-float64(yTrue[i]) / (float64(yPred[i]) + 1e-12))
+	}
+	return grad
+}
+
+//--------------------------------------------------------
+// MLP-Struktur
+//--------------------------------------------------------
+
+// Dense ist eine vollständig verbundene Schicht a = Act(W*x + b) bzw. mit
+// Normalisierung a = Act(Norm(W*x + b)).
+type Dense[T Float] struct {
+	W   *Matrix[T] // Ausgabe x Eingabe
+	B   []T
+	Act Activation[T]
+	// Norm ist eine optionale BatchNorm oder LayerNorm vor der Aktivierung.
+	Norm *Norm[T]
+}
+
+// InputDim liefert die Anzahl der Eingabeneuronen der Schicht.
+func (l *Dense[T]) InputDim() int {
+	return l.W.Cols
+}
+
+// OutputDim liefert die Anzahl der Ausgabeneuronen der Schicht.
+func (l *Dense[T]) OutputDim() int {
+	return l.W.Rows
+}
+
+// MLP ist eine Folge von Dense-Schichten, z. B.
+// Input: 784, Hidden: 512, 256, Output: 10. T ist die Genauigkeit, in der das
+// Netz rechnet und seine Parameter speichert.
+type MLP[T Float] struct {
+	// Conv ist der optionale Faltungsteil vor den Dense-Schichten. Seine
+	// letzte Schicht ist Flatten, deren Ausgabe die Eingabe von Layers[0] ist.
+	Conv   []*ConvLayer[T]
+	Layers []*Dense[T]
+}
+
+// initStd ist die Standardabweichung der Startgewichte von NewMLP.
+const initStd = 0.01
+
+// initWeights initialisiert die Gewichte zufällig mit rng.
+func initWeights[T Float](rng *rand.Rand, rows, cols int) *Matrix[T] {
+	w := NewMatrix[T](rows, cols)
+	for i := range w.Data {
+		// Glorot-Initialisierung oder einfache Normalverteilung
+		w.Data[i] = T(rng.NormFloat64() * initStd)
+	}
+	return w
+}
+
+func initBiases[T Float](dim int) []T {
+	b := make([]T, dim)
+	return b
+}
+
+// NewMLP erzeugt ein mit rng zufällig initialisiertes MLP. sizes enthält die
+// Anzahl der Neuronen je Schicht, beginnend mit der Eingabe, z. B.
+// []int{784, 512, 256, 10}. acts legt die Aktivierung jeder Schicht fest
+// (len(sizes)-1 Einträge). Ohne acts verwenden die versteckten Schichten ReLU
+// und die Ausgabeschicht Softmax.
+func NewMLP[T Float](rng *rand.Rand, sizes []int, acts ...Activation[T]) *MLP[T] {
+	if len(sizes) < 2 {
+		panic("mlp: mindestens Eingabe- und Ausgabeschicht erforderlich")
+	}
+	if acts == nil {
+		acts = DefaultActivations[T](len(sizes) - 1)
+	}
+	if len(acts) != len(sizes)-1 {
+		panic(fmt.Sprintf("mlp: %d Aktivierungen für %d Schichten", len(acts), len(sizes)-1))
+	}
+	m := &MLP[T]{Layers: make([]*Dense[T], len(sizes)-1)}
+	for i := range m.Layers {
+		m.Layers[i] = &Dense[T]{
+			W:   initWeights[T](rng, sizes[i+1], sizes[i]),
+			B:   initBiases[T](sizes[i+1]),
+			Act: acts[i],
+		}
+	}
+	return m
+}
+
+// NewConvNet erzeugt ein mit rng zufällig initialisiertes Netz aus dem
+// Faltungsteil conv für Eingaben der Form in und Dense-Schichten wie bei
+// NewMLP. sizes beginnt wie bei NewMLP mit der Eingabe des Netzes, also
+// in.Size(); die erste Dense-Schicht erhält die Ausgabe von conv. Alle
+// Gewichte werden nach He et al. (2015) initialisiert.
+func NewConvNet[T Float](rng *rand.Rand, in Shape, conv []LayerSpec, sizes []int, acts ...Activation[T]) (*MLP[T], error) {
+	if len(sizes) < 2 || sizes[0] != in.Size() {
+		return nil, fmt.Errorf("Schichten %v passen nicht zur Eingabe %v mit %d Werten", sizes, in, in.Size())
+	}
+	if acts != nil && len(acts) != len(sizes)-1 {
+		return nil, fmt.Errorf("%d Aktivierungen für %d Schichten", len(acts), len(sizes)-1)
+	}
+	layers, err := NewConvLayers[T](rng, in, conv)
+	if err != nil {
+		return nil, err
+	}
+	dense := append([]int{layers[len(layers)-1].Out.Size()}, sizes[1:]...)
+	m := NewMLP(rng, dense, acts...)
+	m.Conv = layers
+	// Die kleinen Startgewichte von NewMLP lassen bei einem tieferen Netz
+	// kaum Gradienten durch, daher wie im Faltungsteil nach He skaliert.
+	for _, l := range m.Layers {
+		scale := T(math.Sqrt(2/float64(l.InputDim())) / initStd)
+		for i := range l.W.Data {
+			l.W.Data[i] *= scale
+		}
+	}
+	return m, nil
+}
+
+// DefaultActivations liefert ReLU für die versteckten Schichten und Softmax für
+// die Ausgabeschicht eines Netzes mit n Schichten.
+func DefaultActivations[T Float](n int) []Activation[T] {
+	acts := make([]Activation[T], n)
+	for i := range acts {
+		acts[i] = ReLU[T]{}
+	}
+	acts[n-1] = Softmax[T]{}
+	return acts
+}
+
+// Activations liefert die Aktivierungen aller Schichten.
+func (m *MLP[T]) Activations() []Activation[T] {
+	acts := make([]Activation[T], len(m.Layers))
+	for i, l := range m.Layers {
+		acts[i] = l.Act
+	}
+	return acts
+}
+
+// Norms liefert die Namen der Normalisierungen aller Schichten, "" für keine.
+func (m *MLP[T]) Norms() []string {
+	norms := make([]string, len(m.Layers))
+	for i, l := range m.Layers {
+		if l.Norm != nil {
+			norms[i] = l.Norm.Kind
+		}
+	}
+	return norms
+}
+
+// AddNorm fügt allen versteckten Schichten eine Normalisierung kind hinzu.
+func (m *MLP[T]) AddNorm(kind string) error {
+	for _, l := range m.Layers[:len(m.Layers)-1] {
+		n, err := NewNorm[T](kind, l.OutputDim())
+		if err != nil {
+			return err
+		}
+		l.Norm = n
+	}
+	return nil
+}
+
+// ConvSpecs liefert die Beschreibung des Faltungsteils, nil ohne.
+func (m *MLP[T]) ConvSpecs() []LayerSpec {
+	var specs []LayerSpec
+	for _, l := range m.Conv {
+		specs = append(specs, l.Spec())
+	}
+	return specs
+}
+
+// InputDim liefert die Anzahl der Eingabewerte des Netzes.
+func (m *MLP[T]) InputDim() int {
+	if len(m.Conv) > 0 {
+		return m.Conv[0].In.Size()
+	}
+	return m.Layers[0].InputDim()
+}
+
+// Sizes liefert die Architektur des Netzes als Anzahl der Neuronen je
+// Dense-Schicht, beginnend mit der Eingabe des Netzes.
+func (m *MLP[T]) Sizes() []int {
+	sizes := []int{m.InputDim()}
+	for _, l := range m.Layers {
+		sizes = append(sizes, l.OutputDim())
+	}
+	return sizes
+}
+
+// Forward berechnet den Forward-Pass für ein einzelnes Beispiel. zs[i] ist die
+// Eingabe der Aktivierung von Schicht i, as[i] die Eingabe von Schicht i; as[0]
+// ist also x und as[len(as)-1] die Ausgabe des Netzes. Die Schichten des
+// Faltungsteils zählen dabei vor den Dense-Schichten, zs ist für Pooling und
+// Flatten nil. BatchNorm verwendet wie bei der Inferenz die gleitenden
+// Statistiken.
+//
+// Für das Training ist ForwardBatch deutlich schneller.
+func (m *MLP[T]) Forward(x []T) (zs, as [][]T) {
+	c := len(m.Conv)
+	zs = make([][]T, c+len(m.Layers))
+	as = make([][]T, c+len(m.Layers)+1)
+	as[0] = x
+
+	for i, layer := range m.Conv {
+		if layer.Kind == Conv2D {
+			zs[i] = make([]T, layer.Out.Size())
+		}
+		as[i+1] = make([]T, layer.Out.Size())
+		layer.forward(as[i], zs[i], as[i+1], newConvWork(layer))
+	}
+
+	for l, layer := range m.Layers {
+		// z = W*a + b
+		in := as[c+l]
+		z := make([]T, layer.OutputDim())
+		for i := range z {
+			z[i] = dot(layer.W.Row(i), in) + layer.B[i]
+		}
+		if layer.Norm != nil {
+			z = layer.normSample(z, nil, nil)
+		}
+		zs[c+l] = z
+
+		// a = Act(z)
+		a := make([]T, len(z))
+		layer.Act.Forward(z, a)
+		as[c+l+1] = a
+	}
+	return
+}
+
+// normSample normalisiert z eines einzelnen Beispiels wie bei der Inferenz
+// und liefert das Ergebnis. Ist dOut nicht nil, wird zusätzlich dLoss/dz aus
+// dOut = dLoss/dNorm(z) berechnet, nach dOut geschrieben und die Gradienten
+// von Gamma und Beta zu g addiert.
+func (l *Dense[T]) normSample(z, dOut []T, g *Norm[T]) []T {
+	w := newNormWork[T](1, len(z))
+	copy(w.pre.Data, z)
+	out := NewMatrix[T](1, len(z))
+	l.Norm.forward([]*normWork[T]{w}, []*Matrix[T]{out}, false)
+	if dOut != nil {
+		d := &Matrix[T]{Rows: 1, Cols: len(dOut), Data: dOut}
+		l.Norm.backward([]*normWork[T]{w}, []*Matrix[T]{d}, false, []*Norm[T]{g})
+		copy(dOut, w.dPre.Data)
+	}
+	return out.Data
+}
+
+// Gradients enthält die Gradienten aller Schichten in der Reihenfolge von
+// MLP.Conv und MLP.Layers.
+type Gradients[T Float] struct {
+	Conv   []*ConvLayer[T]
+	Layers []*Dense[T]
+}
+
+// NewGradients erzeugt mit 0 initialisierte Gradienten passend zur Form des Modells.
+func (m *MLP[T]) NewGradients() Gradients[T] {
+	g := Gradients[T]{Conv: make([]*ConvLayer[T], len(m.Conv)), Layers: make([]*Dense[T], len(m.Layers))}
+	for i, l := range m.Conv {
+		g.Conv[i] = l.newGradients()
+	}
+	for i, l := range m.Layers {
+		g.Layers[i] = &Dense[T]{W: NewMatrix[T](l.OutputDim(), l.InputDim()), B: initBiases[T](l.OutputDim())}
+		if l.Norm != nil {
+			dim := l.Norm.Dim()
+			g.Layers[i].Norm = &Norm[T]{Kind: l.Norm.Kind, Gamma: make([]T, dim), Beta: make([]T, dim)}
+		}
+	}
+	return g
+}
+
+// vectors liefert alle Gradienten-Vektoren in der Reihenfolge von MLP.Params.
+func (g Gradients[T]) vectors() [][]T {
+	var v [][]T
+	for _, l := range g.Conv {
+		if l.W != nil {
+			v = append(v, l.W.Data, l.B)
+		}
+	}
+	for _, l := range g.Layers {
+		v = append(v, l.W.Data, l.B)
+		if n := l.Norm; n != nil {
+			v = append(v, n.Gamma, n.Beta)
+		}
+	}
+	return v
+}
+
+// Add addiert die Gradienten o zu g.
+func (g Gradients[T]) Add(o Gradients[T]) {
+	ov := o.vectors()
+	for i, v := range g.vectors() {
+		axpy(1, ov[i], v)
+	}
+}
+
+// Zero setzt alle Gradienten auf 0.
+func (g Gradients[T]) Zero() {
+	for _, v := range g.vectors() {
+		clear(v)
+	}
+}
+
+// Scale multipliziert alle Gradienten mit f.
+func (g Gradients[T]) Scale(f T) {
+	for _, v := range g.vectors() {
+		for i := range v {
+			v[i] *= f
+		}
+	}
+}
+
+// Backward berechnet die Gradienten für ein einzelnes Beispiel per
+// Backpropagation aus den Ergebnissen von Forward und dem One-Hot-Label y.
+//
+// Für das Training ist BackwardBatch deutlich schneller.
+func (m *MLP[T]) Backward(zs, as [][]T, y []T) Gradients[T] {
+	grads := m.NewGradients()
+
+	c := len(m.Conv)
+	out := as[len(as)-1]
+	dZ := make([]T, len(out))
+	m.outputDelta(zs[len(zs)-1], out, y, dZ)
+
+	var dA []T
+	for l := len(m.Layers) - 1; l >= 0; l-- {
+		layer := m.Layers[l]
+		in := as[c+l]
+
+		// durch die Normalisierung: dZ = dLoss/d(W*a + b)
+		g := grads.Layers[l]
+		if layer.Norm != nil {
+			pre := make([]T, len(dZ))
+			for i := range pre {
+				pre[i] = dot(layer.W.Row(i), in) + layer.B[i]
+			}
+			layer.normSample(pre, dZ, g.Norm)
+		}
+
+		// dW = dZ * a^T, dB = dZ
+		for i := range dZ {
+			axpy(dZ[i], in, g.W.Row(i))
+			g.B[i] = dZ[i]
+		}
+
+		if l == 0 && c == 0 {
+			break
+		}
+
+		// dZ der vorherigen Schicht = Act'(z) * (W^T * dZ)
+		dA = make([]T, len(in))
+		for i := range dZ {
+			axpy(dZ[i], layer.W.Row(i), dA)
+		}
+		if l == 0 {
+			break
+		}
+		dPrev := make([]T, len(in))
+		m.Layers[l-1].Act.Backward(zs[c+l-1], in, dA, dPrev)
+		dZ = dPrev
+	}
+
+	// durch den Faltungsteil, dA = dLoss/d(Ausgabe der Schicht i)
+	for i := c - 1; i >= 0; i-- {
+		layer := m.Conv[i]
+		var dx []T
+		if i > 0 {
+			dx = make([]T, layer.In.Size())
+		}
+		layer.backward(as[i], zs[i], as[i+1], dA, dx, grads.Conv[i], newConvWork(layer))
+		dA = dx
+	}
+
+	return grads
+}
+
+// outputDelta berechnet dLoss/dz der Ausgabeschicht für ein Beispiel mit
+// Ausgabe a = Act(z) und One-Hot-Label y.
+func (m *MLP[T]) outputDelta(z, a, y, dZ []T) {
+	outLayer := m.Layers[len(m.Layers)-1]
+	if _, ok := outLayer.Act.(Softmax[T]); ok {
+		// dLoss/dZ der Ausgabeschicht = (a - y) für Softmax mit Cross-Entropy
+		for i := range a {
+			dZ[i] = a[i] - y[i]
+		}
+		return
+	}
+	outLayer.Act.Backward(z, a, crossEntropyGrad(y, a), dZ)
+}
+
+// Update aktualisiert die Parameter mit den Gradienten grads. Vorher wird der
+// Gradient der L1/L2-Gewichtsabnahme decay zu grads addiert. Ist opt nil,
+// wird ein einfacher Gradientenabstieg W -= lr * dW ausgeführt.
+func (m *MLP[T]) Update(grads Gradients[T], opt Optimizer[T], lr float64, decay WeightDecay) {
+	if opt == nil {
+		opt = &SGD[T]{}
+	}
+	params := m.Params(grads)
+	applyDecay(decay, params)
+	opt.Step(params, lr)
+}
+
+// Predict gibt die vorhergesagte Klasse zurück.
+func (m *MLP[T]) Predict(x []T) int {
+	_, as := m.Forward(x)
+	return argmax(as[len(as)-1])
+}
+
+// argmax liefert den Index des größten Wertes.
+func argmax[T Float](v []T) int {
+	maxVal := T(math.Inf(-1))
+	maxIdx := 0
+	for i, val := range v {
+		if val > maxVal {
+			maxVal = val
+			maxIdx = i
+		}
+	}
+	return maxIdx
+}
+
+// ComputeAccuracy berechnet die Genauigkeit auf einem Datensatz.
+func (m *MLP[T]) ComputeAccuracy(X [][]T, Y [][]T) float64 {
+	correct := 0
+	for i, x := range X {
+		pred := m.Predict(x)
+		// Y[i] ist one-hot, pred sollte der Index sein, wo 1 ist
+		if pred == argmax(Y[i]) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(X))
+}
@@ -0,0 +1,265 @@
+package mnist
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// Augmentation legt fest, wie Trainingsbilder während des Trainings zufällig
+// verändert werden. Jede Transformation wird mit ihrer Wahrscheinlichkeit Prob
+// angewendet, in der Reihenfolge affin, elastisch, Rauschen, Löschen. Der
+// Nullwert verändert die Bilder nicht.
+type Augmentation struct {
+	Affine  Affine  `json:"affine" yaml:"affine"`
+	Elastic Elastic `json:"elastic" yaml:"elastic"`
+	Noise   Noise   `json:"noise" yaml:"noise"`
+	Erasing Erasing `json:"erasing" yaml:"erasing"`
+}
+
+// Affine dreht, schert, skaliert und verschiebt das Bild um den Mittelpunkt.
+// Jeder Betrag ist gleichverteilt zwischen dem negativen und dem positiven
+// Höchstwert.
+type Affine struct {
+	Prob float64 `json:"prob" yaml:"prob"`
+	// Rotation ist der maximale Drehwinkel in Grad.
+	Rotation float64 `json:"rotation" yaml:"rotation"`
+	// Shift ist die maximale Verschiebung in Pixeln, je Achse.
+	Shift float64 `json:"shift" yaml:"shift"`
+	// Scale ist die maximale relative Größenänderung, 0.1 bedeutet 90% bis 110%.
+	Scale float64 `json:"scale" yaml:"scale"`
+	// Shear ist der maximale Scherwinkel in Grad.
+	Shear float64 `json:"shear" yaml:"shear"`
+}
+
+// Elastic verzerrt das Bild elastisch wie bei Simard et al. (2003): Ein
+// zufälliges Verschiebungsfeld (gleichverteilt in [-1,1] je Pixel und Achse)
+// wird mit einem Gaußfilter der Breite Sigma geglättet und mit Alpha skaliert.
+type Elastic struct {
+	Prob  float64 `json:"prob" yaml:"prob"`
+	Alpha float64 `json:"alpha" yaml:"alpha"`
+	Sigma float64 `json:"sigma" yaml:"sigma"`
+}
+
+// Noise addiert normalverteiltes Rauschen mit der Standardabweichung Std zu
+// jedem Pixel und begrenzt das Ergebnis auf [0,1].
+type Noise struct {
+	Prob float64 `json:"prob" yaml:"prob"`
+	Std  float64 `json:"std" yaml:"std"`
+}
+
+// Erasing überschreibt ein zufälliges Rechteck mit zufälligen Grauwerten
+// (Random Erasing, Zhong et al. 2017). Sein Flächenanteil ist gleichverteilt
+// zwischen 2% und Area, das Seitenverhältnis zwischen 0.3 und 3.3.
+type Erasing struct {
+	Prob float64 `json:"prob" yaml:"prob"`
+	Area float64 `json:"area" yaml:"area"`
+}
+
+// DefaultAugmentation liefert übliche Beträge für MNIST, alle
+// Transformationen eingeschaltet.
+func DefaultAugmentation() Augmentation {
+	return Augmentation{
+		Affine:  Affine{Prob: 0.5, Rotation: 10, Shift: 2, Scale: 0.1, Shear: 10},
+		Elastic: Elastic{Prob: 0.3, Alpha: 34, Sigma: 4},
+		Noise:   Noise{Prob: 0.3, Std: 0.1},
+		Erasing: Erasing{Prob: 0.3, Area: 0.15},
+	}
+}
+
+// Enabled gibt an, ob mindestens eine Transformation eingeschaltet ist.
+func (a *Augmentation) Enabled() bool {
+	return a.Affine.Prob > 0 || a.Elastic.Prob > 0 || a.Noise.Prob > 0 || a.Erasing.Prob > 0
+}
+
+// Check prüft Wahrscheinlichkeiten und Beträge auf gültige Werte.
+func (a *Augmentation) Check() error {
+	probs := []struct {
+		name string
+		p    float64
+	}{
+		{"affine", a.Affine.Prob},
+		{"elastic", a.Elastic.Prob},
+		{"noise", a.Noise.Prob},
+		{"erasing", a.Erasing.Prob},
+	}
+	for _, p := range probs {
+		if p.p < 0 || p.p > 1 {
+			return fmt.Errorf("Wahrscheinlichkeit für %s muss zwischen 0 und 1 liegen, nicht %g", p.name, p.p)
+		}
+	}
+	af := a.Affine
+	if af.Rotation < 0 || af.Shift < 0 || af.Scale < 0 || af.Scale >= 1 || af.Shear < 0 || af.Shear >= 90 {
+		return fmt.Errorf("ungültige Beträge für affine Transformationen: %+v", af)
+	}
+	if a.Elastic.Prob > 0 && (a.Elastic.Alpha < 0 || a.Elastic.Sigma <= 0) {
+		return fmt.Errorf("elastische Verzerrung erfordert alpha >= 0 und sigma > 0")
+	}
+	if a.Noise.Std < 0 {
+		return fmt.Errorf("Standardabweichung des Rauschens darf nicht negativ sein")
+	}
+	if a.Erasing.Prob > 0 && (a.Erasing.Area < 0.02 || a.Erasing.Area > 1) {
+		return fmt.Errorf("Flächenanteil für erasing muss zwischen 0.02 und 1 liegen, nicht %g", a.Erasing.Area)
+	}
+	return nil
+}
+
+// Augment verändert das normalisierte 28x28-Bild img zufällig nach a. Alle
+// Zufallszahlen stammen aus rng, so dass das Ergebnis nur von dessen Zustand
+// abhängt.
+func Augment[T mlp.Float](rng *rand.Rand, a *Augmentation, img []T) {
+	x := make([]float64, len(img))
+	for i, v := range img {
+		x[i] = float64(v)
+	}
+
+	if a.Affine.Prob > 0 && rng.Float64() < a.Affine.Prob {
+		x = affine(rng, &a.Affine, x)
+	}
+	if a.Elastic.Prob > 0 && rng.Float64() < a.Elastic.Prob {
+		x = elastic(rng, &a.Elastic, x)
+	}
+	if a.Noise.Prob > 0 && rng.Float64() < a.Noise.Prob {
+		for i := range x {
+			x[i] = min(max(x[i]+a.Noise.Std*rng.NormFloat64(), 0), 1)
+		}
+	}
+	if a.Erasing.Prob > 0 && rng.Float64() < a.Erasing.Prob {
+		erase(rng, &a.Erasing, x)
+	}
+
+	for i, v := range x {
+		img[i] = T(v)
+	}
+}
+
+// uniform liefert eine gleichverteilte Zufallszahl aus [-limit, limit].
+func uniform(rng *rand.Rand, limit float64) float64 {
+	return (2*rng.Float64() - 1) * limit
+}
+
+// affine wendet eine zufällige affine Transformation nach a auf x an.
+func affine(rng *rand.Rand, a *Affine, x []float64) []float64 {
+	rot := uniform(rng, a.Rotation) * math.Pi / 180
+	shear := uniform(rng, a.Shear) * math.Pi / 180
+	scale := 1 + uniform(rng, a.Scale)
+	tx, ty := uniform(rng, a.Shift), uniform(rng, a.Shift)
+
+	// Vorwärtsabbildung M = Drehung * Scherung * Skalierung; für jedes
+	// Zielpixel p wird die Quelle M⁻¹(p - Mitte - t) + Mitte abgetastet.
+	cos, sin, tan := math.Cos(rot), math.Sin(rot), math.Tan(shear)
+	m00, m01 := scale*cos, scale*(cos*tan-sin)
+	m10, m11 := scale*sin, scale*(sin*tan+cos)
+	det := m00*m11 - m01*m10
+	i00, i01 := m11/det, -m01/det
+	i10, i11 := -m10/det, m00/det
+
+	const cx, cy = (Cols - 1) / 2.0, (Rows - 1) / 2.0
+	return warp(x, func(px, py float64) (float64, float64) {
+		dx, dy := px-cx-tx, py-cy-ty
+		return i00*dx + i01*dy + cx, i10*dx + i11*dy + cy
+	})
+}
+
+// elastic verzerrt x elastisch nach e.
+func elastic(rng *rand.Rand, e *Elastic, x []float64) []float64 {
+	fx := make([]float64, Rows*Cols)
+	fy := make([]float64, Rows*Cols)
+	for i := range fx {
+		fx[i] = uniform(rng, 1)
+		fy[i] = uniform(rng, 1)
+	}
+	fx = gaussianBlur(fx, e.Sigma)
+	fy = gaussianBlur(fy, e.Sigma)
+	return warp(x, func(px, py float64) (float64, float64) {
+		i := int(py)*Cols + int(px)
+		return px + e.Alpha*fx[i], py + e.Alpha*fy[i]
+	})
+}
+
+// erase überschreibt ein zufälliges Rechteck in x nach e mit zufälligen
+// Grauwerten.
+func erase(rng *rand.Rand, e *Erasing, x []float64) {
+	area := (0.02 + rng.Float64()*(e.Area-0.02)) * Rows * Cols
+	ratio := math.Exp(math.Log(0.3) + rng.Float64()*(math.Log(3.3)-math.Log(0.3)))
+	h := min(int(math.Round(math.Sqrt(area*ratio))), Rows)
+	w := min(int(math.Round(math.Sqrt(area/ratio))), Cols)
+	top, left := rng.Intn(Rows-h+1), rng.Intn(Cols-w+1)
+	for y := top; y < top+h; y++ {
+		for xx := left; xx < left+w; xx++ {
+			x[y*Cols+xx] = rng.Float64()
+		}
+	}
+}
+
+// warp liefert ein neues Bild, dessen Pixel (px, py) aus x an der Stelle
+// src(px, py) bilinear interpoliert wird. Außerhalb von x ist der Hintergrund 0.
+func warp(x []float64, src func(px, py float64) (float64, float64)) []float64 {
+	out := make([]float64, len(x))
+	for py := 0; py < Rows; py++ {
+		for px := 0; px < Cols; px++ {
+			sx, sy := src(float64(px), float64(py))
+			out[py*Cols+px] = bilinear(x, sx, sy)
+		}
+	}
+	return out
+}
+
+// bilinear interpoliert x an der Stelle (sx, sy).
+func bilinear(x []float64, sx, sy float64) float64 {
+	x0, y0 := math.Floor(sx), math.Floor(sy)
+	fx, fy := sx-x0, sy-y0
+	at := func(px, py int) float64 {
+		if px < 0 || px >= Cols || py < 0 || py >= Rows {
+			return 0
+		}
+		return x[py*Cols+px]
+	}
+	ix, iy := int(x0), int(y0)
+	return (1-fy)*((1-fx)*at(ix, iy)+fx*at(ix+1, iy)) +
+		fy*((1-fx)*at(ix, iy+1)+fx*at(ix+1, iy+1))
+}
+
+// gaussianBlur faltet das Feld f zeilen- und spaltenweise mit einem auf 3*sigma
+// abgeschnittenen Gaußfilter; außerhalb des Bildes ist f 0.
+func gaussianBlur(f []float64, sigma float64) []float64 {
+	r := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*r+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	tmp := make([]float64, len(f))
+	out := make([]float64, len(f))
+	for y := 0; y < Rows; y++ {
+		for x := 0; x < Cols; x++ {
+			var v float64
+			for k, w := range kernel {
+				if xx := x + k - r; xx >= 0 && xx < Cols {
+					v += w * f[y*Cols+xx]
+				}
+			}
+			tmp[y*Cols+x] = v
+		}
+	}
+	for y := 0; y < Rows; y++ {
+		for x := 0; x < Cols; x++ {
+			var v float64
+			for k, w := range kernel {
+				if yy := y + k - r; yy >= 0 && yy < Rows {
+					v += w * tmp[yy*Cols+x]
+				}
+			}
+			out[y*Cols+x] = v
+		}
+	}
+	return out
+}
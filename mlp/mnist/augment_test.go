@@ -0,0 +1,111 @@
+package mnist
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// testImage liefert ein normalisiertes Bild mit einem hellen Ring in der
+// Mitte und Grauwerten zwischen 0 und 1.
+func testImage() []float64 {
+	img := make([]float64, Rows*Cols)
+	for y := 0; y < Rows; y++ {
+		for x := 0; x < Cols; x++ {
+			r := math.Hypot(float64(x)-13.5, float64(y)-13.5)
+			img[y*Cols+x] = max(0, 1-math.Abs(r-7)/3)
+		}
+	}
+	return img
+}
+
+// augmented wendet a mit einer mit seed initialisierten Zufallsquelle auf eine
+// Kopie von img an.
+func augmented(a Augmentation, seed int64, img []float64) []float64 {
+	out := slices.Clone(img)
+	Augment(rand.New(rand.NewSource(seed)), &a, out)
+	return out
+}
+
+func TestAugment(t *testing.T) {
+	img := testImage()
+	for _, tc := range []struct {
+		name string
+		a    Augmentation
+	}{
+		{"affine", Augmentation{Affine: Affine{Prob: 1, Rotation: 30, Shift: 3, Scale: 0.2, Shear: 20}}},
+		{"elastic", Augmentation{Elastic: Elastic{Prob: 1, Alpha: 34, Sigma: 4}}},
+		{"noise", Augmentation{Noise: Noise{Prob: 1, Std: 0.5}}},
+		{"erasing", Augmentation{Erasing: Erasing{Prob: 1, Area: 0.3}}},
+		{"alle", Augmentation{
+			Affine:  Affine{Prob: 1, Rotation: 10, Shift: 2, Scale: 0.1, Shear: 10},
+			Elastic: Elastic{Prob: 1, Alpha: 34, Sigma: 4},
+			Noise:   Noise{Prob: 1, Std: 0.1},
+			Erasing: Erasing{Prob: 1, Area: 0.15},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.a.Check(); err != nil {
+				t.Fatal(err)
+			}
+			for seed := int64(1); seed <= 20; seed++ {
+				got := augmented(tc.a, seed, img)
+				for i, v := range got {
+					if v < 0 || v > 1 || math.IsNaN(v) {
+						t.Fatalf("Seed %d: Pixel %d = %g außerhalb von [0,1]", seed, i, v)
+					}
+				}
+				if slices.Equal(got, img) {
+					t.Errorf("Seed %d: Bild unverändert", seed)
+				}
+				if again := augmented(tc.a, seed, img); !slices.Equal(got, again) {
+					t.Errorf("Seed %d: zwei Aufrufe liefern verschiedene Bilder", seed)
+				}
+			}
+			if slices.Equal(augmented(tc.a, 1, img), augmented(tc.a, 2, img)) {
+				t.Error("Seeds 1 und 2 liefern dasselbe Bild")
+			}
+		})
+	}
+}
+
+func TestAugmentProbZero(t *testing.T) {
+	img := testImage()
+	// große Beträge, aber keine Transformation eingeschaltet
+	a := Augmentation{
+		Affine:  Affine{Rotation: 30, Shift: 3, Scale: 0.2, Shear: 20},
+		Elastic: Elastic{Alpha: 34, Sigma: 4},
+		Noise:   Noise{Std: 0.5},
+		Erasing: Erasing{Area: 0.3},
+	}
+	if a.Enabled() {
+		t.Error("Enabled = true ohne Wahrscheinlichkeiten")
+	}
+	for seed := int64(1); seed <= 5; seed++ {
+		if got := augmented(a, seed, img); !slices.Equal(got, img) {
+			t.Errorf("Seed %d: Bild verändert", seed)
+		}
+	}
+
+	img32 := make([]float32, len(img))
+	for i, v := range img {
+		img32[i] = float32(v)
+	}
+	got := slices.Clone(img32)
+	Augment(rand.New(rand.NewSource(1)), &a, got)
+	if !slices.Equal(got, img32) {
+		t.Error("float32: Bild verändert")
+	}
+}
+
+func TestAffineIdentity(t *testing.T) {
+	// ohne Beträge bildet affine jedes Pixel auf sich selbst ab
+	img := testImage()
+	got := affine(rand.New(rand.NewSource(1)), &Affine{Prob: 1}, img)
+	for i, v := range got {
+		if math.Abs(v-img[i]) > 1e-12 {
+			t.Fatalf("Pixel %d = %g, erwartet %g", i, v, img[i])
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package mnist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Info beschreibt einen Datensatz im Format von MNIST: 28x28-Graustufenbilder
+// und Labels in IDX-Dateien.
+type Info struct {
+	Name string
+	// Dir ist das Verzeichnis, in dem die Dateien standardmäßig liegen.
+	Dir string
+	// Dateinamen der Trainings- und Testdaten in Dir, jeweils ohne .gz.
+	TrainImages, TrainLabels, TestImages, TestLabels string
+	// Classes sind die Namen der Klassen in der Reihenfolge der Labels.
+	Classes []string
+	// LabelOffset ist das kleinste Label in den Dateien, z. B. 1 bei
+	// EMNIST Letters. Beim Laden wird es abgezogen, so dass die Klassen bei 0
+	// beginnen.
+	LabelOffset int
+	// Transposed gibt an, dass die Bilder spaltenweise gespeichert sind (EMNIST).
+	// Sie werden beim Laden transponiert, so dass sie wie MNIST aufrecht stehen.
+	Transposed bool
+}
+
+// Die unterstützten Datensätze.
+var (
+	MNIST = &Info{
+		Name:        "mnist",
+		Dir:         "mnist",
+		TrainImages: "train-images-idx3-ubyte",
+		TrainLabels: "train-labels-idx1-ubyte",
+		TestImages:  "t10k-images-idx3-ubyte",
+		TestLabels:  "t10k-labels-idx1-ubyte",
+		Classes:     Classes,
+	}
+	FashionMNIST = &Info{
+		Name:        "fashion-mnist",
+		Dir:         "fashion-mnist",
+		TrainImages: "train-images-idx3-ubyte",
+		TrainLabels: "train-labels-idx1-ubyte",
+		TestImages:  "t10k-images-idx3-ubyte",
+		TestLabels:  "t10k-labels-idx1-ubyte",
+		Classes: []string{"T-Shirt/Top", "Hose", "Pullover", "Kleid", "Mantel",
+			"Sandale", "Hemd", "Sneaker", "Tasche", "Stiefelette"},
+	}
+	EMNISTLetters = &Info{
+		Name:        "emnist-letters",
+		Dir:         "emnist",
+		TrainImages: "emnist-letters-train-images-idx3-ubyte",
+		TrainLabels: "emnist-letters-train-labels-idx1-ubyte",
+		TestImages:  "emnist-letters-test-images-idx3-ubyte",
+		TestLabels:  "emnist-letters-test-labels-idx1-ubyte",
+		// Groß- und Kleinbuchstaben bilden jeweils eine Klasse.
+		Classes:     strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ", ""),
+		LabelOffset: 1,
+		Transposed:  true,
+	}
+	EMNISTBalanced = &Info{
+		Name:        "emnist-balanced",
+		Dir:         "emnist",
+		TrainImages: "emnist-balanced-train-images-idx3-ubyte",
+		TrainLabels: "emnist-balanced-train-labels-idx1-ubyte",
+		TestImages:  "emnist-balanced-test-images-idx3-ubyte",
+		TestLabels:  "emnist-balanced-test-labels-idx1-ubyte",
+		// Kleinbuchstaben, die sich kaum von ihrem Großbuchstaben
+		// unterscheiden (c, i, j, k, l, m, o, p, s, u, v, w, x, y, z), sind
+		// mit diesem zusammengefasst.
+		Classes:    strings.Split("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabdefghnqrt", ""),
+		Transposed: true,
+	}
+	KMNIST = &Info{
+		Name:        "kmnist",
+		Dir:         "kmnist",
+		TrainImages: "train-images-idx3-ubyte",
+		TrainLabels: "train-labels-idx1-ubyte",
+		TestImages:  "t10k-images-idx3-ubyte",
+		TestLabels:  "t10k-labels-idx1-ubyte",
+		// Kuzushiji-MNIST: zehn Hiragana in kursiver Schreibschrift
+		Classes: []string{"お", "き", "す", "つ", "な", "は", "ま", "や", "れ", "を"},
+	}
+)
+
+// Datasets sind alle unterstützten Datensätze.
+var Datasets = []*Info{MNIST, FashionMNIST, EMNISTLetters, EMNISTBalanced, KMNIST}
+
+// DatasetNames liefert die Namen aller unterstützten Datensätze.
+func DatasetNames() []string {
+	names := make([]string, len(Datasets))
+	for i, d := range Datasets {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// Lookup liefert den Datensatz mit dem Namen name.
+func Lookup(name string) (*Info, error) {
+	for _, d := range Datasets {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unbekannter Datensatz %q, erlaubt sind %s", name, strings.Join(DatasetNames(), ", "))
+}
+
+// Path liefert den Pfad der Datei file in Dir. Gibt es nur die mit gzip
+// komprimierte Datei (file.gz), wie sie heruntergeladen wird, wird diese
+// verwendet.
+func (d *Info) Path(file string) string {
+	path := filepath.Join(d.Dir, file)
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(path + ".gz"); err == nil {
+			return path + ".gz"
+		}
+	}
+	return path
+}
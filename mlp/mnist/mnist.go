@@ -0,0 +1,334 @@
+// Package mnist enthält Hilfsfunktionen zum Laden der MNIST-Daten und
+// gleich aufgebauter Datensätze (Fashion-MNIST, EMNIST, KMNIST) im IDX-Format
+// sowie zum Vorverarbeiten eigener 28x28-Bilder für die Inferenz.
+package mnist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+
+	"grimm.world/mlp_demo/mlp"
+	"grimm.world/mlp_demo/mlp/idx"
+)
+
+// Rows und Cols sind die Höhe und Breite eines MNIST-Bildes.
+const (
+	Rows = 28
+	Cols = 28
+)
+
+// Pixel werden beim Laden mit Scale auf [0,1] normalisiert.
+const Scale = 1.0 / 255
+
+// Classes sind die Namen der zehn MNIST-Klassen.
+var Classes = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+// Magic Numbers der IDX-Dateien: vorzeichenlose Bytes mit 3 Dimensionen
+// (Anzahl, Zeilen, Spalten) für Bilder und mit einer Dimension für Labels.
+const (
+	ImageMagic = 2051
+	LabelMagic = 2049
+)
+
+//--------------------------------------------------------
+// Hilfsfunktionen zum Laden von MNIST
+//--------------------------------------------------------
+
+// Dataset enthält Bilder und Labels wie in den IDX-Dateien als Bytes
+// (784 Bytes je Bild, ein Byte je Label), für die 60000 Trainingsbilder also
+// etwa 47 MB. Erst beim Zugriff über Example werden die Pixel auf [0,1]
+// normalisiert und die Labels One-Hot kodiert, d. h. je Mini-Batch. Dataset
+// implementiert mlp.Dataset[T].
+type Dataset[T mlp.Float] struct {
+	// Pixels enthält die Bilder nacheinander, jedes zeilenweise mit Rows*Cols Bytes.
+	Pixels     []byte
+	Labels     []byte
+	Rows, Cols int
+	// Classes ist die Länge der One-Hot-Labels.
+	Classes int
+	// scale bildet einen Grauwert auf den normalisierten Pixelwert ab.
+	scale [256]T
+}
+
+// Open lädt MNIST-Bilder und -Labels aus den IDX-Dateien imageFile und
+// labelFile (z. B. train-images-idx3-ubyte und train-labels-idx1-ubyte, auch
+// mit gzip komprimiert wie train-images-idx3-ubyte.gz). Jede Datei wird in
+// einem Stück gelesen und anhand ihrer Magic Number und Größe geprüft.
+func Open[T mlp.Float](imageFile, labelFile string) (*Dataset[T], error) {
+	return OpenDataset[T](MNIST, imageFile, labelFile)
+}
+
+// OpenDataset lädt wie Open Bilder und Labels des Datensatzes info. Labels
+// werden um info.LabelOffset verschoben und Bilder bei info.Transposed
+// transponiert.
+func OpenDataset[T mlp.Float](info *Info, imageFile, labelFile string) (*Dataset[T], error) {
+	pixels, rows, cols, err := ReadImages(imageFile)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := ReadLabels(labelFile)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(pixels) / (rows * cols); n != len(labels) {
+		return nil, fmt.Errorf("Anzahl Bilder (%d) und Labels (%d) stimmen nicht überein", n, len(labels))
+	}
+
+	if info.Transposed {
+		pixels, rows, cols = transpose(pixels, rows, cols), cols, rows
+	}
+	d := &Dataset[T]{Pixels: pixels, Labels: labels, Rows: rows, Cols: cols, Classes: len(info.Classes)}
+	for i, l := range labels {
+		c := int(l) - info.LabelOffset
+		if c < 0 || c >= d.Classes {
+			return nil, fmt.Errorf("%s: Label %d von Beispiel %d außerhalb von %d bis %d", labelFile, l, i, info.LabelOffset, info.LabelOffset+d.Classes-1)
+		}
+		labels[i] = byte(c)
+	}
+	for v := range d.scale {
+		// Normalisieren auf [0,1]
+		d.scale[v] = T(v) / 255.0
+	}
+	return d, nil
+}
+
+// transpose vertauscht Zeilen und Spalten jedes Bildes in pixels (rows x cols).
+func transpose(pixels []byte, rows, cols int) []byte {
+	out := make([]byte, len(pixels))
+	size := rows * cols
+	for off := 0; off < len(pixels); off += size {
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				out[off+c*rows+r] = pixels[off+r*cols+c]
+			}
+		}
+	}
+	return out
+}
+
+// SetPixelValues legt fest, welchen Eingabewert Example für jeden Grauwert
+// liefert: values[v] für den Grauwert v. Ohne Aufruf ist das v/255, z. B. für
+// ein Modell mit anderer Normalisierung lässt sich das hier ändern.
+func (d *Dataset[T]) SetPixelValues(values [256]float64) {
+	for v, x := range values {
+		d.scale[v] = T(x)
+	}
+}
+
+// Len liefert die Anzahl der Bilder.
+func (d *Dataset[T]) Len() int {
+	return len(d.Labels)
+}
+
+// Example schreibt die normalisierten Pixel des Bildes i nach x und das
+// One-Hot-kodierte Label nach y.
+func (d *Dataset[T]) Example(i int, x, y []T) {
+	size := d.Rows * d.Cols
+	for j, v := range d.Pixels[i*size : (i+1)*size] {
+		x[j] = d.scale[v]
+	}
+	for j := range y {
+		y[j] = 0
+	}
+	y[d.Labels[i]] = 1
+}
+
+// Class liefert das Label des Bildes i.
+func (d *Dataset[T]) Class(i int) int {
+	return int(d.Labels[i])
+}
+
+// Load lädt Bilder und Labels aus den IDX-Dateien.
+// imageFile und labelFile sind die Pfade zu den entsprechenden MNIST-Dateien.
+// Es liefert slices von Bildern (jede ein slice der Länge 784) und Labels (one-hot Kodierung mit Länge 10)
+// in der Genauigkeit T. Die Daten belegen damit ein Vielfaches des Speichers von Open.
+func Load[T mlp.Float](imageFile, labelFile string) ([][]T, [][]T, error) {
+	d, err := Open[T](imageFile, labelFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	images := make([][]T, d.Len())
+	labels := make([][]T, d.Len())
+	for i := range images {
+		images[i] = make([]T, d.Rows*d.Cols)
+		labels[i] = make([]T, d.Classes)
+		d.Example(i, images[i], labels[i])
+	}
+	return images, labels, nil
+}
+
+// ReadImages liest eine IDX-Bilddatei (Magic Number 2051), unkomprimiert
+// oder mit gzip komprimiert, in einem Stück. Es liefert die Pixel aller Bilder
+// nacheinander (0 bis 255, zeilenweise) sowie Höhe und Breite eines Bildes.
+func ReadImages(filename string) (pixels []byte, rows, cols int, err error) {
+	t, err := readIDX(filename, ImageMagic)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if t.Dims[1] == 0 || t.Dims[2] == 0 {
+		return nil, 0, 0, fmt.Errorf("%s: ungültige Bildgröße %dx%d", filename, t.Dims[1], t.Dims[2])
+	}
+	return t.Data, t.Dims[1], t.Dims[2], nil
+}
+
+// ReadLabels liest eine IDX-Labeldatei (Magic Number 2049), unkomprimiert oder
+// mit gzip komprimiert, in einem Stück.
+func ReadLabels(filename string) ([]byte, error) {
+	t, err := readIDX(filename, LabelMagic)
+	if err != nil {
+		return nil, err
+	}
+	return t.Data, nil
+}
+
+// readIDX liest die IDX-Datei filename und prüft ihre Magic Number.
+func readIDX(filename string, magic int) (*idx.Tensor, error) {
+	t, err := idx.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if t.Magic() != magic {
+		return nil, fmt.Errorf("%s: ungültige Magic Number %d (%v, %d Dimensionen), erwartet %d", filename, t.Magic(), t.Type, len(t.Dims), magic)
+	}
+	return t, nil
+}
+
+// WriteImages schreibt Bilder wie von ReadImages geliefert als IDX-Datei
+// filename, bei der Endung .gz mit gzip komprimiert.
+func WriteImages(filename string, pixels []byte, rows, cols int) error {
+	if rows <= 0 || cols <= 0 || len(pixels)%(rows*cols) != 0 {
+		return fmt.Errorf("%d Pixel passen nicht zu Bildern der Größe %dx%d", len(pixels), rows, cols)
+	}
+	t := &idx.Tensor{Type: idx.UByte, Dims: []int{len(pixels) / (rows * cols), rows, cols}, Data: pixels}
+	return idx.WriteFile(filename, t)
+}
+
+// WriteLabels schreibt Labels als IDX-Datei filename, bei der Endung .gz mit
+// gzip komprimiert.
+func WriteLabels(filename string, labels []byte) error {
+	return idx.WriteFile(filename, &idx.Tensor{Type: idx.UByte, Dims: []int{len(labels)}, Data: labels})
+}
+
+// Hash liefert den SHA-256-Hash über den Inhalt der Dateien files (z. B. der
+// Trainings- und Testdaten) als Hex-String.
+func Hash(files ...string) (string, error) {
+	h := sha256.New()
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("Fehler beim Lesen von %s: %v", name, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LoadImage lädt das Bild mit gegebenem Index aus einer MNIST-Bilddatei (z. B. t10k-images-idx3-ubyte,
+// auch mit gzip komprimiert). Es liefert die rohen Pixel (0 bis 255) zeilenweise.
+func LoadImage(filename string, idx int) ([]byte, error) {
+	pixels, rows, cols, err := ReadImages(filename)
+	if err != nil {
+		return nil, err
+	}
+	size := rows * cols
+	numImages := len(pixels) / size
+	if idx < 0 || idx >= numImages {
+		return nil, fmt.Errorf("Index außerhalb der Reichweite. Anzahl Bilder: %d", numImages)
+	}
+	return pixels[idx*size : (idx+1)*size], nil
+}
+
+// LoadLabel lädt das Label mit gegebenem Index aus einer MNIST-Labeldatei (z. B. t10k-labels-idx1-ubyte,
+// auch mit gzip komprimiert).
+func LoadLabel(filename string, idx int) (uint8, error) {
+	labels, err := ReadLabels(filename)
+	if err != nil {
+		return 0, err
+	}
+	if idx < 0 || idx >= len(labels) {
+		return 0, fmt.Errorf("Index außerhalb der Reichweite. Anzahl Labels: %d", len(labels))
+	}
+	return labels[idx], nil
+}
+
+//--------------------------------------------------------
+// Bild laden und vorverarbeiten
+//--------------------------------------------------------
+
+// LoadPNG lädt ein 28x28 PNG-Bild und wandelt es mit Preprocess in den Eingabevektor um.
+func LoadPNG[T mlp.Float](filename string) ([]T, error) {
+	img, err := decodePNG(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Preprocess[T](img)
+}
+
+// LoadGray lädt ein 28x28 PNG-Bild und liefert es mit Gray als Grauwerte von
+// 0 bis 255, z. B. für einen modelio.Predictor, der die Normalisierung des
+// Modells selbst anwendet.
+func LoadGray(filename string) ([]float64, error) {
+	img, err := decodePNG(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Gray(img)
+}
+
+// decodePNG lädt die PNG-Datei filename.
+func decodePNG(filename string) (image.Image, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Öffnen des Bildes: %v", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Dekodieren des PNG: %v", err)
+	}
+	return img, nil
+}
+
+// Preprocess wandelt ein 28x28-Bild in ein Graustufen-Array um (0 bis 1 normalisiert).
+func Preprocess[T mlp.Float](img image.Image) ([]T, error) {
+	gray, err := Gray(img)
+	if err != nil {
+		return nil, err
+	}
+	input := make([]T, len(gray))
+	for i, p := range gray {
+		// Normalisieren auf [0,1]
+		input[i] = T(p * Scale)
+	}
+	return input, nil
+}
+
+// Gray wandelt ein 28x28-Bild zeilenweise in Grauwerte von 0 bis 255 um.
+func Gray(img image.Image) ([]float64, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width != 28 || height != 28 {
+		return nil, fmt.Errorf("Bildgröße muss 28x28 sein, ist aber %dx%d", width, height)
+	}
+
+	gray := make([]float64, 28*28)
+	for y := 0; y < 28; y++ {
+		for x := 0; x < 28; x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			// Falls c kein Grauwert ist, erstellen wir ihn aus dem Luminanzkanal
+			r, g, b, _ := c.RGBA()
+			gray[y*28+x] = 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+		}
+	}
+	return gray, nil
+}
@@ -0,0 +1,119 @@
+package mnist
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp/idx"
+)
+
+// writeFixture schreibt Bilder (rows x cols) und Labels als IDX-Dateien in
+// ein temporäres Verzeichnis und liefert deren Namen.
+func writeFixture(t *testing.T, pixels []byte, rows, cols int, labels []byte) (imageFile, labelFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	imageFile, labelFile = filepath.Join(dir, "images.idx"), filepath.Join(dir, "labels.idx.gz")
+	images := &idx.Tensor{Type: idx.UByte, Dims: []int{len(pixels) / (rows * cols), rows, cols}, Data: pixels}
+	if err := idx.WriteFile(imageFile, images); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.WriteFile(labelFile, &idx.Tensor{Type: idx.UByte, Dims: []int{len(labels)}, Data: labels}); err != nil {
+		t.Fatal(err)
+	}
+	return imageFile, labelFile
+}
+
+func TestOpenDataset(t *testing.T) {
+	// zwei Bilder mit 2 Zeilen und 3 Spalten
+	pixels := []byte{
+		0, 51, 102,
+		153, 204, 255,
+
+		10, 11, 12,
+		13, 14, 15,
+	}
+	for _, tc := range []struct {
+		info       *Info
+		labels     []byte
+		rows, cols int
+		pixels     []byte
+		classes    []int
+	}{
+		{MNIST, []byte{9, 0}, 2, 3, pixels, []int{9, 0}},
+		// EMNIST speichert die Bilder spaltenweise, Labels beginnen bei 1
+		{EMNISTLetters, []byte{1, 26}, 3, 2, []byte{
+			0, 153,
+			51, 204,
+			102, 255,
+
+			10, 13,
+			11, 14,
+			12, 15,
+		}, []int{0, 25}},
+	} {
+		t.Run(tc.info.Name, func(t *testing.T) {
+			imageFile, labelFile := writeFixture(t, slices.Clone(pixels), 2, 3, tc.labels)
+			d, err := OpenDataset[float64](tc.info, imageFile, labelFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Len() != 2 || d.Rows != tc.rows || d.Cols != tc.cols || d.Classes != len(tc.info.Classes) {
+				t.Fatalf("%d Bilder %dx%d, %d Klassen", d.Len(), d.Rows, d.Cols, d.Classes)
+			}
+			if !slices.Equal(d.Pixels, tc.pixels) {
+				t.Errorf("Pixel %v, erwartet %v", d.Pixels, tc.pixels)
+			}
+
+			x, y := make([]float64, d.Rows*d.Cols), make([]float64, d.Classes)
+			for i, c := range tc.classes {
+				if d.Class(i) != c {
+					t.Errorf("Bild %d: Klasse %d, erwartet %d", i, d.Class(i), c)
+				}
+				d.Example(i, x, y)
+				for j, v := range x {
+					if want := float64(tc.pixels[i*len(x)+j]) / 255; v != want {
+						t.Fatalf("Bild %d: Pixel %d = %g, erwartet %g", i, j, v, want)
+					}
+				}
+				var sum float64
+				for _, v := range y {
+					sum += v
+				}
+				if y[c] != 1 || sum != 1 {
+					t.Errorf("Bild %d: Label %v, erwartet One-Hot für %d", i, y, c)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenDatasetErrors(t *testing.T) {
+	pixels := make([]byte, 2*Rows*Cols)
+	for _, tc := range []struct {
+		name   string
+		info   *Info
+		labels []byte
+		err    string
+	}{
+		{"Label zu groß", MNIST, []byte{3, 10}, "Label 10 von Beispiel 1 außerhalb von 0 bis 9"},
+		{"Label unter Offset", EMNISTLetters, []byte{0, 5}, "Label 0 von Beispiel 0 außerhalb von 1 bis 26"},
+		{"Label über Offset", EMNISTLetters, []byte{5, 27}, "Label 27 von Beispiel 1 außerhalb von 1 bis 26"},
+		{"Anzahl", MNIST, []byte{1, 2, 3}, "Anzahl Bilder (2) und Labels (3)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			imageFile, labelFile := writeFixture(t, pixels, Rows, Cols, tc.labels)
+			_, err := OpenDataset[float32](tc.info, imageFile, labelFile)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Fehler %v, erwartet %q", err, tc.err)
+			}
+		})
+	}
+
+	// Bilder und Labels vertauscht
+	imageFile, labelFile := writeFixture(t, pixels, Rows, Cols, []byte{1, 2})
+	if _, err := Open[float64](labelFile, imageFile); err == nil || !strings.Contains(err.Error(), "Magic Number") {
+		t.Errorf("Fehler %v, erwartet ungültige Magic Number", err)
+	}
+}
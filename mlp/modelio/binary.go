@@ -0,0 +1,376 @@
+package modelio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strconv"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// Das Binärformat einer Modelldatei ist:
+//
+//	magic    4 Byte "MLPB"
+//	version  uint32
+//	hlen     uint32, Länge des Headers in Byte
+//	header   hlen Byte JSON (binaryHeader)
+//	tensors  alle im Header aufgeführten Tensoren nacheinander, zeilenweise,
+//	         little-endian im Typ header.DType
+//	footer   CRC-32 (IEEE, uint32) und SHA-256 (32 Byte) über alle
+//	         vorherigen Bytes
+//
+// Alle Ganzzahlen sind little-endian.
+const (
+	binaryMagic   = "MLPB"
+	binaryVersion = 1
+	footerSize    = 4 + sha256.Size
+)
+
+// binaryHeader ist der JSON-Header einer binären Modelldatei.
+type binaryHeader struct {
+	// DType ist der Typ der Tensoren, mlp.Float32 oder mlp.Float64.
+	DType       string    `json:"dtype"`
+	Metadata    *Metadata `json:"metadata,omitempty"`
+	Sizes       []int     `json:"sizes"`
+	Activations []string  `json:"activations"`
+	// Norms beschreibt die Normalisierung jeder Schicht, nil ohne
+	// Normalisierungen. Ihre Parameter stehen in den Tensoren "<i>.gamma",
+	// "<i>.beta" und bei BatchNorm "<i>.running_mean" und "<i>.running_var".
+	Norms []normInfo `json:"norms,omitempty"`
+	// Input und Conv beschreiben den Faltungsteil, nil ohne. Die Parameter von
+	// Conv2D stehen in den Tensoren "conv<i>.W" und "conv<i>.b".
+	Input   *mlp.Shape      `json:"input,omitempty"`
+	Conv    []mlp.LayerSpec `json:"conv,omitempty"`
+	Tensors []tensorInfo    `json:"tensors"`
+	// Checkpoint ist nur bei Checkpoints gesetzt.
+	Checkpoint *checkpointHeader `json:"checkpoint,omitempty"`
+}
+
+// normInfo beschreibt die Normalisierung einer Schicht; Kind ist "" für keine.
+type normInfo struct {
+	Kind     string  `json:"kind,omitempty"`
+	Momentum float64 `json:"momentum,omitempty"`
+	Epsilon  float64 `json:"epsilon,omitempty"`
+}
+
+// tensorInfo beschreibt einen Tensor im Datenteil der Datei.
+type tensorInfo struct {
+	Name  string `json:"name"`
+	Shape []int  `json:"shape"`
+}
+
+// len liefert die Anzahl der Elemente des Tensors.
+func (t tensorInfo) len() int {
+	n := 1
+	for _, d := range t.Shape {
+		n *= d
+	}
+	return n
+}
+
+// isBinary prüft, ob data mit der Kennung des Binärformats beginnt.
+func isBinary(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(binaryMagic))
+}
+
+// flatten liefert die Zeilen von w hintereinander und die Anzahl der Spalten.
+func flatten[T mlp.Float](w [][]T) ([]T, int) {
+	cols := 0
+	if len(w) > 0 {
+		cols = len(w[0])
+	}
+	data := make([]T, 0, len(w)*cols)
+	for _, row := range w {
+		data = append(data, row...)
+	}
+	return data, cols
+}
+
+// modelTensors liefert die Beschreibung und den Inhalt der Tensoren aller
+// Schichten von modelData, je Conv2D-Schicht "conv<i>.W" und "conv<i>.b", je
+// Dense-Schicht "<i>.W" und "<i>.b".
+func modelTensors[T mlp.Float](modelData *modelFile[T]) ([]tensorInfo, [][]T) {
+	var infos []tensorInfo
+	var tensors [][]T
+	for i, c := range modelData.Conv {
+		if c.W == nil {
+			continue
+		}
+		w, cols := flatten(c.W)
+		infos = append(infos,
+			tensorInfo{Name: fmt.Sprintf("conv%d.W", i), Shape: []int{len(c.W), cols}},
+			tensorInfo{Name: fmt.Sprintf("conv%d.b", i), Shape: []int{len(c.B)}},
+		)
+		tensors = append(tensors, w, c.B)
+	}
+	for i, l := range modelData.Layers {
+		w, cols := flatten(l.W)
+		infos = append(infos,
+			tensorInfo{Name: fmt.Sprintf("%d.W", i), Shape: []int{len(l.W), cols}},
+			tensorInfo{Name: fmt.Sprintf("%d.b", i), Shape: []int{len(l.B)}},
+		)
+		tensors = append(tensors, w, l.B)
+
+		if n := l.Norm; n != nil {
+			named := map[string][]T{"gamma": n.Gamma, "beta": n.Beta, "running_mean": n.RunMean, "running_var": n.RunVar}
+			for _, name := range normTensors(n.Kind) {
+				infos = append(infos, tensorInfo{Name: fmt.Sprintf("%d.%s", i, name), Shape: []int{len(named[name])}})
+				tensors = append(tensors, named[name])
+			}
+		}
+	}
+	return infos, tensors
+}
+
+// normTensors liefert die Namen der Tensoren einer Normalisierung kind.
+func normTensors(kind string) []string {
+	if kind == mlp.BatchNorm {
+		return []string{"gamma", "beta", "running_mean", "running_var"}
+	}
+	return []string{"gamma", "beta"}
+}
+
+// encodeBinary schreibt modelData im Binärformat.
+func encodeBinary[T mlp.Float](modelData *modelFile[T]) ([]byte, error) {
+	hdr := newBinaryHeader(modelData)
+	infos, tensors := modelTensors(modelData)
+	hdr.Tensors = infos
+	return writeBinary(hdr, tensors)
+}
+
+// newBinaryHeader liefert den Header für modelData ohne Tensoren.
+func newBinaryHeader[T mlp.Float](modelData *modelFile[T]) *binaryHeader {
+	hdr := &binaryHeader{
+		DType:    mlp.Precision[T](),
+		Metadata: modelData.Metadata,
+		Sizes:    modelData.Sizes,
+		Input:    modelData.Input,
+	}
+	for _, c := range modelData.Conv {
+		hdr.Conv = append(hdr.Conv, c.LayerSpec)
+	}
+	hasNorm := false
+	for _, l := range modelData.Layers {
+		hdr.Activations = append(hdr.Activations, l.Activation)
+		hasNorm = hasNorm || l.Norm != nil
+	}
+	if hasNorm {
+		hdr.Norms = make([]normInfo, len(modelData.Layers))
+		for i, l := range modelData.Layers {
+			if n := l.Norm; n != nil {
+				hdr.Norms[i] = normInfo{Kind: n.Kind, Momentum: n.Momentum, Epsilon: n.Epsilon}
+			}
+		}
+	}
+	return hdr
+}
+
+// writeBinary schreibt hdr und die Tensoren, die in derselben Reihenfolge wie
+// hdr.Tensors übergeben werden, samt Prüfsummen im Binärformat.
+func writeBinary[T mlp.Float](hdr *binaryHeader, tensors [][]T) ([]byte, error) {
+	header, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Serialisieren des Headers: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(binaryVersion))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(header)))
+	buf.Write(header)
+	for _, t := range tensors {
+		writeTensor(&buf, t)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(sum[:])
+	return buf.Bytes(), nil
+}
+
+// decodeBinary liest eine binäre Modelldatei in der Genauigkeit T und prüft
+// dabei die Prüfsummen. Neben dem Modell liefert es den Header und alle
+// Tensoren nach Namen, z. B. für die Zusatzdaten eines Checkpoints.
+func decodeBinary[T mlp.Float](data []byte) (*modelFile[T], *binaryHeader, map[string][]T, error) {
+	hdr, r, err := readBinaryHeader(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tensors := make(map[string][]T, len(hdr.Tensors))
+	for _, t := range hdr.Tensors {
+		if _, ok := tensors[t.Name]; ok {
+			return nil, nil, nil, fmt.Errorf("Tensor %s ist mehrfach enthalten", t.Name)
+		}
+		tensors[t.Name] = readTensor[T](r, hdr.DType, t.len())
+	}
+	shapes := make(map[string][]int, len(hdr.Tensors))
+	for _, t := range hdr.Tensors {
+		shapes[t.Name] = t.Shape
+	}
+
+	if hdr.Norms != nil && len(hdr.Norms) != len(hdr.Activations) {
+		return nil, nil, nil, fmt.Errorf("Header beschreibt %d Normalisierungen für %d Schichten", len(hdr.Norms), len(hdr.Activations))
+	}
+
+	// matrix liefert die Tensoren <prefix>.W als Zeilen und <prefix>.b.
+	matrix := func(layer, prefix string) ([][]T, []T, error) {
+		wName, bName := prefix+".W", prefix+".b"
+		w, b := shapes[wName], shapes[bName]
+		if w == nil || b == nil {
+			return nil, nil, fmt.Errorf("%s: Tensor %s oder %s fehlt", layer, wName, bName)
+		}
+		if len(w) != 2 || len(b) != 1 {
+			return nil, nil, fmt.Errorf("%s: Tensoren %s %v und %s %v haben die falsche Dimension", layer, wName, w, bName, b)
+		}
+		rows := make([][]T, w[0])
+		data := tensors[wName]
+		for j := range rows {
+			rows[j] = data[j*w[1] : (j+1)*w[1]]
+		}
+		return rows, tensors[bName], nil
+	}
+
+	modelData := &modelFile[T]{Metadata: hdr.Metadata, Sizes: hdr.Sizes, Input: hdr.Input}
+	for i, spec := range hdr.Conv {
+		c := convFile[T]{LayerSpec: spec}
+		if spec.Kind == mlp.Conv2D {
+			if c.W, c.B, err = matrix(fmt.Sprintf("Faltungsschicht %d", i), fmt.Sprintf("conv%d", i)); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		modelData.Conv = append(modelData.Conv, c)
+	}
+	for i, act := range hdr.Activations {
+		w, b, err := matrix(fmt.Sprintf("Schicht %d", i), strconv.Itoa(i))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		l := layerFile[T]{Activation: act, W: w, B: b}
+
+		if hdr.Norms != nil && hdr.Norms[i].Kind != "" {
+			n := hdr.Norms[i]
+			l.Norm = &normFile[T]{Kind: n.Kind, Momentum: n.Momentum, Epsilon: n.Epsilon}
+			named := map[string]*[]T{"gamma": &l.Norm.Gamma, "beta": &l.Norm.Beta, "running_mean": &l.Norm.RunMean, "running_var": &l.Norm.RunVar}
+			for _, name := range normTensors(n.Kind) {
+				t, ok := tensors[fmt.Sprintf("%d.%s", i, name)]
+				if !ok {
+					return nil, nil, nil, fmt.Errorf("Schicht %d: Tensor %d.%s fehlt", i, i, name)
+				}
+				*named[name] = t
+			}
+		}
+		modelData.Layers = append(modelData.Layers, l)
+	}
+	return modelData, hdr, tensors, nil
+}
+
+// readBinaryHeader prüft Kennung, Version und Prüfsummen von data und liefert
+// den Header sowie einen Reader, der auf den Tensoren steht.
+func readBinaryHeader(data []byte) (*binaryHeader, *bytes.Reader, error) {
+	if !isBinary(data) {
+		return nil, nil, fmt.Errorf("keine binäre Modelldatei")
+	}
+	if len(data) < len(binaryMagic)+8+footerSize {
+		return nil, nil, fmt.Errorf("Modelldatei ist zu kurz (%d Byte)", len(data))
+	}
+
+	body, footer := data[:len(data)-footerSize], data[len(data)-footerSize:]
+	if crc := binary.LittleEndian.Uint32(footer); crc != crc32.ChecksumIEEE(body) {
+		return nil, nil, fmt.Errorf("CRC-32-Prüfsumme stimmt nicht, die Datei ist beschädigt")
+	}
+	if sum := sha256.Sum256(body); !bytes.Equal(sum[:], footer[4:]) {
+		return nil, nil, fmt.Errorf("SHA-256-Prüfsumme stimmt nicht, die Datei ist beschädigt")
+	}
+
+	r := bytes.NewReader(data[len(binaryMagic):])
+	var version, hlen uint32
+	binary.Read(r, binary.LittleEndian, &version)
+	binary.Read(r, binary.LittleEndian, &hlen)
+	if version == 0 || version > binaryVersion {
+		return nil, nil, fmt.Errorf("Version %d des Binärformats wird nicht unterstützt (höchstens %d)", version, binaryVersion)
+	}
+	if int(hlen) > r.Len()-footerSize {
+		return nil, nil, fmt.Errorf("Header mit %d Byte ist länger als die Datei", hlen)
+	}
+
+	header := make([]byte, hlen)
+	io.ReadFull(r, header)
+	var hdr binaryHeader
+	if err := json.Unmarshal(header, &hdr); err != nil {
+		return nil, nil, fmt.Errorf("Fehler beim Deserialisieren des Headers: %v", err)
+	}
+	if err := mlp.CheckPrecision(hdr.DType); err != nil {
+		return nil, nil, err
+	}
+
+	// Die Tensoren müssen den Rest der Datei bis zum Footer genau ausfüllen.
+	// Jede Größe wird vor der Multiplikation mit dem Rest verglichen, damit
+	// eine manipulierte Form nicht überläuft.
+	rest, total := r.Len()-footerSize, 0
+	for _, t := range hdr.Tensors {
+		size := dtypeSize(hdr.DType)
+		for _, d := range t.Shape {
+			if d < 0 {
+				return nil, nil, fmt.Errorf("Tensor %s hat die ungültige Form %v", t.Name, t.Shape)
+			}
+			if d > 0 && size > (rest-total)/d {
+				return nil, nil, fmt.Errorf("Tensor %s mit der Form %v ist größer als die Datei", t.Name, t.Shape)
+			}
+			size *= d
+		}
+		total += size
+	}
+	if total != rest {
+		return nil, nil, fmt.Errorf("Tensoren laut Header %d Byte, Datei enthält %d Byte", total, rest)
+	}
+	if hdr.Metadata != nil && hdr.Metadata.Precision == "" {
+		hdr.Metadata.Precision = hdr.DType
+	}
+	return &hdr, r, nil
+}
+
+// writeTensor schreibt x little-endian in der Genauigkeit T nach buf.
+func writeTensor[T mlp.Float](buf *bytes.Buffer, x []T) {
+	var b [8]byte
+	single := mlp.Precision[T]() == mlp.Float32
+	for _, v := range x {
+		if single {
+			binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v)))
+			buf.Write(b[:4])
+		} else {
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(float64(v)))
+			buf.Write(b[:8])
+		}
+	}
+}
+
+// dtypeSize liefert die Größe eines Werts vom Typ dtype in Byte.
+func dtypeSize(dtype string) int {
+	if dtype == mlp.Float32 {
+		return 4
+	}
+	return 8
+}
+
+// readTensor liest n Werte vom Typ dtype aus r und wandelt sie in T um.
+// readBinaryHeader hat bereits geprüft, dass die Datei lang genug ist.
+func readTensor[T mlp.Float](r *bytes.Reader, dtype string, n int) []T {
+	size := dtypeSize(dtype)
+	x := make([]T, n)
+	var b [8]byte
+	for i := range x {
+		r.Read(b[:size])
+		if size == 4 {
+			x[i] = T(math.Float32frombits(binary.LittleEndian.Uint32(b[:])))
+		} else {
+			x[i] = T(math.Float64frombits(binary.LittleEndian.Uint64(b[:])))
+		}
+	}
+	return x
+}
@@ -0,0 +1,202 @@
+package modelio
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// Checkpoint ist ein Zwischenstand des Trainings, aus dem es bitgenau
+// fortgesetzt werden kann.
+type Checkpoint[T mlp.Float] struct {
+	Model     *mlp.MLP[T]
+	Metadata  *Metadata
+	Optimizer mlp.OptimizerState
+	// Progress ist der Stand des Trainings inklusive der Kennzahlen aller
+	// abgeschlossenen Epochen.
+	Progress mlp.Progress
+	// RNG ist der Zustand der Zufallsquelle (mlp.Source.MarshalBinary).
+	RNG []byte
+	// Schedule ist der Zustand eines mlp.MetricSchedule, sonst nil.
+	Schedule mlp.ScheduleState
+}
+
+// NewCheckpoint liefert den aktuellen Trainingszustand von t mit den
+// Metadaten meta. src ist die Zufallsquelle von t.Rand.
+func NewCheckpoint[T mlp.Float](t *mlp.Trainer[T], meta *Metadata, src *mlp.Source) *Checkpoint[T] {
+	c := &Checkpoint[T]{
+		Model:     t.Model,
+		Metadata:  meta,
+		Optimizer: t.Optimizer.State(),
+		Progress:  t.Progress,
+	}
+	c.RNG, _ = src.MarshalBinary()
+	if ms, ok := t.Schedule.(mlp.MetricSchedule); ok {
+		c.Schedule = ms.State()
+	}
+	return c
+}
+
+// Restore stellt Zufallsquelle, Optimizer und Schedule aus c wieder her. Das
+// Modell und den Trainingsstand übernimmt der Aufrufer aus c.Model und
+// c.Progress.
+func (c *Checkpoint[T]) Restore(src *mlp.Source, optimizer mlp.Optimizer[T], schedule mlp.Schedule) error {
+	if err := src.UnmarshalBinary(c.RNG); err != nil {
+		return fmt.Errorf("Zustand der Zufallsquelle: %v", err)
+	}
+	if err := optimizer.SetState(c.Optimizer); err != nil {
+		return err
+	}
+	if ms, ok := schedule.(mlp.MetricSchedule); ok {
+		return ms.SetState(c.Schedule)
+	}
+	return nil
+}
+
+// checkpointFile beschreibt das JSON-Format eines Checkpoints.
+type checkpointFile[T mlp.Float] struct {
+	Optimizer mlp.OptimizerState `json:"optimizer"`
+	Progress  mlp.Progress       `json:"progress"`
+	RNG       []byte             `json:"rng"`
+	Schedule  mlp.ScheduleState  `json:"schedule,omitempty"`
+	Model     *modelFile[T]      `json:"model"`
+}
+
+// checkpointHeader ist der Trainingszustand im Header eines binären
+// Checkpoints. Die Slots des Optimizers stehen als Tensoren
+// "optimizer.<slot>.<i>" im Datenteil, einer je Parameter.
+type checkpointHeader struct {
+	Optimizer string            `json:"optimizer"`
+	Steps     int               `json:"steps"`
+	Slots     []string          `json:"slots,omitempty"`
+	Progress  mlp.Progress      `json:"progress"`
+	RNG       []byte            `json:"rng"`
+	Schedule  mlp.ScheduleState `json:"schedule,omitempty"`
+}
+
+// SaveCheckpoint speichert Modell, Optimizer-Zustand und Trainingsstand. Wie
+// bei Save wird bei der Endung .json eine JSON-Datei geschrieben, sonst das
+// Binärformat, in dem auch die Zustände des Optimizers in der Genauigkeit T
+// stehen. Die Datei wird erst nach dem vollständigen Schreiben ersetzt.
+func SaveCheckpoint[T mlp.Float](filename string, c *Checkpoint[T]) error {
+	modelData := encodeModel(c.Model, c.Metadata)
+	if isJSONFile(filename) {
+		return writeJSON(filename, checkpointFile[T]{
+			Optimizer: c.Optimizer,
+			Progress:  c.Progress,
+			RNG:       c.RNG,
+			Schedule:  c.Schedule,
+			Model:     modelData,
+		})
+	}
+
+	hdr := newBinaryHeader(modelData)
+	hdr.Checkpoint = &checkpointHeader{
+		Optimizer: c.Optimizer.Name,
+		Steps:     c.Optimizer.Steps,
+		Progress:  c.Progress,
+		RNG:       c.RNG,
+		Schedule:  c.Schedule,
+	}
+	infos, tensors := modelTensors(modelData)
+	for name := range c.Optimizer.Slots {
+		hdr.Checkpoint.Slots = append(hdr.Checkpoint.Slots, name)
+	}
+	sort.Strings(hdr.Checkpoint.Slots)
+	for _, name := range hdr.Checkpoint.Slots {
+		for i, v := range c.Optimizer.Slots[name] {
+			infos = append(infos, tensorInfo{Name: slotTensor(name, i), Shape: []int{len(v)}})
+			tensors = append(tensors, mlp.Convert[T](v))
+		}
+	}
+	hdr.Tensors = infos
+
+	data, err := writeBinary(hdr, tensors)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, data)
+}
+
+// LoadCheckpoint lädt einen mit SaveCheckpoint gespeicherten Checkpoint in der
+// Genauigkeit T.
+func LoadCheckpoint[T mlp.Float](filename string) (*Checkpoint[T], error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Lesen der Datei: %v", err)
+	}
+	if !isBinary(data) {
+		return loadJSONCheckpoint[T](filename)
+	}
+
+	modelData, hdr, tensors, err := decodeBinary[T](data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if hdr.Checkpoint == nil {
+		return nil, fmt.Errorf("%s ist ein Modell, kein Checkpoint", filename)
+	}
+	m, err := decodeModel(modelData)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hdr.Checkpoint
+	c := &Checkpoint[T]{
+		Model:     m,
+		Metadata:  modelData.Metadata,
+		Optimizer: mlp.OptimizerState{Name: h.Optimizer, Steps: h.Steps},
+		Progress:  h.Progress,
+		RNG:       h.RNG,
+		Schedule:  h.Schedule,
+	}
+	if len(h.Slots) > 0 {
+		c.Optimizer.Slots = make(map[string][][]float64, len(h.Slots))
+	}
+	for _, name := range h.Slots {
+		var v [][]float64
+		for i := 0; ; i++ {
+			t, ok := tensors[slotTensor(name, i)]
+			if !ok {
+				break
+			}
+			v = append(v, mlp.Convert[float64](t))
+		}
+		if len(v) == 0 {
+			return nil, fmt.Errorf("%s: Tensoren des Optimizer-Zustands %q fehlen", filename, name)
+		}
+		c.Optimizer.Slots[name] = v
+	}
+	return c, nil
+}
+
+// loadJSONCheckpoint lädt einen als JSON gespeicherten Checkpoint.
+func loadJSONCheckpoint[T mlp.Float](filename string) (*Checkpoint[T], error) {
+	var data checkpointFile[T]
+	if err := readJSON(filename, &data); err != nil {
+		return nil, err
+	}
+	if data.Model == nil {
+		return nil, fmt.Errorf("Checkpoint enthält kein Modell")
+	}
+	m, err := decodeModel(data.Model)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint[T]{
+		Model:     m,
+		Metadata:  data.Model.Metadata,
+		Optimizer: data.Optimizer,
+		Progress:  data.Progress,
+		RNG:       data.RNG,
+		Schedule:  data.Schedule,
+	}, nil
+}
+
+// slotTensor liefert den Namen des Tensors für den Zustand name des i-ten
+// Parameters.
+func slotTensor(name string, i int) string {
+	return fmt.Sprintf("optimizer.%s.%d", name, i)
+}
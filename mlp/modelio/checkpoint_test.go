@@ -0,0 +1,153 @@
+package modelio
+
+import (
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// syntheticData erzeugt n zufällige Beispiele mit dim Merkmalen und classes
+// Klassen; die Klasse ist das größte der ersten classes Merkmale.
+func syntheticData[T mlp.Float](n, dim, classes int, seed int64) mlp.Slices[T] {
+	rng := rand.New(rand.NewSource(seed))
+	var d mlp.Slices[T]
+	for i := 0; i < n; i++ {
+		x := make([]T, dim)
+		for j := range x {
+			x[j] = T(rng.Float64())
+		}
+		y := make([]T, classes)
+		y[slices.Index(x, slices.Max(x[:classes]))] = 1
+		d.X = append(d.X, x)
+		d.Y = append(d.Y, y)
+	}
+	return d
+}
+
+// stopAt ist ein ReduceOnPlateau, der stop schließt, sobald die Lernrate für
+// einen Zeitpunkt ab at abgefragt wird.
+type stopAt struct {
+	*mlp.ReduceOnPlateau
+	at   float64
+	stop chan struct{}
+}
+
+func (s *stopAt) LR(epoch float64) float64 {
+	if epoch >= s.at && s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	return s.ReduceOnPlateau.LR(epoch)
+}
+
+// resumeRun enthält die Bestandteile eines Trainingslaufs wie in cmd/train:
+// eine einzige Zufallsquelle für Initialisierung und Training, den Optimizer
+// und den Schedule.
+type resumeRun[T mlp.Float] struct {
+	src     *mlp.Source
+	trainer *mlp.Trainer[T]
+}
+
+// newResumeRun erzeugt einen Lauf mit Adam, Dropout, BatchNorm und einem
+// ReduceOnPlateau, dessen Zustand sich im Laufe des Trainings ändert. Ist
+// stop nicht nil, wird das Training in der Mitte der zweiten Epoche
+// unterbrochen.
+func newResumeRun[T mlp.Float](t *testing.T, stop chan struct{}) *resumeRun[T] {
+	t.Helper()
+	src := mlp.NewSource(3)
+	rng := rand.New(src)
+	m := mlp.NewMLP[T](rng, []int{12, 16, 4})
+	if err := m.AddNorm(mlp.BatchNorm); err != nil {
+		t.Fatal(err)
+	}
+	opt, err := mlp.NewOptimizer[T]("adam", mlp.DefaultOptimizerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sched mlp.Schedule = &mlp.ReduceOnPlateau{Rate: 0.01, Factor: 0.5, Patience: 0, MinDelta: 0.5}
+	if stop != nil {
+		sched = &stopAt{sched.(*mlp.ReduceOnPlateau), 1.5, stop}
+	}
+	return &resumeRun[T]{src: src, trainer: &mlp.Trainer[T]{
+		Model:          m,
+		Epochs:         4,
+		BatchSize:      40,
+		Schedule:       sched,
+		Optimizer:      opt,
+		Rand:           rng,
+		Stop:           stop,
+		Regularization: mlp.Regularization{Dropout: 0.2},
+	}}
+}
+
+// testResume unterbricht ein Training, speichert einen Checkpoint in einer
+// Datei mit der Endung ext und setzt es wie train -resume daraus fort. Das
+// Ergebnis muss bitgenau dem eines durchgehenden Laufs entsprechen.
+func testResume[T mlp.Float](t *testing.T, ext string) {
+	train := syntheticData[T](200, 12, 4, 1)
+	val := mlp.Slices[T]{X: train.X[:50], Y: train.Y[:50]}
+
+	want := newResumeRun[T](t, nil).trainer
+	if err := want.Run(train, val); err != nil {
+		t.Fatal(err)
+	}
+
+	first := newResumeRun[T](t, make(chan struct{}))
+	if err := first.trainer.Run(train, val); err != mlp.ErrStopped {
+		t.Fatalf("Run liefert %v statt ErrStopped", err)
+	}
+	file := filepath.Join(t.TempDir(), "checkpoint"+ext)
+	if err := SaveCheckpoint(file, NewCheckpoint(first.trainer, &Metadata{Seed: 3}, first.src)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neuer Lauf mit frisch initialisiertem Modell, Optimizer und Schedule,
+	// dessen Zustand vollständig aus dem Checkpoint stammt.
+	second := newResumeRun[T](t, nil)
+	ckpt, err := LoadCheckpoint[T](file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ckpt.Restore(second.src, second.trainer.Optimizer, second.trainer.Schedule); err != nil {
+		t.Fatal(err)
+	}
+	second.trainer.Model = ckpt.Model
+	second.trainer.Progress = ckpt.Progress
+	if ckpt.Metadata.Seed != 3 || ckpt.Progress.Epoch != 1 || ckpt.Progress.Batch == 0 {
+		t.Fatalf("Checkpoint mit Metadaten %+v bei Epoche %d, Batch %d", ckpt.Metadata, ckpt.Progress.Epoch, ckpt.Progress.Batch)
+	}
+	if err := second.trainer.Run(train, val); err != nil {
+		t.Fatal(err)
+	}
+
+	equalModels(t, want.Model, second.trainer.Model)
+	got := second.trainer.Progress.History
+	if len(got) != len(want.Progress.History) {
+		t.Fatalf("%d statt %d Epochen im Verlauf", len(got), len(want.Progress.History))
+	}
+	for i, s := range want.Progress.History {
+		if got[i] != s {
+			t.Errorf("Epoche %d: %+v statt %+v", i, got[i], s)
+		}
+	}
+}
+
+func TestResumeIsBitExact(t *testing.T) {
+	t.Run("float64.bin", func(t *testing.T) { testResume[float64](t, ".bin") })
+	t.Run("float64.json", func(t *testing.T) { testResume[float64](t, ".json") })
+	t.Run("float32.bin", func(t *testing.T) { testResume[float32](t, ".bin") })
+	t.Run("float32.json", func(t *testing.T) { testResume[float32](t, ".json") })
+}
+
+func TestLoadCheckpointRejectsModel(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "model.bin")
+	if err := Save(file, mlp.NewMLP[float64](rand.New(rand.NewSource(1)), []int{4, 3}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint[float64](file); err == nil {
+		t.Error("Modelldatei als Checkpoint geladen")
+	}
+}
@@ -0,0 +1,177 @@
+package modelio
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// Metadata beschreibt ein Modell so vollständig, dass es ohne weiteres Wissen
+// verwendet werden kann: Architektur, erwartete Eingabe, Klassen und wie es
+// entstanden ist. Beim Laden werden die Angaben mit den Gewichten verglichen.
+type Metadata struct {
+	// Seed des Zufallsgenerators, mit dem das Modell trainiert wurde.
+	Seed int64 `json:"seed,omitempty"`
+	// Precision ist die Genauigkeit, in der das Modell trainiert und
+	// gespeichert wurde (mlp.Float32 oder mlp.Float64). Modelle ohne Angabe
+	// stammen aus der Zeit vor float32 und gelten als mlp.Float64.
+	Precision string `json:"precision,omitempty"`
+
+	// Architecture ist die Anzahl der Neuronen je Schicht inklusive Eingabe,
+	// Activations die Aktivierung jeder Schicht. Save trägt beide aus dem
+	// Modell ein.
+	Architecture []int    `json:"architecture,omitempty"`
+	Activations  []string `json:"activations,omitempty"`
+	// Norms ist die Normalisierungsschicht (mlp.BatchNorm, mlp.LayerNorm oder
+	// "") jeder Schicht, nil, wenn keine Schicht normalisiert wird.
+	Norms []string `json:"norms,omitempty"`
+	// Conv beschreibt den Faltungsteil vor den Dense-Schichten, nil ohne.
+	// Architecture beginnt auch dann mit der Eingabe des Netzes.
+	Conv []mlp.LayerSpec `json:"conv,omitempty"`
+	// InputShape ist die Form einer Eingabe, z. B. [28, 28] für MNIST. Das
+	// Produkt muss der Anzahl der Eingabeneuronen entsprechen.
+	InputShape []int `json:"input_shape,omitempty"`
+	// Normalization beschreibt, wie Pixel in Eingabewerte umgerechnet werden.
+	Normalization *Normalization `json:"normalization,omitempty"`
+	// Classes sind die Namen der Klassen in der Reihenfolge der Ausgabeneuronen.
+	Classes []string `json:"classes,omitempty"`
+	// Dataset ist der Name des Datensatzes, auf dem das Modell trainiert
+	// wurde, z. B. mnist oder fashion-mnist.
+	Dataset string `json:"dataset,omitempty"`
+
+	// Hyperparameters ist die vollständige Konfiguration des Trainingslaufs.
+	Hyperparameters json.RawMessage `json:"hyperparameters,omitempty"`
+	// Regularization ist die Regularisierung im Training. Dropout wirkt nur
+	// im Training; bei der Inferenz wird das Modell unverändert ausgewertet.
+	Regularization *mlp.Regularization `json:"regularization,omitempty"`
+	// TrainAccuracy und ValidationAccuracy sind die Genauigkeiten in der Epoche,
+	// aus der das Modell stammt, TestAccuracy die einmalig am Ende des
+	// Trainings gemessene Genauigkeit auf den Testdaten (jeweils 0 bis 1).
+	TrainAccuracy      float64 `json:"train_accuracy,omitempty"`
+	ValidationAccuracy float64 `json:"validation_accuracy,omitempty"`
+	TestAccuracy       float64 `json:"test_accuracy,omitempty"`
+	// DatasetHash ist der SHA-256-Hash der Trainings- und Testdaten.
+	DatasetHash string `json:"dataset_hash,omitempty"`
+	// CreatedAt ist der Zeitpunkt der Erstellung im Format RFC 3339.
+	CreatedAt string `json:"created_at,omitempty"`
+	// GoVersion ist die Go-Version, mit der das Modell trainiert wurde.
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// Normalization beschreibt die Vorverarbeitung eines Pixelwerts p zu
+// x = (p*Scale - Mean) / Std.
+type Normalization struct {
+	Scale float64 `json:"scale"`
+	Mean  float64 `json:"mean"`
+	Std   float64 `json:"std"`
+}
+
+// DefaultNormalization ist die Normalisierung von Modellen ohne Angabe in den
+// Metadaten: Pixel werden wie beim Laden der Datensätze auf [0,1] skaliert.
+var DefaultNormalization = Normalization{Scale: 1.0 / 255, Mean: 0, Std: 1}
+
+// Normalize rechnet Grauwerte von 0 bis 255 mit der Normalisierung des Modells
+// (ohne Angabe DefaultNormalization) in Eingabewerte um.
+func (m *Metadata) Normalize(pixels []float64) []float64 {
+	n := DefaultNormalization
+	if m.Normalization != nil {
+		n = *m.Normalization
+	}
+	x := make([]float64, len(pixels))
+	for i, p := range pixels {
+		x[i] = (p*n.Scale - n.Mean) / n.Std
+	}
+	return x
+}
+
+// NewMetadata liefert Metadaten mit dem aktuellen Zeitpunkt und der Go-Version.
+func NewMetadata() *Metadata {
+	return &Metadata{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// ClassName liefert den Namen der Klasse i oder, ohne Klassennamen in den
+// Metadaten, i als Zahl.
+func (m *Metadata) ClassName(i int) string {
+	if i >= 0 && i < len(m.Classes) {
+		return m.Classes[i]
+	}
+	return strconv.Itoa(i)
+}
+
+// metadataOf liefert die Metadaten meta einer Modelldatei, nie nil. Fehlt die
+// Genauigkeit, wird mlp.Float64 eingetragen.
+func metadataOf(meta *Metadata) *Metadata {
+	if meta == nil {
+		meta = &Metadata{}
+	}
+	if meta.Precision == "" {
+		meta.Precision = mlp.Float64
+	}
+	return meta
+}
+
+// checkMetadata prüft, ob die Angaben in meta zum Modell m passen. Fehlende
+// Angaben werden nicht geprüft.
+func checkMetadata[T mlp.Float](meta *Metadata, m *mlp.MLP[T]) error {
+	if meta.Precision != "" {
+		if err := mlp.CheckPrecision(meta.Precision); err != nil {
+			return fmt.Errorf("Metadaten: %v", err)
+		}
+	}
+
+	sizes := m.Sizes()
+	if meta.Architecture != nil && !slices.Equal(meta.Architecture, sizes) {
+		return fmt.Errorf("Metadaten: Architektur %v passt nicht zu den Gewichten %v", meta.Architecture, sizes)
+	}
+	if meta.Activations != nil {
+		if len(meta.Activations) != len(m.Layers) {
+			return fmt.Errorf("Metadaten: %d Aktivierungen für %d Schichten", len(meta.Activations), len(m.Layers))
+		}
+		for i, l := range m.Layers {
+			if meta.Activations[i] != l.Act.Name() {
+				return fmt.Errorf("Metadaten: Schicht %d hat Aktivierung %q, die Gewichte gehören zu %q", i, meta.Activations[i], l.Act.Name())
+			}
+		}
+	}
+
+	if meta.Norms != nil {
+		norms := m.Norms()
+		if len(meta.Norms) != len(norms) {
+			return fmt.Errorf("Metadaten: %d Normalisierungen für %d Schichten", len(meta.Norms), len(norms))
+		}
+		for i, kind := range norms {
+			if meta.Norms[i] != kind {
+				return fmt.Errorf("Metadaten: Schicht %d hat Normalisierung %q, die Gewichte gehören zu %q", i, meta.Norms[i], kind)
+			}
+		}
+	}
+
+	if meta.Conv != nil && !slices.Equal(meta.Conv, m.ConvSpecs()) {
+		return fmt.Errorf("Metadaten: Faltungsteil %v passt nicht zu den Gewichten %v", meta.Conv, m.ConvSpecs())
+	}
+
+	if meta.InputShape != nil {
+		n := 1
+		for _, d := range meta.InputShape {
+			n *= d
+		}
+		if n != sizes[0] {
+			return fmt.Errorf("Metadaten: Eingabeform %v hat %d Werte, die Eingabeschicht %d Neuronen", meta.InputShape, n, sizes[0])
+		}
+	}
+	if out := sizes[len(sizes)-1]; meta.Classes != nil && len(meta.Classes) != out {
+		return fmt.Errorf("Metadaten: %d Klassen, die Ausgabeschicht hat %d Neuronen", len(meta.Classes), out)
+	}
+	if n := meta.Normalization; n != nil && (n.Scale == 0 || n.Std == 0) {
+		return fmt.Errorf("Metadaten: ungültige Normalisierung %+v", *n)
+	}
+	return nil
+}
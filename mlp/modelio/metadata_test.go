@@ -0,0 +1,96 @@
+package modelio
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+func TestMetadataMismatch(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*Metadata)
+	}{
+		{"Architektur", func(m *Metadata) { m.Architecture = []int{36, 5, 4, 4} }},
+		{"Anzahl Aktivierungen", func(m *Metadata) { m.Activations = m.Activations[:2] }},
+		{"Aktivierung", func(m *Metadata) { m.Activations[2] = "relu" }},
+		{"Normalisierungen", func(m *Metadata) { m.Norms = []string{mlp.LayerNorm, mlp.LayerNorm, ""} }},
+		{"Anzahl Normalisierungen", func(m *Metadata) { m.Norms = m.Norms[:1] }},
+		{"Faltungsteil", func(m *Metadata) { m.Conv[0].Filters = 3 }},
+		{"Klassen", func(m *Metadata) { m.Classes = []string{"a", "b"} }},
+		{"Eingabeform", func(m *Metadata) { m.InputShape = []int{5, 5} }},
+		{"Pixelnormalisierung", func(m *Metadata) { m.Normalization = &Normalization{Scale: 1.0 / 255, Std: 0} }},
+		{"Genauigkeit", func(m *Metadata) { m.Precision = "float16" }},
+	} {
+		for _, ext := range []string{".bin", ".json"} {
+			t.Run(tc.name+ext, func(t *testing.T) {
+				modelData := encodeModel(testModel[float64](t), &Metadata{Classes: []string{"a", "b", "c"}, InputShape: []int{6, 6}})
+				tc.modify(modelData.Metadata)
+				file := filepath.Join(t.TempDir(), "model"+ext)
+				var err error
+				if ext == ".json" {
+					err = writeJSON(file, modelData)
+				} else {
+					var data []byte
+					if data, err = encodeBinary(modelData); err == nil {
+						err = writeFile(file, data)
+					}
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _, err = Load[float64](file)
+				if err == nil || !strings.Contains(err.Error(), "Metadaten") {
+					t.Errorf("Fehler %v, erwartet einen Fehler in den Metadaten", err)
+				}
+			})
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	pixels := []float64{0, 51, 255}
+	for _, tc := range []struct {
+		norm *Normalization
+		want []float64
+	}{
+		{nil, []float64{0, 0.2, 1}},
+		{&Normalization{Scale: 1.0 / 255, Mean: 0.5, Std: 0.25}, []float64{-2, -1.2, 2}},
+	} {
+		meta := &Metadata{Normalization: tc.norm}
+		for i, x := range meta.Normalize(pixels) {
+			if math.Abs(x-tc.want[i]) > 1e-12 {
+				t.Errorf("Normalisierung %+v: %v, erwartet %v", tc.norm, meta.Normalize(pixels), tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPredictorNormalizes(t *testing.T) {
+	m := testModel[float32](t)
+	norm := &Normalization{Scale: 1.0 / 255, Mean: 0.1307, Std: 0.3081}
+	file := filepath.Join(t.TempDir(), "model.bin")
+	if err := Save(file, m, &Metadata{Normalization: norm}); err != nil {
+		t.Fatal(err)
+	}
+	predict, meta, err := LoadPredictor(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *meta.Normalization != *norm {
+		t.Fatalf("Normalisierung %+v, erwartet %+v", meta.Normalization, norm)
+	}
+	for k := 0; k < 20; k++ {
+		pixels := make([]float64, 36)
+		for i := range pixels {
+			pixels[i] = float64((i*37 + k*101) % 256)
+		}
+		if got, want := predict(pixels), m.Predict(mlp.Convert[float32](meta.Normalize(pixels))); got != want {
+			t.Errorf("Bild %d: Klasse %d, erwartet %d", k, got, want)
+		}
+	}
+}
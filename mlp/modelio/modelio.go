@@ -0,0 +1,423 @@
+// Package modelio speichert und lädt die Parameter eines mlp.MLP sowie
+// Checkpoints des Trainings. Modelle werden im Binärformat (siehe binary.go)
+// oder als JSON gespeichert; beim Laden wird das Format automatisch erkannt.
+package modelio
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// layerFile beschreibt eine Schicht in der Modelldatei.
+type layerFile[T mlp.Float] struct {
+	Activation string       `json:"activation,omitempty"`
+	W          [][]T        `json:"W"`
+	B          []T          `json:"b"`
+	Norm       *normFile[T] `json:"norm,omitempty"`
+}
+
+// normFile beschreibt die Normalisierung einer Schicht in der Modelldatei.
+type normFile[T mlp.Float] struct {
+	Kind     string  `json:"kind"`
+	Gamma    []T     `json:"gamma"`
+	Beta     []T     `json:"beta"`
+	RunMean  []T     `json:"running_mean,omitempty"`
+	RunVar   []T     `json:"running_var,omitempty"`
+	Momentum float64 `json:"momentum"`
+	Epsilon  float64 `json:"epsilon"`
+}
+
+// convFile beschreibt eine Schicht des Faltungsteils in der Modelldatei; W
+// und B nur bei Conv2D.
+type convFile[T mlp.Float] struct {
+	mlp.LayerSpec
+	W [][]T `json:"W,omitempty"`
+	B []T   `json:"b,omitempty"`
+}
+
+// modelFile beschreibt das JSON-Format der Modelldatei. Ältere Modelle mit genau
+// einer versteckten Schicht wurden mit den Feldern W1, b1, W2 und b2 gespeichert;
+// sie werden beim Laden weiterhin unterstützt. Fehlt die Aktivierung einer
+// Schicht, gilt wie früher ReLU bzw. Softmax für die Ausgabeschicht.
+//
+// Die Parameter werden in der Genauigkeit T des Modells geschrieben; da JSON
+// nur Dezimalzahlen kennt, kann jede Datei in jeder Genauigkeit gelesen werden.
+type modelFile[T mlp.Float] struct {
+	Metadata *Metadata `json:"metadata,omitempty"`
+	Sizes    []int     `json:"sizes,omitempty"`
+	// Input ist die Form der Eingabe des Faltungsteils Conv, nil ohne.
+	Input  *mlp.Shape     `json:"input,omitempty"`
+	Conv   []convFile[T]  `json:"conv,omitempty"`
+	Layers []layerFile[T] `json:"layers,omitempty"`
+
+	W1 [][]T `json:"W1,omitempty"`
+	B1 []T   `json:"b1,omitempty"`
+	W2 [][]T `json:"W2,omitempty"`
+	B2 []T   `json:"b2,omitempty"`
+}
+
+// Save speichert die Metadaten, die Architektur, die Aktivierungen und die
+// Parameter aller Schichten. Endet filename auf .json, wird wie früher eine
+// JSON-Datei geschrieben, sonst das kompakte Binärformat mit Prüfsummen. Die
+// Genauigkeit T des Modells wird in den Metadaten vermerkt.
+// filename: Pfad zur Zieldatei.
+func Save[T mlp.Float](filename string, m *mlp.MLP[T], meta *Metadata) error {
+	modelData := encodeModel(m, meta)
+	if isJSONFile(filename) {
+		return writeJSON(filename, modelData)
+	}
+	data, err := encodeBinary(modelData)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, data)
+}
+
+// Load lädt ein mit Save gespeichertes Modell beliebiger Tiefe in der
+// Genauigkeit T, unabhängig davon, in welcher Genauigkeit es gespeichert wurde,
+// und prüft, ob die Dimensionen der Parameter zueinander passen. Das Format
+// (binär oder JSON) wird am Dateianfang erkannt; bei Binärdateien werden die
+// Prüfsummen kontrolliert. Enthält die Datei keine Metadaten, sind bis auf
+// Precision alle Felder der gelieferten Metadaten leer.
+func Load[T mlp.Float](filename string) (*mlp.MLP[T], *Metadata, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Fehler beim Lesen der Datei: %v", err)
+	}
+
+	var modelData *modelFile[T]
+	if isBinary(data) {
+		if modelData, _, _, err = decodeBinary[T](data); err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	} else {
+		modelData = new(modelFile[T])
+		if err := json.Unmarshal(data, modelData); err != nil {
+			return nil, nil, fmt.Errorf("Fehler beim Deserialisieren der JSON-Daten: %v", err)
+		}
+	}
+
+	m, err := decodeModel(modelData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, modelData.Metadata, nil
+}
+
+// LoadMetadata lädt nur die Metadaten einer mit Save oder SaveCheckpoint
+// gespeicherten Datei, z. B. um das Modell anschließend mit Load in seiner
+// Genauigkeit zu laden.
+func LoadMetadata(filename string) (*Metadata, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Lesen der Datei: %v", err)
+	}
+
+	if isBinary(data) {
+		hdr, _, err := readBinaryHeader(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		return metadataOf(hdr.Metadata), nil
+	}
+
+	// Bei JSON-Checkpoints stehen die Metadaten im Modell.
+	var modelData struct {
+		Metadata *Metadata `json:"metadata"`
+		Model    struct {
+			Metadata *Metadata `json:"metadata"`
+		} `json:"model"`
+	}
+	if err := json.Unmarshal(data, &modelData); err != nil {
+		return nil, fmt.Errorf("Fehler beim Deserialisieren der JSON-Daten: %v", err)
+	}
+	if modelData.Metadata == nil {
+		modelData.Metadata = modelData.Model.Metadata
+	}
+	return metadataOf(modelData.Metadata), nil
+}
+
+// isJSONFile prüft, ob filename die Endung .json hat.
+func isJSONFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".json")
+}
+
+// Predictor sagt die Klasse eines Bildes aus Grauwerten von 0 bis 255 voraus,
+// unabhängig von der Genauigkeit des Modells.
+type Predictor func(pixels []float64) int
+
+// LoadPredictor lädt ein mit Save gespeichertes Modell in der Genauigkeit, in
+// der es trainiert wurde, z. B. für Inferenzprogramme. Der Predictor rechnet
+// die Grauwerte mit der Normalisierung aus den Metadaten in Eingabewerte um.
+func LoadPredictor(filename string) (Predictor, *Metadata, error) {
+	meta, err := LoadMetadata(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch meta.Precision {
+	case mlp.Float32:
+		return loadPredictor[float32](filename)
+	case mlp.Float64:
+		return loadPredictor[float64](filename)
+	}
+	return nil, nil, mlp.CheckPrecision(meta.Precision)
+}
+
+func loadPredictor[T mlp.Float](filename string) (Predictor, *Metadata, error) {
+	m, meta, err := Load[T](filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(pixels []float64) int { return m.Predict(mlp.Convert[T](meta.Normalize(pixels))) }, meta, nil
+}
+
+// encodeModel wandelt m mit den Metadaten meta in das Dateiformat um.
+func encodeModel[T mlp.Float](m *mlp.MLP[T], meta *Metadata) *modelFile[T] {
+	md := Metadata{}
+	if meta != nil {
+		md = *meta
+	}
+	md.Precision = mlp.Precision[T]()
+	md.Architecture = m.Sizes()
+	md.Activations = nil
+	for _, act := range m.Activations() {
+		md.Activations = append(md.Activations, act.Name())
+	}
+	md.Norms = normsOf(m)
+	md.Conv = m.ConvSpecs()
+
+	modelData := &modelFile[T]{Metadata: &md, Sizes: m.Sizes()}
+	for _, l := range m.Conv {
+		cf := convFile[T]{LayerSpec: l.Spec(), B: l.B}
+		if l.W != nil {
+			cf.W = l.W.ToRows()
+		}
+		modelData.Conv = append(modelData.Conv, cf)
+	}
+	if len(m.Conv) > 0 {
+		modelData.Input = &m.Conv[0].In
+	}
+	for _, l := range m.Layers {
+		lf := layerFile[T]{Activation: l.Act.Name(), W: l.W.ToRows(), B: l.B}
+		if n := l.Norm; n != nil {
+			lf.Norm = &normFile[T]{Kind: n.Kind, Gamma: n.Gamma, Beta: n.Beta, RunMean: n.RunMean, RunVar: n.RunVar, Momentum: n.Momentum, Epsilon: n.Epsilon}
+		}
+		modelData.Layers = append(modelData.Layers, lf)
+	}
+	return modelData
+}
+
+// normsOf liefert die Normalisierungen der Schichten von m für die Metadaten
+// oder nil, wenn keine Schicht normalisiert wird.
+func normsOf[T mlp.Float](m *mlp.MLP[T]) []string {
+	for _, kind := range m.Norms() {
+		if kind != "" {
+			return m.Norms()
+		}
+	}
+	return nil
+}
+
+// decodeNorm erzeugt die Normalisierung einer Schicht mit dim Neuronen aus
+// dem Dateiformat.
+func decodeNorm[T mlp.Float](n *normFile[T], dim int) (*mlp.Norm[T], error) {
+	if err := mlp.CheckNorm(n.Kind); err != nil {
+		return nil, err
+	}
+	if len(n.Gamma) != dim || len(n.Beta) != dim {
+		return nil, fmt.Errorf("%s hat %d/%d statt %d Parameter", n.Kind, len(n.Gamma), len(n.Beta), dim)
+	}
+	if n.Kind == mlp.BatchNorm && (len(n.RunMean) != dim || len(n.RunVar) != dim) {
+		return nil, fmt.Errorf("%s hat %d/%d statt %d gleitende Statistiken", n.Kind, len(n.RunMean), len(n.RunVar), dim)
+	}
+	if n.Epsilon <= 0 {
+		return nil, fmt.Errorf("%s hat ungültiges Epsilon %g", n.Kind, n.Epsilon)
+	}
+	norm := &mlp.Norm[T]{Kind: n.Kind, Gamma: n.Gamma, Beta: n.Beta, Momentum: n.Momentum, Epsilon: n.Epsilon}
+	if n.Kind == mlp.BatchNorm {
+		norm.RunMean, norm.RunVar = n.RunMean, n.RunVar
+	}
+	return norm, nil
+}
+
+// decodeConv erzeugt den Faltungsteil aus dem Dateiformat und prüft die Form
+// der Parameter.
+func decodeConv[T mlp.Float](modelData *modelFile[T]) ([]*mlp.ConvLayer[T], error) {
+	if modelData.Input == nil {
+		return nil, fmt.Errorf("Faltungsteil ohne Eingabeform")
+	}
+	specs := make([]mlp.LayerSpec, len(modelData.Conv))
+	for i, c := range modelData.Conv {
+		specs[i] = c.LayerSpec
+	}
+	conv, err := mlp.NewConvLayers[T](nil, *modelData.Input, specs)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range conv {
+		c := modelData.Conv[i]
+		if l.W == nil {
+			if c.W != nil || c.B != nil {
+				return nil, fmt.Errorf("Faltungsschicht %d: %s hat keine Parameter", i, l.Kind)
+			}
+			continue
+		}
+		name := fmt.Sprintf("Faltungsschicht %d", i)
+		if err := checkShape(name, c.W, len(c.B), l.W.Cols); err != nil {
+			return nil, err
+		}
+		if len(c.B) != l.W.Rows {
+			return nil, fmt.Errorf("%s: %d Filter, erwartet %d", name, len(c.B), l.W.Rows)
+		}
+		w, err := mlp.MatrixFromRows(c.W)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		l.W, l.B = w, c.B
+	}
+	return conv, nil
+}
+
+// decodeModel erzeugt aus dem Dateiformat ein MLP und prüft die Dimensionen
+// sowie die Metadaten. Danach ist modelData.Metadata vollständig ausgefüllt.
+func decodeModel[T mlp.Float](modelData *modelFile[T]) (*mlp.MLP[T], error) {
+	layers := modelData.Layers
+	if layers == nil {
+		if modelData.W1 == nil {
+			return nil, fmt.Errorf("keine Schichten gefunden")
+		}
+		// altes Format mit einer versteckten Schicht
+		layers = []layerFile[T]{{W: modelData.W1, B: modelData.B1}, {W: modelData.W2, B: modelData.B2}}
+	}
+
+	m := &mlp.MLP[T]{}
+	defaults := mlp.DefaultActivations[T](len(layers))
+	inputDim := -1
+	if modelData.Conv != nil {
+		conv, err := decodeConv(modelData)
+		if err != nil {
+			return nil, err
+		}
+		m.Conv = conv
+		inputDim = conv[len(conv)-1].Out.Size()
+	}
+	for i, l := range layers {
+		if err := checkShape(fmt.Sprintf("Schicht %d", i), l.W, len(l.B), inputDim); err != nil {
+			return nil, err
+		}
+		if len(l.B) == 0 {
+			return nil, fmt.Errorf("Schicht %d hat keine Neuronen", i)
+		}
+		act := defaults[i]
+		if l.Activation != "" {
+			var err error
+			if act, err = mlp.ParseActivation[T](l.Activation); err != nil {
+				return nil, fmt.Errorf("Schicht %d: %v", i, err)
+			}
+		}
+		w, err := mlp.MatrixFromRows(l.W)
+		if err != nil {
+			return nil, fmt.Errorf("Schicht %d: %v", i, err)
+		}
+		layer := &mlp.Dense[T]{W: w, B: l.B, Act: act}
+		if l.Norm != nil {
+			if layer.Norm, err = decodeNorm(l.Norm, len(l.B)); err != nil {
+				return nil, fmt.Errorf("Schicht %d: %v", i, err)
+			}
+		}
+		m.Layers = append(m.Layers, layer)
+		inputDim = len(l.B)
+	}
+
+	if modelData.Sizes != nil {
+		sizes := m.Sizes()
+		if fmt.Sprint(sizes) != fmt.Sprint(modelData.Sizes) {
+			return nil, fmt.Errorf("Architektur %v passt nicht zu den Gewichten %v", modelData.Sizes, sizes)
+		}
+	}
+	// ältere Dateien enthalten keine oder unvollständige Metadaten
+	meta := metadataOf(modelData.Metadata)
+	if err := checkMetadata(meta, m); err != nil {
+		return nil, err
+	}
+	if meta.Architecture == nil {
+		meta.Architecture = m.Sizes()
+	}
+	if meta.Activations == nil {
+		for _, act := range m.Activations() {
+			meta.Activations = append(meta.Activations, act.Name())
+		}
+	}
+	if meta.Norms == nil {
+		meta.Norms = normsOf(m)
+	}
+	if meta.Conv == nil {
+		meta.Conv = m.ConvSpecs()
+	}
+	modelData.Metadata = meta
+
+	return m, nil
+}
+
+// writeJSON serialisiert v als JSON nach filename.
+func writeJSON(filename string, v interface{}) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Fehler beim Serialisieren der Modellparameter: %v", err)
+	}
+
+	return writeFile(filename, jsonData)
+}
+
+// writeFile schreibt data zunächst in eine temporäre Datei und benennt sie
+// dann in filename um. So bleibt eine vorhandene Datei (z. B. der letzte
+// Checkpoint) erhalten, wenn das Programm beim Schreiben abbricht.
+func writeFile(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("Fehler beim Schreiben der Datei: %v", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("Fehler beim Schreiben der Datei: %v", err)
+	}
+	return nil
+}
+
+// readJSON liest die JSON-Datei filename nach v.
+func readJSON(filename string, v interface{}) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Datei: %v", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("Fehler beim Deserialisieren der JSON-Daten: %v", err)
+	}
+	return nil
+}
+
+// checkShape prüft, ob w genau rows Zeilen mit jeweils cols Spalten hat.
+// Bei cols < 0 müssen die Zeilen nur gleich lang sein.
+func checkShape[T mlp.Float](name string, w [][]T, rows, cols int) error {
+	if len(w) != rows {
+		return fmt.Errorf("%s: W hat %d Zeilen, b hat Länge %d", name, len(w), rows)
+	}
+	if rows == 0 {
+		return nil
+	}
+	if cols < 0 {
+		cols = len(w[0])
+	}
+	for i, row := range w {
+		if len(row) != cols {
+			return fmt.Errorf("%s: Zeile %d hat Länge %d, erwartet %d", name, i, len(row), cols)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package modelio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"grimm.world/mlp_demo/mlp"
+)
+
+// testModel erzeugt ein kleines Netz mit Faltungsteil, BatchNorm und
+// LayerNorm, dessen Parameter alle zufällig und von 0 verschieden sind.
+func testModel[T mlp.Float](t *testing.T) *mlp.MLP[T] {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	conv := []mlp.LayerSpec{
+		{Kind: mlp.Conv2D, Filters: 2, Size: 3},
+		{Kind: mlp.MaxPool, Size: 2},
+		{Kind: mlp.Flatten},
+	}
+	m, err := mlp.NewConvNet[T](rng, mlp.Shape{C: 1, H: 6, W: 6}, conv, []int{36, 5, 4, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, kind := range []string{mlp.BatchNorm, mlp.LayerNorm} {
+		if m.Layers[i].Norm, err = mlp.NewNorm[T](kind, m.Layers[i].OutputDim()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range values(m) {
+		for i := range v {
+			v[i] = T(rng.NormFloat64())
+		}
+	}
+	return m
+}
+
+// values liefert alle gespeicherten Werte von m: die Parameter und die
+// gleitenden Statistiken von BatchNorm.
+func values[T mlp.Float](m *mlp.MLP[T]) [][]T {
+	var v [][]T
+	for _, p := range m.Params(m.NewGradients()) {
+		v = append(v, p.Value)
+	}
+	for _, l := range m.Layers {
+		if n := l.Norm; n != nil && n.Kind == mlp.BatchNorm {
+			v = append(v, n.RunMean, n.RunVar)
+		}
+	}
+	return v
+}
+
+// equalModels prüft, ob b dieselbe Architektur und bitgenau dieselben Werte
+// wie a hat.
+func equalModels[T mlp.Float](t *testing.T, a, b *mlp.MLP[T]) {
+	t.Helper()
+	if !slices.Equal(a.Sizes(), b.Sizes()) || !slices.Equal(a.Norms(), b.Norms()) || !slices.Equal(a.ConvSpecs(), b.ConvSpecs()) {
+		t.Fatalf("Architektur %v %q %v, erwartet %v %q %v", b.Sizes(), b.Norms(), b.ConvSpecs(), a.Sizes(), a.Norms(), a.ConvSpecs())
+	}
+	for i, act := range a.Activations() {
+		if b.Activations()[i].Name() != act.Name() {
+			t.Errorf("Schicht %d: Aktivierung %s, erwartet %s", i, b.Activations()[i].Name(), act.Name())
+		}
+	}
+	va, vb := values(a), values(b)
+	for i := range va {
+		if !slices.Equal(va[i], vb[i]) {
+			t.Fatalf("Tensor %d unterscheidet sich: %v, erwartet %v", i, vb[i], va[i])
+		}
+	}
+}
+
+func testSaveLoad[T mlp.Float](t *testing.T) {
+	for _, ext := range []string{".bin", ".json"} {
+		t.Run(ext, func(t *testing.T) {
+			m := testModel[T](t)
+			file := filepath.Join(t.TempDir(), "model"+ext)
+			meta := &Metadata{Seed: 7, Classes: []string{"a", "b", "c"}}
+			if err := Save(file, m, meta); err != nil {
+				t.Fatal(err)
+			}
+			loaded, got, err := Load[T](file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			equalModels(t, m, loaded)
+			if got.Precision != mlp.Precision[T]() || got.Seed != 7 || !slices.Equal(got.Classes, meta.Classes) {
+				t.Errorf("Metadaten %+v", got)
+			}
+			if !slices.Equal(got.Architecture, m.Sizes()) {
+				t.Errorf("Architektur in den Metadaten %v, erwartet %v", got.Architecture, m.Sizes())
+			}
+			if md, err := LoadMetadata(file); err != nil || md.Precision != mlp.Precision[T]() {
+				t.Errorf("LoadMetadata: %+v, %v", md, err)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Run("float64", testSaveLoad[float64])
+	t.Run("float32", testSaveLoad[float32])
+}
+
+func TestLoadOtherPrecision(t *testing.T) {
+	m := testModel[float64](t)
+	file := filepath.Join(t.TempDir(), "model.bin")
+	if err := Save(file, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	loaded, meta, err := Load[float32](file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Precision != mlp.Float64 {
+		t.Errorf("Genauigkeit in den Metadaten %s, erwartet %s", meta.Precision, mlp.Float64)
+	}
+	want := values(m)
+	for i, v := range values(loaded) {
+		if !slices.Equal(v, mlp.Convert[float32](want[i])) {
+			t.Fatalf("Tensor %d: %v, erwartet %v", i, v, want[i])
+		}
+	}
+}
+
+// withFooter ersetzt die Prüfsummen am Ende von data durch die passenden.
+func withFooter(data []byte) []byte {
+	body := slices.Clone(data[:len(data)-footerSize])
+	sum := sha256.Sum256(body)
+	body = binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE(body))
+	return append(body, sum[:]...)
+}
+
+// withHeader ändert den Header von data mit modify und liefert die Datei mit
+// passenden Prüfsummen.
+func withHeader(t *testing.T, data []byte, modify func(hdr *binaryHeader)) []byte {
+	t.Helper()
+	start := len(binaryMagic) + 8
+	end := start + int(binary.LittleEndian.Uint32(data[len(binaryMagic)+4:]))
+	var hdr binaryHeader
+	if err := json.Unmarshal(data[start:end], &hdr); err != nil {
+		t.Fatal(err)
+	}
+	modify(&hdr)
+	header, err := json.Marshal(&hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := slices.Clone(data[:start])
+	binary.LittleEndian.PutUint32(out[len(binaryMagic)+4:], uint32(len(header)))
+	out = append(append(out, header...), data[end:]...)
+	return withFooter(out)
+}
+
+func TestBinaryChecks(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "model.bin")
+	if err := Save(file, testModel[float32](t), nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("MLPB")) {
+		t.Fatalf("Datei beginnt mit %q", data[:4])
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func([]byte) []byte
+		err    string
+	}{
+		// das letzte Byte vor dem Footer gehört zum letzten Tensor
+		{"Tensor", func(d []byte) []byte { d[len(d)-footerSize-1] ^= 1; return d }, "CRC-32-Prüfsumme"},
+		{"CRC-32", func(d []byte) []byte { d[len(d)-footerSize] ^= 1; return d }, "CRC-32-Prüfsumme"},
+		{"SHA-256", func(d []byte) []byte { d[len(d)-1] ^= 1; return d }, "SHA-256-Prüfsumme"},
+		{"gekürzt", func(d []byte) []byte { return d[:20] }, "zu kurz"},
+		{"Version", func(d []byte) []byte {
+			binary.LittleEndian.PutUint32(d[4:], binaryVersion+1)
+			return withFooter(d)
+		}, "Version 2"},
+		{"Tensorlänge", func(d []byte) []byte {
+			// ein zusätzlicher Wert vor dem Footer mit gültigen Prüfsummen
+			d = slices.Insert(d, len(d)-footerSize, 0, 0, 0, 0)
+			return withFooter(d)
+		}, "Tensoren laut Header"},
+		{"Überlauf", func(d []byte) []byte {
+			// Die zusätzliche Dimension 2^62+1 ändert die Größe des ersten
+			// Tensors modulo 2^64 nicht, die Summe passt ohne Prüfung zur Datei.
+			return withHeader(t, d, func(hdr *binaryHeader) {
+				hdr.Tensors[0].Shape = append(hdr.Tensors[0].Shape, 1<<62+1)
+			})
+		}, "größer als die Datei"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			broken := filepath.Join(dir, tc.name+".bin")
+			if err := os.WriteFile(broken, tc.modify(slices.Clone(data)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := Load[float32](broken)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Fehler %v, erwartet %q", err, tc.err)
+			}
+			if _, err := LoadMetadata(broken); err == nil {
+				t.Error("LoadMetadata: kein Fehler")
+			}
+		})
+	}
+}
+
+func TestConvertLegacyJSON(t *testing.T) {
+	// Format der ersten Version mit einer versteckten Schicht und ohne
+	// Metadaten
+	legacy := `{
+		"W1": [[0.1, -0.2], [0.3, 0.4], [-0.5, 0.6]],
+		"b1": [0.01, 0.02, 0.03],
+		"W2": [[1, -1, 0.5], [-0.25, 0.75, 2]],
+		"b2": [0.1, -0.1]
+	}`
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "model.json"), filepath.Join(dir, "model.bin")
+	if err := os.WriteFile(in, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// wie cmd/convert: laden, binär speichern und erneut laden
+	m, meta, err := Load[float64](in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(m.Sizes(), []int{2, 3, 2}) || meta.Precision != mlp.Float64 {
+		t.Fatalf("Architektur %v, Genauigkeit %s", m.Sizes(), meta.Precision)
+	}
+	if got := []string{m.Layers[0].Act.Name(), m.Layers[1].Act.Name()}; !slices.Equal(got, []string{"relu", "softmax"}) {
+		t.Errorf("Aktivierungen %v, erwartet ReLU und Softmax", got)
+	}
+	if err := Save(out, m, meta); err != nil {
+		t.Fatal(err)
+	}
+	converted, cmeta, err := Load[float64](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalModels(t, m, converted)
+	if !slices.Equal(m.Layers[1].W.Data, []float64{1, -1, 0.5, -0.25, 0.75, 2}) {
+		t.Errorf("W2 = %v", m.Layers[1].W.Data)
+	}
+	if !slices.Equal(cmeta.Architecture, []int{2, 3, 2}) || !slices.Equal(cmeta.Activations, []string{"relu", "softmax"}) {
+		t.Errorf("Metadaten der umgewandelten Datei %+v", cmeta)
+	}
+}
@@ -0,0 +1,286 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Namen der Normalisierungen, wie sie z. B. in Modelldateien gespeichert werden.
+const (
+	BatchNorm = "batchnorm"
+	LayerNorm = "layernorm"
+)
+
+// NormNames sind die Namen aller Normalisierungen.
+var NormNames = []string{BatchNorm, LayerNorm}
+
+// Norm normalisiert die Ausgabe z = W*x + b einer Dense-Schicht vor der
+// Aktivierung: y = Gamma * (z - μ)/sqrt(σ² + Epsilon) + Beta.
+//
+// Bei LayerNorm sind μ und σ² Mittelwert und Varianz über die Neuronen eines
+// Beispiels. Bei BatchNorm sind sie im Training Mittelwert und Varianz jedes
+// Neurons über alle Beispiele des Mini-Batches, auch wenn er auf mehrere
+// Shards verteilt ist. Bei der Inferenz verwendet BatchNorm stattdessen
+// RunMean und RunVar.
+type Norm[T Float] struct {
+	Kind        string
+	Gamma, Beta []T
+	// RunMean und RunVar sind bei BatchNorm gleitende Mittelwerte von
+	// Mittelwert und Varianz der Mini-Batches, sonst nil.
+	RunMean, RunVar []T
+	// Momentum ist das Gewicht eines neuen Mini-Batches in RunMean und RunVar.
+	Momentum float64
+	Epsilon  float64
+}
+
+// NewNorm erzeugt eine Normalisierung kind für dim Neuronen mit Gamma = 1 und
+// Beta = 0.
+func NewNorm[T Float](kind string, dim int) (*Norm[T], error) {
+	if err := CheckNorm(kind); err != nil {
+		return nil, err
+	}
+	n := &Norm[T]{Kind: kind, Gamma: make([]T, dim), Beta: make([]T, dim), Momentum: 0.1, Epsilon: 1e-5}
+	for i := range n.Gamma {
+		n.Gamma[i] = 1
+	}
+	if kind == BatchNorm {
+		n.RunMean = make([]T, dim)
+		n.RunVar = make([]T, dim)
+		for i := range n.RunVar {
+			n.RunVar[i] = 1
+		}
+	}
+	return n, nil
+}
+
+// CheckNorm prüft, ob kind eine bekannte Normalisierung ist.
+func CheckNorm(kind string) error {
+	switch kind {
+	case BatchNorm, LayerNorm:
+		return nil
+	}
+	return fmt.Errorf("unbekannte Normalisierung %q, erlaubt sind %v", kind, NormNames)
+}
+
+// Dim liefert die Anzahl der Neuronen.
+func (n *Norm[T]) Dim() int {
+	return len(n.Gamma)
+}
+
+// normWork ist der Arbeitsspeicher einer Norm für einen Batch.
+type normWork[T Float] struct {
+	// pre ist die Eingabe z der Norm, xhat das normalisierte z vor Gamma und
+	// Beta, dPre ist dLoss/dz.
+	pre, xhat, dPre *Matrix[T]
+	// mean, variance (ohne Bessel-Korrektur) und istd = 1/sqrt(σ² + Epsilon)
+	// je Neuron (BatchNorm) bzw. je Beispiel (LayerNorm).
+	mean, variance, istd []T
+}
+
+// newNormWork erzeugt den Arbeitsspeicher für bis zu rows Beispiele.
+func newNormWork[T Float](rows, dim int) *normWork[T] {
+	n := max(rows, dim)
+	return &normWork[T]{
+		pre:      NewMatrix[T](rows, dim),
+		xhat:     NewMatrix[T](rows, dim),
+		dPre:     NewMatrix[T](rows, dim),
+		mean:     make([]T, n),
+		variance: make([]T, n),
+		istd:     make([]T, n),
+	}
+}
+
+// byRow gibt an, ob die Statistiken je Beispiel (Zeile) statt je Neuron
+// (Spalte) berechnet werden.
+func (n *Norm[T]) byRow() bool {
+	return n.Kind == LayerNorm
+}
+
+// forward normalisiert w.pre nach out für die Shards works eines Batches,
+// outs[i] ist die Ausgabe von works[i]. Im Training (train) verwendet
+// BatchNorm Mittelwert und Varianz über die Zeilen aller Shards, sonst RunMean
+// und RunVar. Die Summen werden in der Reihenfolge der Shards gebildet, so
+// dass das Ergebnis nicht von der Anzahl der Worker abhängt.
+func (n *Norm[T]) forward(works []*normWork[T], outs []*Matrix[T], train bool) {
+	dim := n.Dim()
+	eps := n.Epsilon
+	total := 0
+	for _, w := range works {
+		w.xhat.SetRows(w.pre.Rows)
+		total += w.pre.Rows
+	}
+
+	switch {
+	case n.byRow():
+		for _, w := range works {
+			for i := 0; i < w.pre.Rows; i++ {
+				mean, variance := moments(w.pre.Row(i))
+				w.mean[i], w.variance[i] = T(mean), T(variance)
+				w.istd[i] = T(1 / math.Sqrt(variance+eps))
+				x, xhat := w.pre.Row(i), w.xhat.Row(i)
+				for j := range x {
+					xhat[j] = (x[j] - w.mean[i]) * w.istd[i]
+				}
+			}
+		}
+	case train:
+		for j := 0; j < dim; j++ {
+			var sum float64
+			for _, w := range works {
+				for i := 0; i < w.pre.Rows; i++ {
+					sum += float64(w.pre.At(i, j))
+				}
+			}
+			mean := sum / float64(total)
+			var sq float64
+			for _, w := range works {
+				for i := 0; i < w.pre.Rows; i++ {
+					d := float64(w.pre.At(i, j)) - mean
+					sq += d * d
+				}
+			}
+			variance := sq / float64(total)
+			for _, w := range works {
+				w.mean[j], w.variance[j] = T(mean), T(variance)
+				w.istd[j] = T(1 / math.Sqrt(variance+eps))
+			}
+		}
+		for _, w := range works {
+			n.normalizeColumns(w)
+		}
+	default:
+		for _, w := range works {
+			for j := 0; j < dim; j++ {
+				w.mean[j] = n.RunMean[j]
+				w.istd[j] = T(1 / math.Sqrt(float64(n.RunVar[j])+eps))
+			}
+			n.normalizeColumns(w)
+		}
+	}
+
+	for s, w := range works {
+		for i := 0; i < w.pre.Rows; i++ {
+			xhat, y := w.xhat.Row(i), outs[s].Row(i)
+			for j := range y {
+				y[j] = n.Gamma[j]*xhat[j] + n.Beta[j]
+			}
+		}
+	}
+}
+
+// normalizeColumns berechnet xhat aus pre mit mean und istd je Spalte.
+func (n *Norm[T]) normalizeColumns(w *normWork[T]) {
+	for i := 0; i < w.pre.Rows; i++ {
+		x, xhat := w.pre.Row(i), w.xhat.Row(i)
+		for j := range x {
+			xhat[j] = (x[j] - w.mean[j]) * w.istd[j]
+		}
+	}
+}
+
+// backward berechnet für die Shards works nach forward aus dOuts[i] =
+// dLoss/dy den Gradienten works[i].dPre = dLoss/dz und addiert die Gradienten
+// von Gamma und Beta zu gs[i]. Im Training werden die Mittelwerte von
+// BatchNorm wie in forward über alle Shards gebildet.
+func (n *Norm[T]) backward(works []*normWork[T], dOuts []*Matrix[T], train bool, gs []*Norm[T]) {
+	dim := n.Dim()
+	total := 0
+	for s, w := range works {
+		dOut, g := dOuts[s], gs[s]
+		w.dPre.SetRows(dOut.Rows)
+		total += dOut.Rows
+
+		// dxhat = dOut * Gamma, zwischengespeichert in dPre
+		for i := 0; i < dOut.Rows; i++ {
+			d, xhat, dx := dOut.Row(i), w.xhat.Row(i), w.dPre.Row(i)
+			for j := range d {
+				g.Gamma[j] += d[j] * xhat[j]
+				g.Beta[j] += d[j]
+				dx[j] = d[j] * n.Gamma[j]
+			}
+		}
+	}
+
+	// dz = istd * (dxhat - mean(dxhat) - xhat * mean(dxhat * xhat)), die
+	// Mittelwerte über dieselbe Achse wie die Statistiken
+	switch {
+	case n.byRow():
+		for _, w := range works {
+			for i := 0; i < w.dPre.Rows; i++ {
+				dx, xhat := w.dPre.Row(i), w.xhat.Row(i)
+				m1, m2 := dot(dx, xhat), T(0)
+				for _, v := range dx {
+					m2 += v
+				}
+				m1 /= T(dim)
+				m2 /= T(dim)
+				for j := range dx {
+					dx[j] = w.istd[i] * (dx[j] - m2 - xhat[j]*m1)
+				}
+			}
+		}
+	case train:
+		for j := 0; j < dim; j++ {
+			var m1, m2 T
+			for _, w := range works {
+				for i := 0; i < w.dPre.Rows; i++ {
+					m1 += w.dPre.At(i, j) * w.xhat.At(i, j)
+					m2 += w.dPre.At(i, j)
+				}
+			}
+			m1 /= T(total)
+			m2 /= T(total)
+			for _, w := range works {
+				for i := 0; i < w.dPre.Rows; i++ {
+					w.dPre.Set(i, j, w.istd[j]*(w.dPre.At(i, j)-m2-w.xhat.At(i, j)*m1))
+				}
+			}
+		}
+	default:
+		// mit festen Statistiken ist die Norm affin
+		for _, w := range works {
+			for i := 0; i < w.dPre.Rows; i++ {
+				dx := w.dPre.Row(i)
+				for j := range dx {
+					dx[j] *= w.istd[j]
+				}
+			}
+		}
+	}
+}
+
+// moments liefert Mittelwert und Varianz (ohne Bessel-Korrektur) von x.
+func moments[T Float](x []T) (mean, variance float64) {
+	for _, v := range x {
+		mean += float64(v)
+	}
+	mean /= float64(len(x))
+	for _, v := range x {
+		d := float64(v) - mean
+		variance += d * d
+	}
+	return mean, variance / float64(len(x))
+}
+
+// updateRunningStats führt RunMean und RunVar mit Mittelwert und
+// (Bessel-korrigierter) Varianz des Mini-Batches nach, die forward im Training
+// in jedem seiner Shards works hinterlegt hat.
+func (n *Norm[T]) updateRunningStats(works []*normWork[T]) {
+	total := 0
+	for _, w := range works {
+		total += w.pre.Rows
+	}
+	if total == 0 {
+		return
+	}
+	mom := n.Momentum
+	w := works[0]
+	for j := range n.RunMean {
+		mean, variance := float64(w.mean[j]), 0.0
+		if total > 1 {
+			variance = float64(w.variance[j]) * float64(total) / float64(total-1)
+		}
+		n.RunMean[j] = T((1-mom)*float64(n.RunMean[j]) + mom*mean)
+		n.RunVar[j] = T((1-mom)*float64(n.RunVar[j]) + mom*variance)
+	}
+}
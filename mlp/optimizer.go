@@ -0,0 +1,372 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Param ist ein trainierbarer Parameter-Tensor zusammen mit seinem Gradienten.
+type Param[T Float] struct {
+	Value []T
+	Grad  []T
+	// Decay gibt an, ob Gewichtsabnahme auf den Parameter angewendet wird
+	// (Gewichte ja, Bias nein).
+	Decay bool
+}
+
+// Params liefert alle Parameter des Modells zusammen mit den Gradienten grads:
+// je Conv2D-Schicht die Filter und den Bias, dann je Dense-Schicht die
+// Gewichtsmatrix, den Bias und ggf. Gamma und Beta der Normalisierung. Die
+// Reihenfolge ist stabil, so dass der Zustand eines Optimizers den Parametern
+// zugeordnet bleibt.
+func (m *MLP[T]) Params(grads Gradients[T]) []Param[T] {
+	var params []Param[T]
+	for i, layer := range m.Conv {
+		if layer.W == nil {
+			continue
+		}
+		params = append(params,
+			Param[T]{Value: layer.W.Data, Grad: grads.Conv[i].W.Data, Decay: true},
+			Param[T]{Value: layer.B, Grad: grads.Conv[i].B},
+		)
+	}
+	for l, layer := range m.Layers {
+		g := grads.Layers[l]
+		params = append(params,
+			Param[T]{Value: layer.W.Data, Grad: g.W.Data, Decay: true},
+			Param[T]{Value: layer.B, Grad: g.B},
+		)
+		if n := layer.Norm; n != nil {
+			params = append(params,
+				Param[T]{Value: n.Gamma, Grad: g.Norm.Gamma},
+				Param[T]{Value: n.Beta, Grad: g.Norm.Beta},
+			)
+		}
+	}
+	return params
+}
+
+// Optimizer aktualisiert die Parameter anhand ihrer Gradienten. Der Zustand
+// pro Parameter (z. B. Geschwindigkeit oder Momente) liegt im Optimizer neben
+// dem Modell und wird beim ersten Step passend zu den Parametern angelegt.
+type Optimizer[T Float] interface {
+	// Name liefert den Namen, unter dem NewOptimizer den Optimizer erzeugt.
+	Name() string
+	// Step führt einen Update-Schritt mit Lernrate lr aus.
+	Step(params []Param[T], lr float64)
+	// State liefert den Zustand für Checkpoints.
+	State() OptimizerState
+	// SetState stellt einen mit State gesicherten Zustand wieder her.
+	SetState(OptimizerState) error
+}
+
+// OptimizerState ist der serialisierbare Zustand eines Optimizers. Die Slots
+// werden unabhängig von der Genauigkeit des Modells als float64 gespeichert.
+type OptimizerState struct {
+	Name  string                 `json:"name"`
+	Steps int                    `json:"steps"`
+	Slots map[string][][]float64 `json:"slots,omitempty"`
+}
+
+// OptimizerConfig enthält die Hyperparameter aller Optimizer.
+type OptimizerConfig struct {
+	Momentum    float64 // SGD mit Momentum, Nesterov
+	Beta1       float64 // Adam, AdamW
+	Beta2       float64 // Adam, AdamW
+	Rho         float64 // RMSProp
+	Epsilon     float64 // AdaGrad, RMSProp, Adam, AdamW
+	WeightDecay float64 // AdamW (entkoppelt)
+}
+
+// DefaultOptimizerConfig liefert übliche Standardwerte.
+func DefaultOptimizerConfig() OptimizerConfig {
+	return OptimizerConfig{
+		Momentum:    0.9,
+		Beta1:       0.9,
+		Beta2:       0.999,
+		Rho:         0.9,
+		Epsilon:     1e-8,
+		WeightDecay: 0.01,
+	}
+}
+
+// OptimizerNames sind die Namen, die NewOptimizer versteht.
+var OptimizerNames = []string{"sgd", "momentum", "nesterov", "adagrad", "rmsprop", "adam", "adamw"}
+
+// NewOptimizer erzeugt den Optimizer mit dem Namen name. Die von ihm
+// verwendeten Hyperparameter aus cfg müssen im erlaubten Bereich liegen:
+// Momentum, Beta1, Beta2 und Rho in [0, 1), Epsilon größer 0 und
+// WeightDecay nicht negativ.
+func NewOptimizer[T Float](name string, cfg OptimizerConfig) (Optimizer[T], error) {
+	if err := cfg.check(name); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "sgd":
+		return &SGD[T]{name: name}, nil
+	case "momentum":
+		return &SGD[T]{Momentum: cfg.Momentum, name: name}, nil
+	case "nesterov":
+		return &SGD[T]{Momentum: cfg.Momentum, Nesterov: true, name: name}, nil
+	case "adagrad":
+		return &AdaGrad[T]{Epsilon: cfg.Epsilon}, nil
+	case "rmsprop":
+		return &RMSProp[T]{Rho: cfg.Rho, Epsilon: cfg.Epsilon}, nil
+	case "adam":
+		return &Adam[T]{Beta1: cfg.Beta1, Beta2: cfg.Beta2, Epsilon: cfg.Epsilon}, nil
+	case "adamw":
+		return &AdamW[T]{Adam: Adam[T]{Beta1: cfg.Beta1, Beta2: cfg.Beta2, Epsilon: cfg.Epsilon}, WeightDecay: cfg.WeightDecay}, nil
+	}
+	return nil, fmt.Errorf("unbekannter Optimizer %q, erlaubt sind %v", name, OptimizerNames)
+}
+
+// check prüft die Hyperparameter, die der Optimizer name verwendet.
+func (cfg OptimizerConfig) check(name string) error {
+	unit := func(param string, v float64) error {
+		if v < 0 || v >= 1 {
+			return fmt.Errorf("Optimizer %s: %s muss in [0, 1) liegen, ist %g", name, param, v)
+		}
+		return nil
+	}
+	var errs []error
+	switch name {
+	case "momentum", "nesterov":
+		errs = append(errs, unit("momentum", cfg.Momentum))
+	case "rmsprop":
+		errs = append(errs, unit("rho", cfg.Rho))
+	case "adam", "adamw":
+		errs = append(errs, unit("beta1", cfg.Beta1), unit("beta2", cfg.Beta2))
+	}
+	switch name {
+	case "adagrad", "rmsprop", "adam", "adamw":
+		if cfg.Epsilon <= 0 {
+			errs = append(errs, fmt.Errorf("Optimizer %s: eps muss größer 0 sein, ist %g", name, cfg.Epsilon))
+		}
+	}
+	if name == "adamw" && cfg.WeightDecay < 0 {
+		errs = append(errs, fmt.Errorf("Optimizer %s: weight_decay darf nicht negativ sein, ist %g", name, cfg.WeightDecay))
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slots verwaltet den Zustand pro Parameter, den alle Optimizer gemeinsam haben.
+type slots[T Float] struct {
+	steps int
+	slots map[string][][]T
+}
+
+// slot liefert den Zustand name für params und legt ihn beim ersten Aufruf an.
+func (s *slots[T]) slot(name string, params []Param[T]) [][]T {
+	if s.slots == nil {
+		s.slots = make(map[string][][]T)
+	}
+	v, ok := s.slots[name]
+	if !ok {
+		v = make([][]T, len(params))
+		for i, p := range params {
+			v[i] = make([]T, len(p.Value))
+		}
+		s.slots[name] = v
+		return v
+	}
+	if len(v) != len(params) {
+		panic(fmt.Sprintf("mlp: Optimizer-Zustand %q hat %d Parameter, Modell hat %d", name, len(v), len(params)))
+	}
+	for i, p := range params {
+		if len(v[i]) != len(p.Value) {
+			panic(fmt.Sprintf("mlp: Optimizer-Zustand %q: Parameter %d hat Länge %d, erwartet %d", name, i, len(v[i]), len(p.Value)))
+		}
+	}
+	return v
+}
+
+func (s *slots[T]) state(name string) OptimizerState {
+	st := OptimizerState{Name: name, Steps: s.steps}
+	if s.slots != nil {
+		st.Slots = make(map[string][][]float64, len(s.slots))
+		for k, v := range s.slots {
+			st.Slots[k] = ConvertRows[float64](v)
+		}
+	}
+	return st
+}
+
+func (s *slots[T]) setState(name string, st OptimizerState) error {
+	if st.Name != name {
+		return fmt.Errorf("Optimizer-Zustand für %q passt nicht zu %q", st.Name, name)
+	}
+	s.steps = st.Steps
+	s.slots = nil
+	if st.Slots != nil {
+		s.slots = make(map[string][][]T, len(st.Slots))
+		for k, v := range st.Slots {
+			s.slots[k] = ConvertRows[T](v)
+		}
+	}
+	return nil
+}
+
+// SGD ist stochastischer Gradientenabstieg, optional mit Momentum bzw.
+// Nesterov-Momentum.
+type SGD[T Float] struct {
+	Momentum float64
+	Nesterov bool
+	// name ist der Name aus NewOptimizer. Er bleibt auch mit Momentum 0
+	// erhalten, damit ein Checkpoint von -optimizer momentum -momentum 0 zu
+	// seinem eigenen Optimizer passt.
+	name string
+	slots[T]
+}
+
+// Name liefert den Namen aus NewOptimizer, bei einem direkt angelegten SGD
+// den aus Momentum und Nesterov abgeleiteten.
+func (o *SGD[T]) Name() string {
+	switch {
+	case o.name != "":
+		return o.name
+	case o.Nesterov:
+		return "nesterov"
+	case o.Momentum != 0:
+		return "momentum"
+	}
+	return "sgd"
+}
+
+func (o *SGD[T]) Step(params []Param[T], lr float64) {
+	o.steps++
+	rate := T(lr)
+	if o.Momentum == 0 {
+		// W -= lr * dW
+		for _, p := range params {
+			axpy(-rate, p.Grad, p.Value)
+		}
+		return
+	}
+
+	mom := T(o.Momentum)
+	vel := o.slot("velocity", params)
+	for i, p := range params {
+		v := vel[i]
+		for j, g := range p.Grad {
+			v[j] = mom*v[j] + g
+			if o.Nesterov {
+				p.Value[j] -= rate * (g + mom*v[j])
+			} else {
+				p.Value[j] -= rate * v[j]
+			}
+		}
+	}
+}
+
+func (o *SGD[T]) State() OptimizerState            { return o.state(o.Name()) }
+func (o *SGD[T]) SetState(st OptimizerState) error { return o.setState(o.Name(), st) }
+
+// AdaGrad skaliert die Lernrate mit der Summe aller bisherigen quadrierten Gradienten.
+type AdaGrad[T Float] struct {
+	Epsilon float64
+	slots[T]
+}
+
+func (o *AdaGrad[T]) Name() string { return "adagrad" }
+
+func (o *AdaGrad[T]) Step(params []Param[T], lr float64) {
+	o.steps++
+	rate, eps := T(lr), T(o.Epsilon)
+	sum := o.slot("sum", params)
+	for i, p := range params {
+		s := sum[i]
+		for j, g := range p.Grad {
+			s[j] += g * g
+			p.Value[j] -= rate * g / (sqrt(s[j]) + eps)
+		}
+	}
+}
+
+func (o *AdaGrad[T]) State() OptimizerState            { return o.state(o.Name()) }
+func (o *AdaGrad[T]) SetState(st OptimizerState) error { return o.setState(o.Name(), st) }
+
+// RMSProp skaliert die Lernrate mit dem gleitenden Mittel der quadrierten Gradienten.
+type RMSProp[T Float] struct {
+	Rho     float64
+	Epsilon float64
+	slots[T]
+}
+
+func (o *RMSProp[T]) Name() string { return "rmsprop" }
+
+func (o *RMSProp[T]) Step(params []Param[T], lr float64) {
+	o.steps++
+	rate, rho, eps := T(lr), T(o.Rho), T(o.Epsilon)
+	avg := o.slot("sq_avg", params)
+	for i, p := range params {
+		s := avg[i]
+		for j, g := range p.Grad {
+			s[j] = rho*s[j] + (1-rho)*g*g
+			p.Value[j] -= rate * g / (sqrt(s[j]) + eps)
+		}
+	}
+}
+
+func (o *RMSProp[T]) State() OptimizerState            { return o.state(o.Name()) }
+func (o *RMSProp[T]) SetState(st OptimizerState) error { return o.setState(o.Name(), st) }
+
+// Adam verwendet gleitende Mittel des ersten und zweiten Moments der Gradienten
+// mit Bias-Korrektur.
+type Adam[T Float] struct {
+	Beta1   float64
+	Beta2   float64
+	Epsilon float64
+	slots[T]
+}
+
+func (o *Adam[T]) Name() string { return "adam" }
+
+func (o *Adam[T]) Step(params []Param[T], lr float64) {
+	o.steps++
+	m1 := o.slot("m", params)
+	m2 := o.slot("v", params)
+	c1 := T(1 - math.Pow(o.Beta1, float64(o.steps)))
+	c2 := T(1 - math.Pow(o.Beta2, float64(o.steps)))
+	rate, b1, b2, eps := T(lr), T(o.Beta1), T(o.Beta2), T(o.Epsilon)
+	for i, p := range params {
+		m, v := m1[i], m2[i]
+		for j, g := range p.Grad {
+			m[j] = b1*m[j] + (1-b1)*g
+			v[j] = b2*v[j] + (1-b2)*g*g
+			p.Value[j] -= rate * (m[j] / c1) / (sqrt(v[j]/c2) + eps)
+		}
+	}
+}
+
+func (o *Adam[T]) State() OptimizerState            { return o.state(o.Name()) }
+func (o *Adam[T]) SetState(st OptimizerState) error { return o.setState(o.Name(), st) }
+
+// AdamW ist Adam mit entkoppelter Gewichtsabnahme: Die Gewichte werden vor dem
+// Adam-Schritt direkt um lr*WeightDecay*W verkleinert.
+type AdamW[T Float] struct {
+	Adam[T]
+	WeightDecay float64
+}
+
+func (o *AdamW[T]) Name() string { return "adamw" }
+
+func (o *AdamW[T]) Step(params []Param[T], lr float64) {
+	decay := T(lr * o.WeightDecay)
+	for _, p := range params {
+		if !p.Decay {
+			continue
+		}
+		for j := range p.Value {
+			p.Value[j] -= decay * p.Value[j]
+		}
+	}
+	o.Adam.Step(params, lr)
+}
+
+func (o *AdamW[T]) State() OptimizerState            { return o.state(o.Name()) }
+func (o *AdamW[T]) SetState(st OptimizerState) error { return o.setState(o.Name(), st) }
@@ -0,0 +1,49 @@
+package mlp
+
+import (
+	randv2 "math/rand/v2"
+)
+
+// pcgIncrement ist der zweite Teil des PCG-Seeds, der aus einem int64-Seed
+// nicht hervorgeht.
+const pcgIncrement = 0x9e3779b97f4a7c15
+
+// Source ist eine Zufallsquelle für math/rand.Rand, deren Zustand sich
+// sichern und wiederherstellen lässt. So kann ein Training aus einem Checkpoint
+// bitgenau fortgesetzt werden. Intern ist sie ein PCG aus math/rand/v2.
+type Source struct {
+	pcg *randv2.PCG
+}
+
+// NewSource erzeugt eine mit seed initialisierte Zufallsquelle.
+func NewSource(seed int64) *Source {
+	return &Source{pcg: randv2.NewPCG(uint64(seed), pcgIncrement)}
+}
+
+// Seed setzt die Quelle auf den Zustand von NewSource(seed) zurück.
+func (s *Source) Seed(seed int64) {
+	s.pcg.Seed(uint64(seed), pcgIncrement)
+}
+
+// Int63 liefert eine nichtnegative Zufallszahl mit 63 Bit.
+func (s *Source) Int63() int64 {
+	return int64(s.pcg.Uint64() >> 1)
+}
+
+// Uint64 liefert eine Zufallszahl mit 64 Bit.
+func (s *Source) Uint64() uint64 {
+	return s.pcg.Uint64()
+}
+
+// MarshalBinary liefert den Zustand der Quelle.
+func (s *Source) MarshalBinary() ([]byte, error) {
+	return s.pcg.MarshalBinary()
+}
+
+// UnmarshalBinary stellt einen mit MarshalBinary gesicherten Zustand wieder her.
+func (s *Source) UnmarshalBinary(data []byte) error {
+	if s.pcg == nil {
+		s.pcg = new(randv2.PCG)
+	}
+	return s.pcg.UnmarshalBinary(data)
+}
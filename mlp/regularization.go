@@ -0,0 +1,72 @@
+package mlp
+
+import "math/rand"
+
+// Regularization fasst die Regularisierungen des Trainings zusammen. Der
+// Nullwert bedeutet keine Regularisierung.
+type Regularization struct {
+	WeightDecay
+	// Dropout ist der Anteil der Aktivierungen versteckter Schichten, der im
+	// Training zufällig auf 0 gesetzt wird (inverted Dropout: die übrigen
+	// werden mit 1/(1-Dropout) skaliert, so dass die Inferenz unverändert bleibt).
+	Dropout float64 `json:"dropout,omitempty"`
+	// LabelSmoothing verteilt diesen Anteil der Wahrscheinlichkeit des
+	// One-Hot-Labels gleichmäßig auf alle Klassen.
+	LabelSmoothing float64 `json:"label_smoothing,omitempty"`
+}
+
+// WeightDecay bestraft große Gewichte mit L1*|w| + L2/2*w² im Loss. Update
+// addiert dazu L1*sign(w) + L2*w zum Gradienten der Gewichte; Biases sind
+// ausgenommen.
+type WeightDecay struct {
+	L1 float64 `json:"l1,omitempty"`
+	L2 float64 `json:"l2,omitempty"`
+}
+
+// applyDecay addiert den Gradienten der Strafterme von d zu den Gradienten
+// aller Parameter in params, für die Decay gesetzt ist.
+func applyDecay[T Float](d WeightDecay, params []Param[T]) {
+	if d.L1 == 0 && d.L2 == 0 {
+		return
+	}
+	l1, l2 := T(d.L1), T(d.L2)
+	for _, p := range params {
+		if !p.Decay {
+			continue
+		}
+		for i, w := range p.Value {
+			g := l2 * w
+			if w > 0 {
+				g += l1
+			} else if w < 0 {
+				g -= l1
+			}
+			p.Grad[i] += g
+		}
+	}
+}
+
+// smoothLabel glättet das One-Hot-Label y für Label Smoothing mit eps:
+// y = (1-eps)*y + eps/K bei K Klassen. crossEntropyLoss und outputDelta
+// arbeiten unverändert mit den geglätteten Zielen.
+func smoothLabel[T Float](y []T, eps float64) {
+	k := float64(len(y))
+	for i, v := range y {
+		y[i] = T((1-eps)*float64(v) + eps/k)
+	}
+}
+
+// dropout setzt in a jedes Element mit Wahrscheinlichkeit p auf 0 und
+// skaliert die übrigen mit 1/(1-p). mask erhält die Faktoren (0 oder 1/(1-p))
+// für den Backward-Pass.
+func dropout[T Float](rng *rand.Rand, p float64, a, mask []T) {
+	scale := T(1 / (1 - p))
+	for i := range a {
+		if rng.Float64() < p {
+			mask[i] = 0
+		} else {
+			mask[i] = scale
+		}
+		a[i] *= mask[i]
+	}
+}
@@ -0,0 +1,106 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSmoothLabel(t *testing.T) {
+	for _, tc := range []struct {
+		eps   float64
+		label int
+		want  []float64
+	}{
+		{0, 2, []float64{0, 0, 1, 0}},
+		{0.1, 2, []float64{0.025, 0.025, 0.925, 0.025}},
+		{0.2, 0, []float64{0.85, 0.05, 0.05, 0.05}},
+		{1, 3, []float64{0.25, 0.25, 0.25, 0.25}},
+	} {
+		y := make([]float64, len(tc.want))
+		y[tc.label] = 1
+		smoothLabel(y, tc.eps)
+		var sum float64
+		for i, v := range y {
+			if math.Abs(v-tc.want[i]) > 1e-15 {
+				t.Errorf("eps %g: y = %v, erwartet %v", tc.eps, y, tc.want)
+				break
+			}
+			sum += v
+		}
+		if math.Abs(sum-1) > 1e-15 {
+			t.Errorf("eps %g: Summe %g, erwartet 1", tc.eps, sum)
+		}
+	}
+}
+
+func TestDropout(t *testing.T) {
+	const n = 10000
+	for _, p := range []float64{0, 0.25, 0.5} {
+		in := make([]float64, n)
+		for i := range in {
+			in[i] = float64(i%7) - 3
+		}
+		a, mask := slices.Clone(in), make([]float64, n)
+		dropout(rand.New(rand.NewSource(1)), p, a, mask)
+
+		scale := 1 / (1 - p)
+		dropped := 0
+		for i := range a {
+			switch mask[i] {
+			case 0:
+				dropped++
+				if a[i] != 0 {
+					t.Fatalf("p %g: Element %d verworfen, aber %g", p, i, a[i])
+				}
+			case scale:
+				if a[i] != in[i]*scale {
+					t.Fatalf("p %g: Element %d = %g, erwartet %g/(1-p) = %g", p, i, a[i], in[i], in[i]*scale)
+				}
+			default:
+				t.Fatalf("p %g: Maske %g, erwartet 0 oder %g", p, mask[i], scale)
+			}
+		}
+		if frac := float64(dropped) / n; math.Abs(frac-p) > 0.02 {
+			t.Errorf("p %g: Anteil verworfen %g", p, frac)
+		}
+	}
+}
+
+// TestDropoutInference prüft, dass Dropout nur ForwardBatch im Training
+// betrifft: Forward, Predict und Trainer.Evaluate bleiben unverändert.
+func TestDropoutInference(t *testing.T) {
+	X, Y := syntheticData(20, 12, 4, 1)
+	m := NewMLP[float64](rand.New(rand.NewSource(2)), []int{12, 9, 7, 4})
+	var want [][]float64
+	var predicted []int
+	for _, x := range X {
+		_, as := m.Forward(x)
+		want = append(want, as[len(as)-1])
+		predicted = append(predicted, m.Predict(x))
+	}
+	plain := &Trainer[float64]{Model: m, Workers: 2}
+	wantLoss, wantAcc := plain.Evaluate(Slices[float64]{X, Y})
+
+	// Training mit Dropout verändert die Ausgabe des Batches ...
+	b := m.NewBatch(len(X))
+	batchOf(b, X, Y)
+	b.SetDropout(0.5, 1)
+	out := m.ForwardBatch(b)
+	if slices.Equal(out.Row(0), want[0]) {
+		t.Error("ForwardBatch mit Dropout liefert die Ausgabe ohne Dropout")
+	}
+
+	// ... aber nicht die Inferenz
+	for i, x := range X {
+		_, as := m.Forward(x)
+		if !slices.Equal(as[len(as)-1], want[i]) || m.Predict(x) != predicted[i] {
+			t.Fatalf("Beispiel %d: Ausgabe %v, erwartet %v", i, as[len(as)-1], want[i])
+		}
+	}
+	dropped := &Trainer[float64]{Model: m, Workers: 2, Regularization: Regularization{Dropout: 0.5}}
+	if loss, acc := dropped.Evaluate(Slices[float64]{X, Y}); loss != wantLoss || acc != wantAcc {
+		t.Errorf("Evaluate mit Dropout: Loss %g, Acc %g, erwartet %g und %g", loss, acc, wantLoss, wantAcc)
+	}
+}
@@ -0,0 +1,301 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Schedule legt die Lernrate im Verlauf des Trainings fest.
+type Schedule interface {
+	// LR liefert die Lernrate zum Zeitpunkt epoch. Der Nachkommateil gibt den
+	// Fortschritt innerhalb der Epoche an, 2.5 ist also die Mitte der dritten Epoche.
+	LR(epoch float64) float64
+}
+
+// MetricSchedule ist ein Schedule, der nach jeder Epoche eine Kennzahl wie die
+// Genauigkeit erhält und die Lernrate daran anpasst. Da die Lernrate damit vom
+// bisherigen Verlauf abhängt, lässt sich ihr Zustand für Checkpoints sichern.
+type MetricSchedule interface {
+	Schedule
+	Observe(metric float64)
+	State() ScheduleState
+	SetState(ScheduleState) error
+}
+
+// ScheduleState ist der serialisierbare Zustand eines MetricSchedule.
+type ScheduleState map[string]float64
+
+// ScheduleConfig enthält die Parameter aller Schedules. Alle Zeitangaben sind
+// in Epochen.
+type ScheduleConfig struct {
+	Base         float64 // Start- bzw. Maximallernrate
+	Epochs       int     // Gesamtzahl der Epochen (onecycle)
+	StepSize     float64 // step: Abstand der Reduktionen
+	Gamma        float64 // step, exponential: Faktor pro Schritt bzw. Epoche
+	Period       float64 // cosine: Länge der ersten Periode
+	PeriodMult   float64 // cosine: Faktor für die Länge jeder weiteren Periode
+	MinLR        float64 // cosine, plateau: Untergrenze der Lernrate
+	PctStart     float64 // onecycle: Anteil der Anstiegsphase
+	Factor       float64 // plateau: Faktor bei jeder Reduktion
+	Patience     int     // plateau: Epochen ohne Verbesserung bis zur Reduktion
+	MinDelta     float64 // plateau: minimale Verbesserung der Kennzahl
+	WarmupEpochs float64 // alle: Dauer der linearen Aufwärmphase, 0 für keine
+}
+
+// DefaultScheduleConfig liefert übliche Standardwerte für die Lernrate base.
+func DefaultScheduleConfig(base float64, epochs int) ScheduleConfig {
+	return ScheduleConfig{
+		Base:       base,
+		Epochs:     epochs,
+		StepSize:   10,
+		Gamma:      0.5,
+		Period:     10,
+		PeriodMult: 2,
+		MinLR:      0,
+		PctStart:   0.3,
+		Factor:     0.5,
+		Patience:   3,
+		MinDelta:   1e-4,
+	}
+}
+
+// ScheduleNames sind die Namen, die NewSchedule versteht.
+var ScheduleNames = []string{"constant", "step", "exponential", "cosine", "onecycle", "plateau"}
+
+// NewSchedule erzeugt den Schedule mit dem Namen name. Ist cfg.WarmupEpochs
+// größer 0, wird er in eine lineare Aufwärmphase ab 1% der Lernrate eingebettet.
+// Liegen die vom Schedule verwendeten Parameter außerhalb des sinnvollen
+// Bereichs, liefert NewSchedule einen Fehler.
+func NewSchedule(name string, cfg ScheduleConfig) (Schedule, error) {
+	if err := cfg.check(name); err != nil {
+		return nil, err
+	}
+	var s Schedule
+	switch name {
+	case "constant":
+		s = Constant{Rate: cfg.Base}
+	case "step":
+		s = StepDecay{Base: cfg.Base, Gamma: cfg.Gamma, StepSize: cfg.StepSize}
+	case "exponential":
+		s = ExponentialDecay{Base: cfg.Base, Gamma: cfg.Gamma}
+	case "cosine":
+		s = CosineRestarts{Base: cfg.Base, Min: cfg.MinLR, Period: cfg.Period, PeriodMult: cfg.PeriodMult}
+	case "onecycle":
+		s = OneCycle{Max: cfg.Base, Epochs: float64(cfg.Epochs), PctStart: cfg.PctStart}
+	case "plateau":
+		s = &ReduceOnPlateau{Rate: cfg.Base, Factor: cfg.Factor, Patience: cfg.Patience, MinDelta: cfg.MinDelta, Min: cfg.MinLR}
+	default:
+		return nil, fmt.Errorf("unbekannter Schedule %q, erlaubt sind %v", name, ScheduleNames)
+	}
+	if cfg.WarmupEpochs > 0 {
+		w := Warmup{Epochs: cfg.WarmupEpochs, Start: 0.01, Inner: s}
+		if ms, ok := s.(MetricSchedule); ok {
+			return &metricWarmup{Warmup: w, metric: ms}, nil
+		}
+		s = w
+	}
+	return s, nil
+}
+
+// check prüft die Parameter, die der Schedule name verwendet.
+func (cfg ScheduleConfig) check(name string) error {
+	var err error
+	fail := func(format string, args ...any) {
+		if err == nil {
+			err = fmt.Errorf("Schedule %s: "+format, append([]any{name}, args...)...)
+		}
+	}
+	switch name {
+	case "step":
+		if cfg.StepSize <= 0 {
+			fail("step_size muss größer 0 sein, ist %g", cfg.StepSize)
+		}
+		if cfg.Gamma <= 0 {
+			fail("gamma muss größer 0 sein, ist %g", cfg.Gamma)
+		}
+	case "exponential":
+		if cfg.Gamma <= 0 {
+			fail("gamma muss größer 0 sein, ist %g", cfg.Gamma)
+		}
+	case "cosine":
+		if cfg.Period <= 0 {
+			fail("period muss größer 0 sein, ist %g", cfg.Period)
+		}
+		if cfg.PeriodMult < 1 {
+			fail("period_mult muss mindestens 1 sein, ist %g", cfg.PeriodMult)
+		}
+	case "onecycle":
+		if cfg.Epochs <= 0 {
+			fail("die Anzahl der Epochen muss größer 0 sein, ist %d", cfg.Epochs)
+		}
+		if cfg.PctStart <= 0 || cfg.PctStart >= 1 {
+			fail("pct_start muss zwischen 0 und 1 liegen, ist %g", cfg.PctStart)
+		}
+	case "plateau":
+		if cfg.Factor <= 0 || cfg.Factor >= 1 {
+			fail("factor muss zwischen 0 und 1 liegen, ist %g", cfg.Factor)
+		}
+		if cfg.Patience < 0 {
+			fail("patience darf nicht negativ sein, ist %d", cfg.Patience)
+		}
+	}
+	if cfg.WarmupEpochs < 0 {
+		fail("warmup darf nicht negativ sein, ist %g", cfg.WarmupEpochs)
+	}
+	return err
+}
+
+// Constant ist eine konstante Lernrate.
+type Constant struct {
+	Rate float64
+}
+
+func (c Constant) LR(epoch float64) float64 { return c.Rate }
+
+// StepDecay multipliziert die Lernrate alle StepSize Epochen mit Gamma.
+type StepDecay struct {
+	Base     float64
+	Gamma    float64
+	StepSize float64
+}
+
+func (s StepDecay) LR(epoch float64) float64 {
+	return s.Base * math.Pow(s.Gamma, math.Floor(epoch/s.StepSize))
+}
+
+// ExponentialDecay multipliziert die Lernrate pro Epoche mit Gamma, wobei
+// auch innerhalb einer Epoche stetig abgesenkt wird.
+type ExponentialDecay struct {
+	Base  float64
+	Gamma float64
+}
+
+func (s ExponentialDecay) LR(epoch float64) float64 {
+	return s.Base * math.Pow(s.Gamma, epoch)
+}
+
+// CosineRestarts senkt die Lernrate in jeder Periode entlang einer Kosinuskurve
+// von Base auf Min und beginnt dann neu (SGDR). Jede Periode ist PeriodMult mal
+// so lang wie die vorherige.
+type CosineRestarts struct {
+	Base       float64
+	Min        float64
+	Period     float64
+	PeriodMult float64
+}
+
+func (s CosineRestarts) LR(epoch float64) float64 {
+	period := s.Period
+	mult := s.PeriodMult
+	if mult < 1 {
+		mult = 1
+	}
+	for epoch >= period {
+		epoch -= period
+		period *= mult
+	}
+	return s.Min + 0.5*(s.Base-s.Min)*(1+math.Cos(math.Pi*epoch/period))
+}
+
+// OneCycle steigt im ersten Anteil PctStart des Trainings von Max/25 auf Max
+// und fällt danach bis zum Ende auf Max/1e4 (jeweils entlang einer Kosinuskurve).
+type OneCycle struct {
+	Max      float64
+	Epochs   float64
+	PctStart float64
+}
+
+func (s OneCycle) LR(epoch float64) float64 {
+	start := s.Max / 25
+	end := start / 1e4
+	up := s.Epochs * s.PctStart
+	if epoch < up {
+		return cosineInterp(start, s.Max, epoch/up)
+	}
+	return cosineInterp(s.Max, end, math.Min(1, (epoch-up)/(s.Epochs-up)))
+}
+
+// cosineInterp interpoliert für t in [0,1] entlang einer Kosinuskurve von a nach b.
+func cosineInterp(a, b, t float64) float64 {
+	return b + (a-b)*0.5*(1+math.Cos(math.Pi*t))
+}
+
+// Warmup erhöht die Lernrate in den ersten Epochs Epochen linear vom Anteil
+// Start auf die volle Lernrate von Inner.
+type Warmup struct {
+	Epochs float64
+	Start  float64
+	Inner  Schedule
+}
+
+func (w Warmup) LR(epoch float64) float64 {
+	lr := w.Inner.LR(epoch)
+	if epoch < w.Epochs {
+		lr *= w.Start + (1-w.Start)*epoch/w.Epochs
+	}
+	return lr
+}
+
+// metricWarmup ist ein Warmup um einen MetricSchedule, der die Kennzahlen weiterreicht.
+type metricWarmup struct {
+	Warmup
+	metric MetricSchedule
+}
+
+func (w *metricWarmup) Observe(metric float64)             { w.metric.Observe(metric) }
+func (w *metricWarmup) State() ScheduleState               { return w.metric.State() }
+func (w *metricWarmup) SetState(state ScheduleState) error { return w.metric.SetState(state) }
+
+// ReduceOnPlateau multipliziert die Lernrate mit Factor, sobald sich die
+// beobachtete Kennzahl (z. B. die Genauigkeit) Patience Epochen lang nicht um
+// mindestens MinDelta verbessert hat. Die Lernrate fällt nicht unter Min.
+type ReduceOnPlateau struct {
+	Rate     float64
+	Factor   float64
+	Patience int
+	MinDelta float64
+	Min      float64
+
+	best    float64
+	started bool
+	wait    int
+}
+
+func (s *ReduceOnPlateau) LR(epoch float64) float64 { return s.Rate }
+
+// Observe erhält die Kennzahl einer Epoche; größere Werte sind besser.
+func (s *ReduceOnPlateau) Observe(metric float64) {
+	if !s.started || metric > s.best+s.MinDelta {
+		s.best = metric
+		s.started = true
+		s.wait = 0
+		return
+	}
+	s.wait++
+	if s.wait > s.Patience {
+		s.Rate = math.Max(s.Rate*s.Factor, s.Min)
+		s.wait = 0
+	}
+}
+
+// State liefert die aktuelle Lernrate und den Stand der Plateau-Erkennung.
+func (s *ReduceOnPlateau) State() ScheduleState {
+	started := 0.0
+	if s.started {
+		started = 1
+	}
+	return ScheduleState{"rate": s.Rate, "best": s.best, "started": started, "wait": float64(s.wait)}
+}
+
+// SetState stellt einen mit State gesicherten Zustand wieder her.
+func (s *ReduceOnPlateau) SetState(state ScheduleState) error {
+	for _, k := range []string{"rate", "best", "started", "wait"} {
+		if _, ok := state[k]; !ok {
+			return fmt.Errorf("Zustand des Schedules enthält %q nicht", k)
+		}
+	}
+	s.Rate, s.best = state["rate"], state["best"]
+	s.started = state["started"] != 0
+	s.wait = int(state["wait"])
+	return nil
+}
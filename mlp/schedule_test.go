@@ -0,0 +1,148 @@
+package mlp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScheduleLR(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		s     Schedule
+		epoch float64
+		want  float64
+	}{
+		{"constant", Constant{Rate: 0.1}, 7.5, 0.1},
+		{"step/start", StepDecay{Base: 1, Gamma: 0.5, StepSize: 10}, 0, 1},
+		{"step/vor Stufe", StepDecay{Base: 1, Gamma: 0.5, StepSize: 10}, 9.9, 1},
+		{"step/Stufe", StepDecay{Base: 1, Gamma: 0.5, StepSize: 10}, 10, 0.5},
+		{"step/zweite Stufe", StepDecay{Base: 1, Gamma: 0.5, StepSize: 10}, 25, 0.25},
+		{"exponential", ExponentialDecay{Base: 1, Gamma: 0.5}, 2, 0.25},
+		{"exponential/stetig", ExponentialDecay{Base: 1, Gamma: 0.5}, 0.5, math.Sqrt(0.5)},
+		{"cosine/start", CosineRestarts{Base: 1, Min: 0.1, Period: 10, PeriodMult: 2}, 0, 1},
+		{"cosine/Mitte", CosineRestarts{Base: 1, Min: 0.1, Period: 10, PeriodMult: 2}, 5, 0.55},
+		{"cosine/Neustart", CosineRestarts{Base: 1, Min: 0.1, Period: 10, PeriodMult: 2}, 10, 1},
+		// die zweite Periode dauert 20 Epochen
+		{"cosine/zweite Periode", CosineRestarts{Base: 1, Min: 0.1, Period: 10, PeriodMult: 2}, 20, 0.55},
+		{"cosine/dritte Periode", CosineRestarts{Base: 1, Min: 0.1, Period: 10, PeriodMult: 2}, 30, 1},
+		{"onecycle/start", OneCycle{Max: 1, Epochs: 10, PctStart: 0.3}, 0, 0.04},
+		{"onecycle/Anstieg", OneCycle{Max: 1, Epochs: 10, PctStart: 0.3}, 1.5, 0.52},
+		{"onecycle/Maximum", OneCycle{Max: 1, Epochs: 10, PctStart: 0.3}, 3, 1},
+		{"onecycle/Ende", OneCycle{Max: 1, Epochs: 10, PctStart: 0.3}, 10, 4e-6},
+		{"onecycle/nach dem Ende", OneCycle{Max: 1, Epochs: 10, PctStart: 0.3}, 12, 4e-6},
+		{"warmup/start", Warmup{Epochs: 2, Start: 0.01, Inner: Constant{Rate: 1}}, 0, 0.01},
+		{"warmup/Mitte", Warmup{Epochs: 2, Start: 0.01, Inner: Constant{Rate: 1}}, 1, 0.505},
+		{"warmup/Ende", Warmup{Epochs: 2, Start: 0.01, Inner: Constant{Rate: 1}}, 2, 1},
+		{"warmup/step", Warmup{Epochs: 2, Start: 0.01, Inner: StepDecay{Base: 1, Gamma: 0.5, StepSize: 1}}, 1, 0.2525},
+	} {
+		if got := tc.s.LR(tc.epoch); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("%s: LR(%g) = %g, erwartet %g", tc.name, tc.epoch, got, tc.want)
+		}
+	}
+}
+
+func TestNewScheduleWarmup(t *testing.T) {
+	cfg := DefaultScheduleConfig(1, 10)
+	cfg.WarmupEpochs = 2
+	for _, name := range ScheduleNames {
+		s, err := NewSchedule(name, cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cfg.WarmupEpochs = 0
+		inner, err := NewSchedule(name, cfg)
+		cfg.WarmupEpochs = 2
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got, want := s.LR(1), 0.505*inner.LR(1); math.Abs(got-want) > 1e-12 {
+			t.Errorf("%s: LR(1) mit Aufwärmphase = %g, erwartet %g", name, got, want)
+		}
+		if got, want := s.LR(5), inner.LR(5); got != want {
+			t.Errorf("%s: LR(5) nach der Aufwärmphase = %g, erwartet %g", name, got, want)
+		}
+		if _, ok := inner.(MetricSchedule); ok {
+			if _, ok := s.(MetricSchedule); !ok {
+				t.Errorf("%s: mit Aufwärmphase kein MetricSchedule", name)
+			}
+		}
+	}
+}
+
+func TestReduceOnPlateau(t *testing.T) {
+	s := &ReduceOnPlateau{Rate: 1, Factor: 0.5, Patience: 1, MinDelta: 0.01, Min: 0.2}
+	for i, tc := range []struct {
+		metric, want float64
+	}{
+		{0.5, 1},
+		{0.505, 1}, // keine Verbesserung um MinDelta
+		{0.6, 1},
+		{0.6, 1},
+		{0.6, 0.5}, // Patience überschritten
+		{0.6, 0.5},
+		{0.6, 0.25},
+		{0.6, 0.25},
+		{0.6, 0.2}, // Untergrenze Min
+	} {
+		s.Observe(tc.metric)
+		if got := s.LR(float64(i)); got != tc.want {
+			t.Errorf("nach Epoche %d: LR = %g, erwartet %g", i, got, tc.want)
+		}
+	}
+}
+
+func TestReduceOnPlateauState(t *testing.T) {
+	a := &ReduceOnPlateau{Rate: 1, Factor: 0.5, Patience: 2, MinDelta: 0.01}
+	for _, m := range []float64{0.5, 0.6, 0.6, 0.6} {
+		a.Observe(m)
+	}
+	b := &ReduceOnPlateau{Rate: 1, Factor: 0.5, Patience: 2, MinDelta: 0.01}
+	if err := b.SetState(a.State()); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range []float64{0.6, 0.6, 0.6, 0.7, 0.7, 0.7, 0.7} {
+		a.Observe(m)
+		b.Observe(m)
+		if a.LR(0) != b.LR(0) {
+			t.Fatalf("nach %d Epochen: LR %g, vor dem Sichern %g", i+1, b.LR(0), a.LR(0))
+		}
+	}
+	if a.LR(0) != 0.25 {
+		t.Errorf("LR = %g, erwartet 0.25", a.LR(0))
+	}
+	if err := b.SetState(ScheduleState{"rate": 1}); err == nil {
+		t.Error("unvollständiger Zustand wurde akzeptiert")
+	}
+}
+
+func TestNewScheduleChecksConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*ScheduleConfig)
+	}{
+		{"step", func(c *ScheduleConfig) { c.StepSize = 0 }},
+		{"step", func(c *ScheduleConfig) { c.Gamma = 0 }},
+		{"exponential", func(c *ScheduleConfig) { c.Gamma = -0.5 }},
+		{"cosine", func(c *ScheduleConfig) { c.Period = 0 }},
+		{"cosine", func(c *ScheduleConfig) { c.PeriodMult = 0.5 }},
+		{"onecycle", func(c *ScheduleConfig) { c.PctStart = 0 }},
+		{"onecycle", func(c *ScheduleConfig) { c.PctStart = 1 }},
+		{"onecycle", func(c *ScheduleConfig) { c.Epochs = 0 }},
+		{"plateau", func(c *ScheduleConfig) { c.Factor = 1 }},
+		{"plateau", func(c *ScheduleConfig) { c.Factor = 0 }},
+		{"plateau", func(c *ScheduleConfig) { c.Patience = -1 }},
+		{"constant", func(c *ScheduleConfig) { c.WarmupEpochs = -1 }},
+	} {
+		cfg := DefaultScheduleConfig(0.1, 10)
+		tc.modify(&cfg)
+		if _, err := NewSchedule(tc.name, cfg); err == nil {
+			t.Errorf("%s mit %+v: kein Fehler", tc.name, cfg)
+		}
+	}
+	// Parameter anderer Schedules spielen keine Rolle.
+	cfg := DefaultScheduleConfig(0.1, 10)
+	cfg.Period = 0
+	if _, err := NewSchedule("step", cfg); err != nil {
+		t.Errorf("step mit period 0: %v", err)
+	}
+}
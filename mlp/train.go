@@ -0,0 +1,356 @@
+package mlp
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardSize ist die Anzahl Beispiele, die gemeinsam mit Matrixmultiplikationen
+// verarbeitet und deren Gradienten aufsummiert werden. Ein Mini-Batch wird
+// unabhängig von der Anzahl der Worker immer gleich in Shards zerlegt und die
+// Shards immer in derselben Reihenfolge addiert, so dass das Ergebnis nicht von
+// der Anzahl der Worker abhängt.
+const shardSize = 16
+
+// ErrStopped liefert Trainer.Run, wenn das Training über Trainer.Stop
+// unterbrochen wurde.
+var ErrStopped = errors.New("Training unterbrochen")
+
+// EpochStats fasst die Kennzahlen einer Trainingsepoche zusammen. Loss ist der
+// mittlere Loss der Mini-Batches der Epoche; ValLoss und ValAcc sind ohne
+// Validierungsdaten 0.
+type EpochStats struct {
+	Epoch int `json:"epoch"`
+	// LR ist die Lernrate im letzten Schritt der Epoche.
+	LR       float64 `json:"lr"`
+	Loss     float64 `json:"loss"`
+	TrainAcc float64 `json:"train_acc"`
+	ValLoss  float64 `json:"val_loss"`
+	ValAcc   float64 `json:"val_acc"`
+}
+
+// Progress ist der Stand eines Trainings. Zusammen mit den Parametern, dem
+// Zustand des Optimizers, des Schedules und der Zufallsquelle lässt sich das
+// Training daraus bitgenau fortsetzen, auch mitten in einer Epoche.
+type Progress struct {
+	// Epoch ist die Anzahl der abgeschlossenen Epochen.
+	Epoch int `json:"epoch"`
+	// Batch ist die Anzahl der abgeschlossenen Mini-Batches der laufenden Epoche.
+	Batch int `json:"batch"`
+	// Perm ist die Reihenfolge der Trainingsbeispiele in der laufenden Epoche,
+	// nil zwischen zwei Epochen.
+	Perm []int `json:"perm,omitempty"`
+	// Loss ist die Summe der Loss-Werte der abgeschlossenen Mini-Batches der
+	// laufenden Epoche.
+	Loss float64 `json:"loss"`
+	// History enthält die Kennzahlen aller abgeschlossenen Epochen.
+	History []EpochStats `json:"history,omitempty"`
+}
+
+// Trainer trainiert ein MLP mit Mini-Batch-Gradientenabstieg.
+type Trainer[T Float] struct {
+	Model        *MLP[T]
+	Epochs       int
+	BatchSize    int
+	LearningRate float64
+	// Schedule bestimmt die Lernrate je Schritt, nil bedeutet konstant LearningRate.
+	// Ein MetricSchedule erhält nach jeder Epoche die Validierungsgenauigkeit,
+	// ohne Validierungsdaten die Trainingsgenauigkeit.
+	Schedule Schedule
+	// Optimizer für die Parameterupdates, nil bedeutet einfaches SGD.
+	Optimizer Optimizer[T]
+	// Progress ist der Stand des Trainings. Run beginnt dort, z. B. beim
+	// Fortsetzen aus einem Checkpoint, und schreibt ihn laufend fort.
+	Progress Progress
+	// EvalSubset begrenzt aus Performancegründen die Anzahl der Trainingsbeispiele,
+	// auf denen TrainAcc berechnet wird. 0 bedeutet alle.
+	EvalSubset int
+	// Workers ist die Anzahl der Goroutinen, auf die ein Mini-Batch verteilt
+	// wird. 0 bedeutet runtime.GOMAXPROCS(0).
+	Workers int
+	// Rand ist die einzige Zufallsquelle des Trainings (z. B. für das Mischen der
+	// Trainingsdaten). Mit demselben Seed sind Läufe bitgenau reproduzierbar.
+	Rand *rand.Rand
+	// OnEpoch wird nach jeder Epoche aufgerufen, z. B. für die Ausgabe. Die
+	// Epoche ist dann bereits in Progress.History eingetragen.
+	OnEpoch func(EpochStats)
+	// Regularization legt Gewichtsabnahme, Dropout und Label Smoothing fest.
+	Regularization Regularization
+	// Augment verändert im Training jedes Beispiel x eines Mini-Batches
+	// zufällig (Datenaugmentierung), die Trainingsdaten selbst bleiben
+	// unverändert; nil bedeutet keine Augmentierung. Es wird parallel für
+	// verschiedene Shards aufgerufen, rng hängt nur vom Mini-Batch und vom
+	// Shard ab.
+	Augment func(rng *rand.Rand, x []T)
+	// EarlyStopping beendet das Training vor t.Epochs, sobald sich die
+	// Validierung nicht mehr verbessert; nil bedeutet nie. Erfordert
+	// Validierungsdaten.
+	EarlyStopping *EarlyStopping
+	// Stop unterbricht das Training vor dem nächsten Mini-Batch, sobald der
+	// Kanal geschlossen wird. Run liefert dann ErrStopped und Progress
+	// beschreibt den erreichten Stand.
+	Stop <-chan struct{}
+}
+
+// Run trainiert das Modell auf train ab t.Progress bis t.Epochs und wertet
+// nach jeder Epoche auf val aus. Die Beispiele werden je Shard aus den
+// Datasets gelesen. val darf nil sein, wenn t.EarlyStopping nil ist.
+func (t *Trainer[T]) Run(train, val Dataset[T]) error {
+	m := t.Model
+	if val != nil && val.Len() == 0 {
+		val = nil
+	}
+	if t.EarlyStopping != nil && val == nil {
+		return errors.New("Early Stopping erfordert Validierungsdaten")
+	}
+
+	evalN := train.Len()
+	if t.EvalSubset > 0 && t.EvalSubset < evalN {
+		evalN = t.EvalSubset
+	}
+	numTrain := train.Len()
+
+	if t.Optimizer == nil {
+		t.Optimizer = &SGD[T]{}
+	}
+	if t.Rand == nil {
+		t.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if t.Schedule == nil {
+		t.Schedule = Constant{Rate: t.LearningRate}
+	}
+	if t.Workers <= 0 {
+		t.Workers = runtime.GOMAXPROCS(0)
+	}
+	numBatches := (numTrain + t.BatchSize - 1) / t.BatchSize
+
+	// Arbeitsspeicher und Gradienten-Akkumulatoren je Shard, über alle
+	// Mini-Batches wiederverwendet
+	numShards := (t.BatchSize + shardSize - 1) / shardSize
+	shards := make([]Gradients[T], numShards)
+	work := make([]*Batch[T], numShards)
+	labels := make([]*Matrix[T], numShards)
+	for s := range shards {
+		shards[s] = m.NewGradients()
+		work[s] = m.NewBatch(shardSize)
+		work[s].Train = true
+		labels[s] = NewMatrix[T](shardSize, m.Layers[len(m.Layers)-1].OutputDim())
+	}
+	losses := make([]float64, len(shards))
+	var augSrc []*Source
+	var augRng []*rand.Rand
+	if t.Augment != nil {
+		augSrc = make([]*Source, numShards)
+		augRng = make([]*rand.Rand, numShards)
+		for s := range augSrc {
+			augSrc[s] = NewSource(0)
+			augRng[s] = rand.New(augSrc[s])
+		}
+	}
+
+	p := &t.Progress
+	for p.Epoch < t.Epochs {
+		if t.EarlyStopping != nil && t.EarlyStopping.Done(p.History) {
+			break
+		}
+		if p.Perm == nil {
+			// Shuffle der Trainingsdaten
+			p.Perm = t.Rand.Perm(numTrain)
+			p.Batch, p.Loss = 0, 0
+		}
+		var lr float64
+
+		for i := p.Batch * t.BatchSize; i < numTrain; i += t.BatchSize {
+			if t.stopped() {
+				return ErrStopped
+			}
+			end := i + t.BatchSize
+			if end > numTrain {
+				end = numTrain
+			}
+
+			// Mini-Batch
+			batch := p.Perm[i:end]
+			n := (len(batch) + shardSize - 1) / shardSize
+			reg := t.Regularization
+			var seed int64
+			if reg.Dropout > 0 {
+				// Die Dropout-Masken jedes Shards hängen nur von seed und
+				// der Nummer des Shards ab, nicht von den Workern.
+				seed = t.Rand.Int63()
+			}
+			var augSeed int64
+			if t.Augment != nil {
+				augSeed = t.Rand.Int63()
+			}
+			t.parallel(n, func(s int) {
+				lo, hi := s*shardSize, min((s+1)*shardSize, len(batch))
+				b, y := work[s], labels[s]
+				x := b.Input(hi - lo)
+				readBatch(train, batch[lo:hi], x, y)
+				if reg.LabelSmoothing > 0 {
+					for k := 0; k < y.Rows; k++ {
+						smoothLabel(y.Row(k), reg.LabelSmoothing)
+					}
+				}
+				if t.Augment != nil {
+					augSrc[s].Seed(augSeed + int64(s))
+					for k := 0; k < hi-lo; k++ {
+						t.Augment(augRng[s], x.Row(k))
+					}
+				}
+				b.SetDropout(reg.Dropout, seed+int64(s))
+			})
+
+			// Forward- und Backward-Pass Schicht für Schicht über alle
+			// Shards, damit BatchNorm den ganzen Mini-Batch sieht
+			m.forwardShards(work[:n], t.parallel)
+			t.parallel(n, func(s int) {
+				out := work[s].Output()
+				losses[s] = 0
+				for k := 0; k < out.Rows; k++ {
+					losses[s] += crossEntropyLoss(labels[s].Row(k), out.Row(k))
+				}
+				shards[s].Zero()
+			})
+			m.backwardShards(work[:n], labels[:n], shards[:n], t.parallel)
+
+			// Reduktion der Shards in fester Reihenfolge
+			gradSum := shards[0]
+			batchLoss := losses[0]
+			for s := 1; s < n; s++ {
+				gradSum.Add(shards[s])
+				batchLoss += losses[s]
+			}
+
+			m.updateRunningStats(work[:n])
+
+			// Durchschnittliche Gradienten des Mini-Batches
+			batchCount := len(batch)
+			gradSum.Scale(1 / T(batchCount))
+
+			// Parameterupdate
+			lr = t.Schedule.LR(float64(p.Epoch) + float64(p.Batch)/float64(numBatches))
+			m.Update(gradSum, t.Optimizer, lr, reg.WeightDecay)
+			p.Loss += batchLoss / float64(batchCount)
+			p.Batch++
+		}
+
+		stats := EpochStats{
+			Epoch: p.Epoch,
+			LR:    lr,
+			Loss:  p.Loss / float64(numBatches),
+		}
+		_, stats.TrainAcc = t.Evaluate(Head(train, evalN))
+		metric := stats.TrainAcc
+		if val != nil {
+			stats.ValLoss, stats.ValAcc = t.Evaluate(val)
+			metric = stats.ValAcc
+		}
+		p.Epoch++
+		p.Batch, p.Perm, p.Loss = 0, nil, 0
+		p.History = append(p.History, stats)
+
+		if ms, ok := t.Schedule.(MetricSchedule); ok {
+			ms.Observe(metric)
+		}
+		if t.OnEpoch != nil {
+			t.OnEpoch(stats)
+		}
+	}
+	return nil
+}
+
+// stopped prüft, ob t.Stop geschlossen wurde.
+func (t *Trainer[T]) stopped() bool {
+	select {
+	case <-t.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// parallel ruft fn(0) bis fn(n-1) verteilt auf t.Workers Goroutinen auf und
+// wartet, bis alle Aufrufe beendet sind.
+func (t *Trainer[T]) parallel(n int, fn func(i int)) {
+	workers := min(t.Workers, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// evalChunk ist die Anzahl der Beispiele, die Evaluate und Metrics gemeinsam
+// durch das Modell schicken.
+const evalChunk = 256
+
+// chunks liefert die Anzahl der Blöcke von forChunks für d.
+func (t *Trainer[T]) chunks(d Dataset[T]) int {
+	return (d.Len() + evalChunk - 1) / evalChunk
+}
+
+// forChunks berechnet die Ausgaben des Modells für d in Blöcken von evalChunk
+// Beispielen, verteilt auf t.Workers Goroutinen, und ruft fn für jeden Block c
+// mit den Labels y und den Ausgaben out auf.
+func (t *Trainer[T]) forChunks(d Dataset[T], fn func(c int, y, out *Matrix[T])) {
+	n := d.Len()
+	if t.Workers <= 0 {
+		t.Workers = runtime.GOMAXPROCS(0)
+	}
+	t.parallel(t.chunks(d), func(c int) {
+		lo, hi := c*evalChunk, min((c+1)*evalChunk, n)
+		idx := make([]int, hi-lo)
+		for i := range idx {
+			idx[i] = lo + i
+		}
+		b := t.Model.NewBatch(hi - lo)
+		y := NewMatrix[T](hi-lo, t.Model.Layers[len(t.Model.Layers)-1].OutputDim())
+		readBatch(d, idx, b.Input(hi-lo), y)
+		fn(c, y, t.Model.ForwardBatch(b))
+	})
+}
+
+// Evaluate berechnet den mittleren Loss und die Genauigkeit des Modells auf
+// d. Die Beispiele werden in Blöcken auf t.Workers Goroutinen verteilt.
+func (t *Trainer[T]) Evaluate(d Dataset[T]) (loss, acc float64) {
+	n := d.Len()
+	if n == 0 {
+		return 0, 0
+	}
+	correct := make([]int, t.chunks(d))
+	losses := make([]float64, len(correct))
+	t.forChunks(d, func(c int, y, out *Matrix[T]) {
+		for k := 0; k < out.Rows; k++ {
+			losses[c] += crossEntropyLoss(y.Row(k), out.Row(k))
+			if argmax(out.Row(k)) == argmax(y.Row(k)) {
+				correct[c]++
+			}
+		}
+	})
+	total := 0
+	for c := range correct {
+		total += correct[c]
+		loss += losses[c]
+	}
+	return loss / float64(n), float64(total) / float64(n)
+}
@@ -0,0 +1,316 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// syntheticData erzeugt n zufällige Beispiele mit dim Merkmalen und classes
+// Klassen; die Klasse hängt vom größten der ersten classes Merkmale ab.
+func syntheticData(n, dim, classes int, seed int64) (X, Y [][]float64) {
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		x := make([]float64, dim)
+		for j := range x {
+			x[j] = rng.Float64()
+		}
+		y := make([]float64, classes)
+		y[argmax(x[:classes])] = 1
+		X = append(X, x)
+		Y = append(Y, y)
+	}
+	return X, Y
+}
+
+// trainRun trainiert ein kleines Netz mit dem Seed seed und der
+// Regularisierung reg auf workers Goroutinen und liefert es zurück.
+func trainRun[T Float](seed int64, workers int, reg Regularization, X, Y [][]T) *MLP[T] {
+	rng := rand.New(rand.NewSource(seed))
+	m := NewMLP[T](rng, []int{len(X[0]), 16, 8, len(Y[0])})
+	t := &Trainer[T]{
+		Model:          m,
+		Epochs:         3,
+		BatchSize:      40,
+		LearningRate:   0.1,
+		Optimizer:      &SGD[T]{Momentum: 0.9},
+		Workers:        workers,
+		Rand:           rng,
+		Regularization: reg,
+	}
+	t.Run(Slices[T]{X, Y}, Slices[T]{X[:50], Y[:50]})
+	return m
+}
+
+// equalWeights vergleicht alle Parameter zweier Modelle bitgenau.
+func equalWeights[T Float](a, b *MLP[T]) bool {
+	for l := range a.Layers {
+		for i := range a.Layers[l].W.Data {
+			if a.Layers[l].W.Data[i] != b.Layers[l].W.Data[i] {
+				return false
+			}
+		}
+		for i := range a.Layers[l].B {
+			if a.Layers[l].B[i] != b.Layers[l].B[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestTrainingIsReproducible(t *testing.T) {
+	X, Y := syntheticData(200, 12, 4, 1)
+
+	a := trainRun(42, 1, Regularization{}, X, Y)
+	b := trainRun(42, 1, Regularization{}, X, Y)
+	if !equalWeights(a, b) {
+		t.Error("zwei Läufe mit Seed 42 liefern unterschiedliche Gewichte")
+	}
+
+	c := trainRun(43, 1, Regularization{}, X, Y)
+	if equalWeights(a, c) {
+		t.Error("Läufe mit Seed 42 und 43 liefern identische Gewichte")
+	}
+}
+
+func TestTrainingIndependentOfWorkers(t *testing.T) {
+	X, Y := syntheticData(200, 12, 4, 1)
+
+	regs := []Regularization{
+		{},
+		{WeightDecay: WeightDecay{L1: 1e-4, L2: 1e-3}, Dropout: 0.3, LabelSmoothing: 0.1},
+	}
+	for _, reg := range regs {
+		want := trainRun(7, 1, reg, X, Y)
+		for _, workers := range []int{2, 3, 8} {
+			if got := trainRun(7, workers, reg, X, Y); !equalWeights(want, got) {
+				t.Errorf("%+v: %d Worker liefern andere Gewichte als 1 Worker", reg, workers)
+			}
+		}
+	}
+}
+
+func TestAugmentIndependentOfWorkers(t *testing.T) {
+	X, Y := syntheticData(200, 12, 4, 1)
+
+	run := func(workers int) *MLP[float64] {
+		rng := rand.New(rand.NewSource(3))
+		m := NewMLP[float64](rng, []int{12, 16, 4})
+		tr := &Trainer[float64]{
+			Model:        m,
+			Epochs:       2,
+			BatchSize:    40,
+			LearningRate: 0.1,
+			Workers:      workers,
+			Rand:         rng,
+			Augment: func(rng *rand.Rand, x []float64) {
+				for i := range x {
+					x[i] += 0.1 * rng.NormFloat64()
+				}
+			},
+		}
+		tr.Run(Slices[float64]{X, Y}, nil)
+		return m
+	}
+	want := run(1)
+	for _, workers := range []int{2, 5} {
+		if !equalWeights(want, run(workers)) {
+			t.Errorf("%d Worker liefern mit Augmentierung andere Gewichte als 1 Worker", workers)
+		}
+	}
+
+	orig, _ := syntheticData(200, 12, 4, 1)
+	for i := range X {
+		for j := range X[i] {
+			if X[i][j] != orig[i][j] {
+				t.Fatal("Augment hat die Trainingsdaten verändert")
+			}
+		}
+	}
+}
+
+// trainAccuracy trainiert ein Netz in der Genauigkeit T auf den ersten
+// nTrain Beispielen und liefert die Genauigkeit auf den übrigen.
+func trainAccuracy[T Float](X, Y [][]float64, nTrain int) float64 {
+	rng := rand.New(rand.NewSource(5))
+	m := NewMLP[T](rng, []int{len(X[0]), 32, len(Y[0])})
+	t := &Trainer[T]{
+		Model:     m,
+		Epochs:    20,
+		BatchSize: 32,
+		Schedule:  Constant{Rate: 0.01},
+		Optimizer: &Adam[T]{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8},
+		Rand:      rng,
+	}
+	trainX, trainY := ConvertRows[T](X[:nTrain]), ConvertRows[T](Y[:nTrain])
+	testX, testY := ConvertRows[T](X[nTrain:]), ConvertRows[T](Y[nTrain:])
+	t.Run(Slices[T]{trainX, trainY}, Slices[T]{testX, testY})
+	return m.ComputeAccuracy(testX, testY)
+}
+
+func TestFloat32MatchesFloat64(t *testing.T) {
+	X, Y := syntheticData(1200, 12, 4, 1)
+
+	acc64 := trainAccuracy[float64](X, Y, 1000)
+	acc32 := trainAccuracy[float32](X, Y, 1000)
+
+	t.Logf("Testgenauigkeit float64: %.2f%%, float32: %.2f%%", acc64*100, acc32*100)
+	if acc64 < 0.8 {
+		t.Fatalf("float64-Modell lernt nicht: Testgenauigkeit %.2f%%", acc64*100)
+	}
+	if math.Abs(acc32-acc64) > 0.02 {
+		t.Errorf("Testgenauigkeit float32 %.2f%% weicht von float64 %.2f%% ab", acc32*100, acc64*100)
+	}
+}
+
+func TestStratifiedSplit(t *testing.T) {
+	X, Y := syntheticData(1000, 12, 4, 1)
+	train, val := StratifiedSplit(rand.New(rand.NewSource(1)), Slices[float64]{X, Y}, 0.2)
+
+	if len(train)+len(val) != len(Y) {
+		t.Fatalf("%d + %d Indizes für %d Beispiele", len(train), len(val), len(Y))
+	}
+	seen := make(map[int]bool)
+	for _, i := range append(append([]int{}, train...), val...) {
+		if seen[i] {
+			t.Fatalf("Index %d ist doppelt", i)
+		}
+		seen[i] = true
+	}
+
+	count := func(idxs []int) []int {
+		n := make([]int, 4)
+		for _, i := range idxs {
+			n[argmax(Y[i])]++
+		}
+		return n
+	}
+	all, v := count(append(append([]int{}, train...), val...)), count(val)
+	for c := range all {
+		if want := math.Round(0.2 * float64(all[c])); float64(v[c]) != want {
+			t.Errorf("Klasse %d: %d von %d in der Validierung, erwartet %.0f", c, v[c], all[c], want)
+		}
+	}
+}
+
+func TestEarlyStopping(t *testing.T) {
+	losses := func(l ...float64) []EpochStats {
+		h := make([]EpochStats, len(l))
+		for i := range l {
+			h[i] = EpochStats{Epoch: i, ValLoss: l[i], ValAcc: 1 - l[i]}
+		}
+		return h
+	}
+	tests := []struct {
+		name    string
+		e       EarlyStopping
+		history []EpochStats
+		best    int
+		done    bool
+	}{
+		{"leer", EarlyStopping{MetricLoss, 2, 0}, nil, -1, false},
+		{"verbessert", EarlyStopping{MetricLoss, 2, 0}, losses(0.5, 0.4, 0.3), 2, false},
+		{"geduldig", EarlyStopping{MetricLoss, 2, 0}, losses(0.5, 0.4, 0.45), 1, false},
+		{"abbruch", EarlyStopping{MetricLoss, 2, 0}, losses(0.5, 0.4, 0.45, 0.41), 1, true},
+		{"min-delta", EarlyStopping{MetricLoss, 2, 0.05}, losses(0.5, 0.48, 0.46), 0, true},
+		{"genauigkeit", EarlyStopping{MetricAccuracy, 1, 0}, losses(0.5, 0.3, 0.4), 1, true},
+		{"aus", EarlyStopping{MetricLoss, 0, 0}, losses(0.1, 0.5, 0.6, 0.7), 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Best(tt.history); got != tt.best {
+			t.Errorf("%s: Best = %d, erwartet %d", tt.name, got, tt.best)
+		}
+		if got := tt.e.Done(tt.history); got != tt.done {
+			t.Errorf("%s: Done = %v, erwartet %v", tt.name, got, tt.done)
+		}
+	}
+}
+
+func TestEpochLossPartialBatch(t *testing.T) {
+	// Weniger Beispiele als ein Mini-Batch: der Loss der Epoche ist der
+	// mittlere Loss des einzigen, unvollständigen Mini-Batches.
+	X, Y := syntheticData(30, 12, 4, 1)
+	m := NewMLP[float64](rand.New(rand.NewSource(1)), []int{12, 8, 4})
+	var stats EpochStats
+	tr := &Trainer[float64]{
+		Model:     m,
+		Epochs:    1,
+		BatchSize: 40,
+		Optimizer: &SGD[float64]{},
+		Rand:      rand.New(rand.NewSource(2)),
+		OnEpoch:   func(s EpochStats) { stats = s },
+	}
+	tr.Run(Slices[float64]{X, Y}, nil)
+	// Mit Lernrate 0 ändert das Training das Modell nicht.
+	want, _ := tr.Evaluate(Slices[float64]{X, Y})
+	if math.IsInf(stats.Loss, 0) || math.Abs(stats.Loss-want) > 1e-9 {
+		t.Errorf("Loss der Epoche %v, erwartet %v", stats.Loss, want)
+	}
+}
+
+func TestBatchNormUsesWholeMiniBatch(t *testing.T) {
+	// 49 Beispiele: drei volle Shards und einer mit nur einem Beispiel. Ein
+	// Schritt des Trainers muss einem Schritt mit einem einzigen Batch über
+	// alle 49 Beispiele entsprechen.
+	X, Y := syntheticData(49, 12, 4, 1)
+	model := func() *MLP[float64] {
+		m := NewMLP[float64](rand.New(rand.NewSource(5)), []int{12, 16, 8, 4})
+		if err := m.AddNorm(BatchNorm); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	want := model()
+	b := want.NewBatch(len(X))
+	b.Train = true
+	y := batchOf(b, X, Y)
+	want.ForwardBatch(b)
+	grads := want.NewGradients()
+	want.BackwardBatch(b, y, grads)
+	want.updateRunningStats([]*Batch[float64]{b})
+	grads.Scale(1 / float64(len(X)))
+	want.Update(grads, &SGD[float64]{}, 0.5, WeightDecay{})
+
+	var first *MLP[float64]
+	for _, workers := range []int{1, 3} {
+		got := model()
+		tr := &Trainer[float64]{
+			Model:        got,
+			Epochs:       1,
+			BatchSize:    len(X),
+			LearningRate: 0.5,
+			Optimizer:    &SGD[float64]{},
+			Workers:      workers,
+			Rand:         rand.New(rand.NewSource(2)),
+		}
+		if err := tr.Run(Slices[float64]{X, Y}, nil); err != nil {
+			t.Fatal(err)
+		}
+		// Die Reihenfolge der Beispiele im Mini-Batch ändert nur die
+		// Rundung der Summen.
+		gv, wv := got.Params(got.NewGradients()), want.Params(want.NewGradients())
+		for k := range wv {
+			for i, v := range wv[k].Value {
+				if math.Abs(gv[k].Value[i]-v) > 1e-12 {
+					t.Fatalf("%d Worker: Parameter %d[%d] = %g, erwartet %g", workers, k, i, gv[k].Value[i], v)
+				}
+			}
+		}
+		for l, layer := range want.Layers[:len(want.Layers)-1] {
+			n := got.Layers[l].Norm
+			for i := range layer.Norm.RunMean {
+				if math.Abs(n.RunMean[i]-layer.Norm.RunMean[i]) > 1e-12 || math.Abs(n.RunVar[i]-layer.Norm.RunVar[i]) > 1e-12 {
+					t.Fatalf("%d Worker: Schicht %d: gleitende Statistik %d = (%g, %g), erwartet (%g, %g)", workers, l, i,
+						n.RunMean[i], n.RunVar[i], layer.Norm.RunMean[i], layer.Norm.RunVar[i])
+				}
+			}
+		}
+		if first == nil {
+			first = got
+		} else if !equalWeights(first, got) {
+			t.Errorf("%d Worker liefern andere Gewichte als 1 Worker", workers)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Kennzahlen, nach denen EarlyStopping die Validierung bewertet.
+const (
+	MetricLoss     = "loss"
+	MetricAccuracy = "accuracy"
+)
+
+// StratifiedSplit teilt die Indizes der Beispiele von d in Trainings- und
+// Validierungsdaten auf. Von jeder Klasse kommt der Anteil frac (gerundet) in
+// die Validierungsdaten, so dass die Klassenverteilung in beiden Teilen gleich
+// ist. Die Auswahl innerhalb einer Klasse bestimmt rng; beide Listen sind
+// aufsteigend sortiert und lassen sich als Subset von d verwenden.
+func StratifiedSplit[T Float](rng *rand.Rand, d Dataset[T], frac float64) (train, val []int) {
+	var classes [][]int
+	for i := 0; i < d.Len(); i++ {
+		c := d.Class(i)
+		for len(classes) <= c {
+			classes = append(classes, nil)
+		}
+		classes[c] = append(classes[c], i)
+	}
+
+	for _, idxs := range classes {
+		n := int(math.Round(frac * float64(len(idxs))))
+		for k, j := range rng.Perm(len(idxs)) {
+			if k < n {
+				val = append(val, idxs[j])
+			} else {
+				train = append(train, idxs[j])
+			}
+		}
+	}
+	sort.Ints(train)
+	sort.Ints(val)
+	return train, val
+}
+
+// EarlyStopping beendet das Training, wenn sich die Kennzahl Metric auf den
+// Validierungsdaten Patience Epochen lang nicht um mehr als MinDelta verbessert
+// hat. Der Zustand ergibt sich allein aus dem Verlauf der Kennzahlen und
+// bleibt damit beim Fortsetzen aus einem Checkpoint erhalten.
+type EarlyStopping struct {
+	// Metric ist MetricLoss (kleiner ist besser) oder MetricAccuracy.
+	Metric string
+	// Patience ist die Anzahl der Epochen ohne Verbesserung, 0 schaltet das
+	// Abbrechen aus; Best bleibt nutzbar.
+	Patience int
+	MinDelta float64
+}
+
+// Check prüft, ob Metric eine bekannte Kennzahl ist.
+func (e *EarlyStopping) Check() error {
+	switch e.Metric {
+	case MetricLoss, MetricAccuracy:
+		return nil
+	}
+	return fmt.Errorf("unbekannte Kennzahl %q für Early Stopping, erlaubt sind %s und %s", e.Metric, MetricLoss, MetricAccuracy)
+}
+
+// score liefert die Kennzahl von s so, dass größere Werte besser sind.
+func (e *EarlyStopping) score(s EpochStats) float64 {
+	if e.Metric == MetricAccuracy {
+		return s.ValAcc
+	}
+	return -s.ValLoss
+}
+
+// Best liefert den Index der besten Epoche in history oder -1 für einen leeren
+// Verlauf. Eine spätere Epoche gilt nur als besser, wenn sie die bisher beste
+// um mehr als MinDelta übertrifft.
+func (e *EarlyStopping) Best(history []EpochStats) int {
+	best := -1
+	for i, s := range history {
+		if best < 0 || e.score(s) > e.score(history[best])+e.MinDelta {
+			best = i
+		}
+	}
+	return best
+}
+
+// Done prüft, ob das Training nach den Epochen in history beendet werden soll.
+func (e *EarlyStopping) Done(history []EpochStats) bool {
+	if e.Patience <= 0 || len(history) == 0 {
+		return false
+	}
+	return len(history)-1-e.Best(history) >= e.Patience
+}